@@ -2,10 +2,14 @@
 package bnk
 
 import (
+	"bytes"
+	"crypto/sha256"
 	"encoding/binary"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"math"
 	"os"
 	"strings"
@@ -20,7 +24,7 @@ import (
 const wemAlignmentBytes = 16
 
 // A LoopValue identifier for looping infinite times.
-const InfiniteLoops = 0
+const InfiniteLoops = wwise.InfiniteLoops
 
 // A File represents an open Wwise SoundBank.
 type File struct {
@@ -35,17 +39,40 @@ type File struct {
 }
 
 // LoopValue describes the loop parameters of a given audio object.
-type LoopValue struct {
-	// True if this audio object loops; and false if otherwise.
-	Loops bool
-	// The number of times this audio track will play. 0 means that this audio will
-	// play infinite times. This value is not vaild if loops is false.
-	Value uint32
+type LoopValue = wwise.LoopValue
+
+// A PlaybackMode describes how a Playback plays its children.
+type PlaybackMode int
+
+const (
+	// Every child is played in turn.
+	PlaySequence PlaybackMode = iota
+	// A single child, chosen at random, is played.
+	PlayRandom
+	// A single child, chosen by the value of a switch or state, is played.
+	PlaySwitch
+	// Every child is played at once.
+	PlayLayered
+)
+
+// A Playback describes what an object of a SoundBank plays: either a single
+// wem stored in the SoundBank, or the children of a container.
+type Playback struct {
+	// The index of the wem that is played, or -1 if this plays its children.
+	Wem      int
+	Mode     PlaybackMode
+	Children []*Playback
 }
 
 // NewFile creates a new File for access Wwise SoundBank files. The file is
 // expected to start at position 0 in the io.ReaderAt.
 func NewFile(r io.ReaderAt) (*File, error) {
+	return newFile(r, nil)
+}
+
+// newFile creates a new File reading from r. If rep is not nil, the DATA
+// section is read leniently by rep, which records every correction it makes.
+func newFile(r io.ReaderAt, rep *repairer) (*File, error) {
 	bnk := new(File)
 
 	sr := util.NewResettingReader(r, 0, math.MaxInt64)
@@ -68,6 +95,10 @@ func NewFile(r io.ReaderAt) (*File, error) {
 			bnk.BankHeaderSection = sec
 			bnk.sections = append(bnk.sections, sec)
 		case didxHeaderId:
+			if rep != nil {
+				rep.indexOffset, _ = sr.Seek(0, io.SeekCurrent)
+				rep.indexOffset -= SECTION_HEADER_BYTES
+			}
 			sec, err := hdr.NewDataIndexSection(sr)
 			if err != nil {
 				return nil, err
@@ -75,7 +106,17 @@ func NewFile(r io.ReaderAt) (*File, error) {
 			bnk.IndexSection = sec
 			bnk.sections = append(bnk.sections, sec)
 		case dataHeaderId:
-			sec, err := hdr.NewDataSection(sr, bnk.IndexSection)
+			if bnk.IndexSection == nil {
+				offset, _ := sr.Seek(0, io.SeekCurrent)
+				return nil, fmt.Errorf("0x%08X: The DATA section comes before any "+
+					"DIDX section describing its wems", offset-SECTION_HEADER_BYTES)
+			}
+			var sec *DataSection
+			if rep != nil {
+				sec, err = rep.readDataSection(sr, hdr, bnk.IndexSection)
+			} else {
+				sec, err = hdr.NewDataSection(sr, bnk.IndexSection)
+			}
 			if err != nil {
 				return nil, err
 			}
@@ -152,12 +193,23 @@ func (bnk *File) Wems() []*wwise.Wem {
 }
 
 func (bnk *File) ReplaceWems(rs ...*wwise.ReplacementWem) {
+	// The lengths of the replaced wems before and after replacement, by ID.
+	oldLengths := make(map[uint32]uint32)
+	newLengths := make(map[uint32]uint32)
+	for _, r := range rs {
+		desc := bnk.Wems()[r.WemIndex].Descriptor
+		oldLengths[desc.WemId] = desc.Length
+		newLengths[desc.WemId] = uint32(r.Length)
+	}
 	surplus := wwise.ReplaceWems(bnk, wemAlignmentBytes, rs...)
 
 	if surplus != 0 {
 		// Update the length of the DATA header to account for the change in size.
 		bnk.DataSection.Header.Length += uint32(surplus)
 	}
+	if bnk.ObjectSection != nil {
+		bnk.ObjectSection.updateWemLengths(oldLengths, newLengths)
+	}
 }
 
 func (bnk *File) DataStart() uint32 {
@@ -186,81 +238,522 @@ func (bnk *File) LoopOf(i int) LoopValue {
 	return LoopValue{ok, times}
 }
 
+// CanLoop returns true if the loop value of the wem stored in this SoundBank at
+// index i can be changed with ReplaceLoopOf. This requires the wem to be played
+// by a sound effect object.
+func (bnk *File) CanLoop(i int) bool {
+	return bnk.soundObjectOf(i) != nil
+}
+
 // ReplaceLoopOf replaces the loop value of the wem stored in this SoundBank at
 // index i with the new value. This method is idempotent.
 func (bnk *File) ReplaceLoopOf(i int, loop LoopValue) {
-	if bnk.DataSection == nil {
+	if !loop.Loops {
+		// Remove looping from the audio object, if it has a loop.
+		bnk.RemoveParameterOf(i, parameterLoopType)
 		return
 	}
+	var lbs [4]byte
+	binary.LittleEndian.PutUint32(lbs[:], loop.Value)
+	bnk.ReplaceParameterOf(i, parameterLoopType, lbs)
+}
 
-	wems := bnk.DataSection.Wems
-	if i < 0 || i >= len(wems) {
-		return
+// ParameterOf returns the value of the parameter of type paramType of the sound
+// object playing the wem stored in this SoundBank at index i. The returned bool
+// is false if the object does not have this parameter.
+func (bnk *File) ParameterOf(i int, paramType byte) ([4]byte, bool) {
+	object := bnk.soundObjectOf(i)
+	if object == nil {
+		return [4]byte{}, false
+	}
+	ss := object.Structure
+	for j, t := range ss.ParameterTypes {
+		if t == paramType {
+			return ss.ParameterValues[j], true
+		}
 	}
+	return [4]byte{}, false
+}
 
-	desc := bnk.DataSection.Wems[i].Descriptor
-	if bnk.ObjectSection == nil {
-		return
+// ParameterTypesOf returns the types of every parameter of the sound object
+// playing the wem stored in this SoundBank at index i, in the order they are
+// stored.
+func (bnk *File) ParameterTypesOf(i int) []byte {
+	object := bnk.soundObjectOf(i)
+	if object == nil {
+		return nil
 	}
+	return append([]byte(nil), object.Structure.ParameterTypes...)
+}
 
-	oldValue, oldLoops := bnk.ObjectSection.loopOf[desc.WemId]
-	// Return if the loop values aren't changing.
-	if oldLoops == false && loop.Loops == false || ((oldLoops == loop.Loops) &&
-		oldValue == loop.Value) {
+// ReplaceParameterOf sets the parameter of type paramType of the sound object
+// playing the wem stored in this SoundBank at index i to value. The parameter
+// is added to the object if it does not already have it. This method is
+// idempotent.
+func (bnk *File) ReplaceParameterOf(i int, paramType byte, value [4]byte) {
+	object := bnk.soundObjectOf(i)
+	if object == nil {
 		return
 	}
+	if paramType == parameterLoopType {
+		bnk.ObjectSection.loopOf[object.WemDescriptor.WemId] =
+			binary.LittleEndian.Uint32(value[:])
+	}
+	ss := object.Structure
+	for j, t := range ss.ParameterTypes {
+		if t == paramType {
+			// We are modifying the existing value of the parameter.
+			ss.ParameterValues[j] = value
+			return
+		}
+	}
 
-	// The HIRC object that maps to the target wem.
-	object, ok := bnk.ObjectSection.wemToObject[desc.WemId]
-	if !ok {
+	// We are adding a parameter that the audio object did not have.
+	ss.ParameterCount++
+	ss.ParameterTypes = append(ss.ParameterTypes, paramType)
+	ss.ParameterValues = append(ss.ParameterValues, value)
+
+	lengthIncrease := uint32(PARAMETER_TYPE_BYTES + PARAMETER_VALUE_BYTES)
+	bnk.ObjectSection.Header.Length += lengthIncrease
+	object.Descriptor.Length += lengthIncrease
+}
+
+// RemoveParameterOf removes the parameter of type paramType from the sound
+// object playing the wem stored in this SoundBank at index i, if the object has
+// this parameter.
+func (bnk *File) RemoveParameterOf(i int, paramType byte) {
+	object := bnk.soundObjectOf(i)
+	if object == nil {
 		return
 	}
-	// The sound structure that maps to the target wem.
+	if paramType == parameterLoopType {
+		delete(bnk.ObjectSection.loopOf, object.WemDescriptor.WemId)
+	}
 	ss := object.Structure
+	for j, t := range ss.ParameterTypes {
+		if t == paramType {
+			ss.ParameterCount--
+			ss.ParameterTypes =
+				append(ss.ParameterTypes[:j], ss.ParameterTypes[j+1:]...)
+			ss.ParameterValues =
+				append(ss.ParameterValues[:j], ss.ParameterValues[j+1:]...)
+
+			lengthDecrease := uint32(PARAMETER_TYPE_BYTES + PARAMETER_VALUE_BYTES)
+			bnk.ObjectSection.Header.Length -= lengthDecrease
+			object.Descriptor.Length -= lengthDecrease
+			return
+		}
+	}
+}
+
+// SetPaddingOf sets the number of bytes of padding that follow the wem stored
+// in this SoundBank at index i to padding, moving every later wem to account
+// for the change in size.
+func (bnk *File) SetPaddingOf(i int, padding int64) {
+	wems := bnk.Wems()
+	if i < 0 || i >= len(wems) {
+		return
+	}
+	surplus := padding - wems[i].Padding.Size()
+	if surplus == 0 {
+		return
+	}
+	wems[i].Padding = util.NewResettingReader(&util.InfiniteReaderAt{0}, 0,
+		padding)
+	for _, wem := range wems[i+1:] {
+		wem.Descriptor.Offset += uint32(surplus)
+	}
+	bnk.DataSection.Header.Length += uint32(surplus)
+}
+
+// ReplaceObjects replaces objects of the HIRC section of this SoundBank with
+// the objects serialized in data, by their index, where zero is the first
+// object. Each object must be complete, including its descriptor, as written by
+// its WriteTo method. The section is read again afterwards, so that the loop
+// values and parameters of the new objects take effect.
+func (bnk *File) ReplaceObjects(data map[int][]byte) error {
+	hrc := bnk.ObjectSection
+	if hrc == nil {
+		return errors.New("This SoundBank has no HIRC section")
+	}
+	for i := range data {
+		if i < 0 || i >= len(hrc.objects) {
+			return fmt.Errorf("%d is not a valid object index; the SoundBank has "+
+				"%d objects", i+1, len(hrc.objects))
+		}
+	}
+
+	buf := new(bytes.Buffer)
+	binary.Write(buf, binary.LittleEndian, hrc.ObjectCount)
+	for i, obj := range hrc.objects {
+		if d, ok := data[i]; ok {
+			buf.Write(d)
+			continue
+		}
+		_, err := obj.WriteTo(buf)
+		if err != nil {
+			return err
+		}
+	}
+	hdr := &SectionHeader{hrc.Header.Identifier, uint32(buf.Len())}
+	sr := util.NewResettingReader(bytes.NewReader(buf.Bytes()), 0,
+		int64(buf.Len()))
+	sec, err := hdr.NewObjectHierarchySection(sr)
+	if err != nil {
+		return err
+	}
+	for i, s := range bnk.sections {
+		if s == hrc {
+			bnk.sections[i] = sec
+		}
+	}
+	bnk.ObjectSection = sec
+	return nil
+}
+
+// soundObjectOf returns the HIRC sound object that plays the wem stored in this
+// SoundBank at index i, or nil if there is none.
+func (bnk *File) soundObjectOf(i int) *SfxVoiceSoundObject {
+	if bnk.DataSection == nil || bnk.ObjectSection == nil {
+		return nil
+	}
+	wems := bnk.DataSection.Wems
+	if i < 0 || i >= len(wems) {
+		return nil
+	}
+	return bnk.ObjectSection.wemToObject[wems[i].Descriptor.WemId]
+}
+
+// StreamedWemIds returns the IDs of the wems played by sound objects of this
+// SoundBank that are streamed from another file, such as a File Package,
+// rather than embedded in this SoundBank. IDs are in the order of their sound
+// objects.
+func (bnk *File) StreamedWemIds() []uint32 {
+	var ids []uint32
+	if bnk.ObjectSection == nil {
+		return ids
+	}
+	for _, obj := range bnk.ObjectSection.objects {
+		sound, ok := obj.(*SfxVoiceSoundObject)
+		if ok && !sound.Embedded() {
+			ids = append(ids, sound.WemDescriptor.WemId)
+		}
+	}
+	return ids
+}
+
+// Events returns every event stored in this SoundBank, in the order that they
+// appear in the HIRC section.
+func (bnk *File) Events() []*EventObject {
+	if bnk.ObjectSection == nil {
+		return nil
+	}
+	return bnk.ObjectSection.events
+}
+
+// EventWems returns the indexes of the wems stored in this SoundBank that are
+// played by the event e, in ascending order. A wem is played by e if one of its
+// play actions targets the wem's sound object, or any object containing it.
+func (bnk *File) EventWems(e *EventObject) []int {
+	var indexes []int
+	if bnk.ObjectSection == nil || bnk.DataSection == nil {
+		return indexes
+	}
+	hrc := bnk.ObjectSection
+
+	targets := make(map[uint32]bool)
+	for _, id := range e.ActionIds {
+		action, ok := hrc.actionOf[id]
+		if ok && action.ActionType == actionTypePlay {
+			targets[action.TargetId] = true
+		}
+	}
 
-	if loop.Loops == false {
-		// We are removing looping from an audio object that already has a loop.
-		for i, paramType := range ss.ParameterTypes {
-			if paramType == parameterLoopType {
-				ss.ParameterCount--
-				ss.ParameterTypes =
-					append(ss.ParameterTypes[:i], ss.ParameterTypes[i+1:]...)
-				ss.ParameterValues =
-					append(ss.ParameterValues[:i], ss.ParameterValues[i+1:]...)
-
-				lengthDecrease := uint32(PARAMETER_TYPE_BYTES + PARAMETER_VALUE_BYTES)
-				bnk.ObjectSection.Header.Length -= lengthDecrease
-				object.Descriptor.Length -= lengthDecrease
-
-				delete(bnk.ObjectSection.loopOf, desc.WemId)
-				return
+	for i, wem := range bnk.DataSection.Wems {
+		object, ok := hrc.wemToObject[wem.Descriptor.WemId]
+		if !ok {
+			continue
+		}
+		// Walk up the hierarchy from the sound, guarding against cycles in
+		// malformed files.
+		id := object.Descriptor.ObjectId
+		for depth := 0; id != 0 && depth <= len(hrc.parentOf); depth++ {
+			if targets[id] {
+				indexes = append(indexes, i)
+				break
 			}
+			id = hrc.parentOf[id]
+		}
+	}
+	return indexes
+}
+
+// EventPlayback returns what the event e plays, or nil if it plays none of the
+// wems stored in this SoundBank. Every object targeted by a play action of e
+// is played at once. Random and sequence containers play their children as
+// their playlist describes, switch containers play one of their children, and
+// blend containers play all of them at once. Other containers, and random or
+// sequence containers whose playlist cannot be read, play their children in
+// turn.
+func (bnk *File) EventPlayback(e *EventObject) *Playback {
+	if bnk.ObjectSection == nil || bnk.DataSection == nil {
+		return nil
+	}
+	hrc := bnk.ObjectSection
+
+	indexOf := make(map[uint32]int)
+	for i, wem := range bnk.DataSection.Wems {
+		indexOf[wem.Descriptor.WemId] = i
+	}
+	objectOf := make(map[uint32]Object)
+	childrenOf := make(map[uint32][]uint32)
+	for _, obj := range hrc.objects {
+		id := DescriptorOf(obj).ObjectId
+		objectOf[id] = obj
+		if parent, ok := hrc.parentOf[id]; ok {
+			childrenOf[parent] = append(childrenOf[parent], id)
+		}
+	}
+
+	// The objects being visited, guarding against cycles in malformed files.
+	visiting := make(map[uint32]bool)
+	var visit func(id uint32) *Playback
+	visit = func(id uint32) *Playback {
+		if visiting[id] {
+			return nil
 		}
-	} else {
-		var lbs [4]byte
-		binary.LittleEndian.PutUint32(lbs[:], loop.Value)
-		if oldLoops {
-			// We are modifying the existing loop value of an audio object.
-			for i, paramType := range ss.ParameterTypes {
-				if paramType == parameterLoopType {
-					ss.ParameterValues[i] = lbs
-					bnk.ObjectSection.loopOf[desc.WemId] = loop.Value
-					return
+		visiting[id] = true
+		defer delete(visiting, id)
+		switch obj := objectOf[id].(type) {
+		case *SfxVoiceSoundObject:
+			i, ok := indexOf[obj.WemDescriptor.WemId]
+			if !ok {
+				return nil
+			}
+			return &Playback{Wem: i}
+		case *UnknownObject:
+			p := &Playback{Wem: -1, Mode: PlaySequence}
+			children := childrenOf[id]
+			switch obj.Descriptor.Type {
+			case randomSequenceObjectId:
+				known := make(map[uint32]bool)
+				for _, child := range children {
+					known[child] = true
+				}
+				sequence, playlist, ok := obj.readPlaylist(known)
+				if ok {
+					children = playlist
+					if !sequence {
+						p.Mode = PlayRandom
+					}
+				}
+			case switchObjectId:
+				p.Mode = PlaySwitch
+			case blendObjectId:
+				p.Mode = PlayLayered
+			}
+			for _, child := range children {
+				if c := visit(child); c != nil {
+					p.Children = append(p.Children, c)
 				}
 			}
-		} else { // oldLoops == false
-			// We are adding looping to an audio object that did not loop.
-			ss := object.Structure
-			ss.ParameterCount++
-			ss.ParameterTypes = append(ss.ParameterTypes, parameterLoopType)
-			ss.ParameterValues = append(ss.ParameterValues, lbs)
-			bnk.ObjectSection.loopOf[desc.WemId] = loop.Value
-
-			lengthIncrease := uint32(PARAMETER_TYPE_BYTES + PARAMETER_VALUE_BYTES)
-			bnk.ObjectSection.Header.Length += lengthIncrease
-			object.Descriptor.Length += lengthIncrease
+			if len(p.Children) == 0 {
+				return nil
+			}
+			return p
+		}
+		return nil
+	}
+
+	root := &Playback{Wem: -1, Mode: PlayLayered}
+	for _, id := range e.ActionIds {
+		action, ok := hrc.actionOf[id]
+		if !ok || action.ActionType != actionTypePlay {
+			continue
+		}
+		if p := visit(action.TargetId); p != nil {
+			root.Children = append(root.Children, p)
+		}
+	}
+	switch len(root.Children) {
+	case 0:
+		return nil
+	case 1:
+		return root.Children[0]
+	}
+	return root
+}
+
+// Listing returns a structured description of the sections, wems and HIRC
+// objects of this SoundBank.
+func (bnk *File) Listing() *wwise.Listing {
+	l := wwise.NewListing(bnk, wwise.SoundBankType)
+	for i, wem := range l.Wems {
+		if bnk.ObjectSection != nil {
+			loop := bnk.LoopOf(i)
+			wem.Loop = &loop
+		}
+	}
+
+	offset := int64(0)
+	for _, sec := range bnk.sections {
+		hdr := HeaderOf(sec)
+		l.Sections = append(l.Sections,
+			&wwise.SectionListing{string(hdr.Identifier[:]), offset, hdr.Length})
+		offset += SECTION_HEADER_BYTES + int64(hdr.Length)
+	}
+	l.Size = offset
+
+	if bnk.ObjectSection == nil {
+		return l
+	}
+	for _, obj := range bnk.ObjectSection.objects {
+		desc := DescriptorOf(obj)
+		o := &wwise.ObjectListing{
+			Id:       desc.ObjectId,
+			Type:     desc.Type,
+			TypeName: ObjectTypeName(desc.Type),
+			Length:   desc.Length,
+			ParentId: bnk.ObjectSection.parentOf[desc.ObjectId],
+		}
+		if sound, ok := obj.(*SfxVoiceSoundObject); ok {
+			o.WemId = sound.WemDescriptor.WemId
+		}
+		h := sha256.New()
+		obj.WriteTo(h)
+		o.Hash = hex.EncodeToString(h.Sum(nil))
+		l.Objects = append(l.Objects, o)
+	}
+	return l
+}
+
+// Verify checks that this SoundBank is internally consistent: that every wem
+// lies within the DATA section, in order and aligned to 16 bytes, that wem IDs
+// are unique, that the lengths of the HIRC objects add up to the length of the
+// section, and that every sound embedded in this SoundBank plays a wem it
+// stores, with the length it is stored with.
+func (bnk *File) Verify() []*wwise.Problem {
+	var problems []*wwise.Problem
+	report := func(offset int64, format string, a ...interface{}) {
+		problems = append(problems, wwise.NewProblem(offset, format, a...))
+	}
+
+	offsets := make(map[Section]int64)
+	offset := int64(0)
+	for _, sec := range bnk.sections {
+		offsets[sec] = offset
+		offset += SECTION_HEADER_BYTES + int64(HeaderOf(sec).Length)
+	}
+	if bnk.BankHeaderSection == nil {
+		report(0, "There is no BKHD section")
+	}
+
+	idx := bnk.IndexSection
+	if idx != nil {
+		start := offsets[idx]
+		if idx.Header.Length%DIDX_ENTRY_BYTES != 0 {
+			report(start, "The DIDX section is %d bytes, which is not a multiple "+
+				"of the %d byte entry size", idx.Header.Length, DIDX_ENTRY_BYTES)
+		}
+		seen := make(map[uint32]bool)
+		for i, id := range idx.WemIds {
+			if seen[id] {
+				report(start+SECTION_HEADER_BYTES+int64(i*DIDX_ENTRY_BYTES),
+					"Wem %d has the same id, %d, as an earlier wem", i+1, id)
+			}
+			seen[id] = true
+		}
+	}
+
+	if data := bnk.DataSection; data != nil && idx != nil {
+		entryOffset := func(i int) int64 {
+			return offsets[idx] + SECTION_HEADER_BYTES +
+				int64(i*DIDX_ENTRY_BYTES)
+		}
+		end := uint32(0)
+		for i, wem := range data.Wems {
+			desc := wem.Descriptor
+			switch {
+			case desc.Offset%wemAlignmentBytes != 0:
+				report(entryOffset(i), "Wem %d (id %d) starts at offset %d of the "+
+					"DATA section, which is not aligned to %d bytes", i+1, desc.WemId,
+					desc.Offset, wemAlignmentBytes)
+			case i > 0 && desc.Offset < end:
+				report(entryOffset(i), "Wem %d (id %d) starts at offset %d of the "+
+					"DATA section, before the previous wem ends at offset %d", i+1,
+					desc.WemId, desc.Offset, end)
+			}
+			if int64(desc.Offset)+int64(desc.Length) > int64(data.Header.Length) {
+				report(entryOffset(i), "Wem %d (id %d) ends at offset %d, past the "+
+					"end of the %d byte DATA section", i+1, desc.WemId,
+					int64(desc.Offset)+int64(desc.Length), data.Header.Length)
+			}
+			end = desc.Offset + desc.Length
 		}
+		problems = append(problems, wwise.VerifyReadable(bnk, func(i int) int64 {
+			return int64(data.DataStart) + int64(data.Wems[i].Descriptor.Offset)
+		})...)
+	}
+
+	if hrc := bnk.ObjectSection; hrc != nil {
+		problems = append(problems, bnk.verifyObjects(offsets[hrc])...)
+	}
+	wwise.SortProblems(problems)
+	return problems
+}
+
+// verifyObjects checks the objects of the HIRC section, which starts at offset
+// start.
+func (bnk *File) verifyObjects(start int64) []*wwise.Problem {
+	var problems []*wwise.Problem
+	report := func(offset int64, format string, a ...interface{}) {
+		problems = append(problems, wwise.NewProblem(offset, format, a...))
+	}
+	hrc := bnk.ObjectSection
+	// The length of every wem stored in this SoundBank, by its ID.
+	stored := make(map[uint32]uint32)
+	for _, wem := range bnk.Wems() {
+		stored[wem.Descriptor.WemId] = wem.Descriptor.Length
+	}
+
+	offset := start + SECTION_HEADER_BYTES + OBJECT_COUNT_BYTES
+	total := int64(OBJECT_COUNT_BYTES)
+	for i, obj := range hrc.objects {
+		desc := DescriptorOf(obj)
+		// The length of an object covers its ID and data, but not its type or
+		// the length itself.
+		length := int64(OBJECT_DESCRIPTOR_BYTES-OBJECT_DESCRIPTOR_ID_BYTES) +
+			int64(desc.Length)
+		n, err := obj.WriteTo(ioutil.Discard)
+		switch {
+		case err != nil:
+			report(offset, "Object %d (id %d) could not be read: %s", i+1,
+				desc.ObjectId, err)
+		case n != length:
+			report(offset, "Object %d (id %d) declares %d bytes, but its contents "+
+				"take up %d bytes", i+1, desc.ObjectId, length, n)
+		}
+		if sound, ok := obj.(*SfxVoiceSoundObject); ok && sound.Embedded() {
+			wem := sound.WemDescriptor
+			storedLength, ok := stored[wem.WemId]
+			switch {
+			case !ok:
+				report(offset, "Sound %d embeds wem %d, which is not stored in this "+
+					"SoundBank", desc.ObjectId, wem.WemId)
+			case wem.WemLength != storedLength:
+				report(offset, "Sound %d gives the length of wem %d as %d bytes, "+
+					"but it is %d bytes", desc.ObjectId, wem.WemId, wem.WemLength,
+					storedLength)
+			}
+		}
+		offset += length
+		total += length
+	}
+	if total != int64(hrc.Header.Length) {
+		report(start, "The HIRC section is %d bytes, but its objects take up %d "+
+			"bytes", hrc.Header.Length, total)
 	}
+	return problems
 }
 
 func (bnk *File) String() string {
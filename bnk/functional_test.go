@@ -4,6 +4,10 @@ package bnk
 // Large system tests for the bnk package.
 import (
 	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 	"testing"
@@ -85,6 +89,98 @@ func TestReplaceWemCases(t *testing.T) {
 	}
 }
 
+func TestReplaceWemUpdatesMusicTracks(t *testing.T) {
+	util.SkipIfShort(t)
+
+	bnk, err := Open(filepath.Join(testDir, complexSoundBank))
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	defer bnk.Close()
+	desc := bnk.Wems()[0].Descriptor
+	id, length := desc.WemId, desc.Length
+
+	// The playlist that follows the sources of a track holds bytes that look
+	// like a source of the replaced wem, which must be left alone.
+	lookalike := []byte{streamSettingEmbedded, 0, 0, 0, 0, 0, 0, 0, 0, 0}
+	binary.LittleEndian.PutUint32(lookalike[1:], id)
+	binary.LittleEndian.PutUint32(lookalike[5:], length)
+	track := musicTrack(1, musicTrackData([][2]uint32{{id + 1, length},
+		{id, length}}, lookalike))
+	// A track declaring more sources than it holds has an unknown layout, and
+	// must not be changed at all.
+	data := musicTrackData([][2]uint32{{id, length}}, nil)
+	binary.LittleEndian.PutUint32(data[1:], 5)
+	unknown := musicTrack(2, data)
+	for _, tr := range []*musicTrackTest{track, unknown} {
+		bnk.ObjectSection.objects = append(bnk.ObjectSection.objects, tr.object)
+	}
+
+	newLength := length + 100
+	replacement := make([]byte, newLength)
+	bnk.ReplaceWems(&wwise.ReplacementWem{bytes.NewReader(replacement), 0,
+		int64(newLength)})
+
+	got := track.written(t)
+	expect := append([]byte(nil), track.data...)
+	binary.LittleEndian.PutUint32(
+		expect[MUSIC_TRACK_HEADER_BYTES+MUSIC_TRACK_SOURCE_BYTES+9:], newLength)
+	if !bytes.Equal(got, expect) {
+		t.Errorf("Expected only the source of wem %d to give its new length "+
+			"%d:\n% X\nbut got:\n% X", id, newLength, expect, got)
+	}
+	if got := unknown.written(t); !bytes.Equal(got, unknown.data) {
+		t.Error("Expected a music track of an unknown layout not to be changed")
+	}
+	if sound := bnk.soundObjectOf(0); sound.WemDescriptor.WemLength != newLength {
+		t.Errorf("Expected the sound object to give the new length %d of wem %d, "+
+			"but got %d", newLength, id, sound.WemDescriptor.WemLength)
+	}
+}
+
+// A musicTrackTest is a music track added to a SoundBank by a test.
+type musicTrackTest struct {
+	object *UnknownObject
+	// The data of the track when it was created.
+	data []byte
+}
+
+// musicTrack returns a music track with the ID id holding data.
+func musicTrack(id uint32, data []byte) *musicTrackTest {
+	track := &UnknownObject{
+		&ObjectDescriptor{musicTrackObjectId, uint32(4 + len(data)), id},
+		util.NewResettingReader(bytes.NewReader(data), 0, int64(len(data)))}
+	return &musicTrackTest{track, append([]byte(nil), data...)}
+}
+
+// musicTrackData returns the data of a music track holding an embedded source
+// for each wem ID and length of sources, followed by rest.
+func musicTrackData(sources [][2]uint32, rest []byte) []byte {
+	data := []byte{0, 0, 0, 0, 0}
+	binary.LittleEndian.PutUint32(data[1:], uint32(len(sources)))
+	for _, s := range sources {
+		// The plugin ID, stream type, wem ID, wem length and source bits.
+		source := make([]byte, MUSIC_TRACK_SOURCE_BYTES)
+		binary.LittleEndian.PutUint32(source, 0x00040001)
+		source[4] = streamSettingEmbedded
+		binary.LittleEndian.PutUint32(source[5:], s[0])
+		binary.LittleEndian.PutUint32(source[9:], s[1])
+		data = append(data, source...)
+	}
+	return append(data, rest...)
+}
+
+// written returns the data of the track as it is written.
+func (tr *musicTrackTest) written(t *testing.T) []byte {
+	b := new(bytes.Buffer)
+	_, err := tr.object.WriteTo(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()[OBJECT_DESCRIPTOR_BYTES:]
+}
+
 func TestReplaceLoopOfCases(t *testing.T) {
 	util.SkipIfShort(t)
 
@@ -185,5 +281,571 @@ func assertReplacedFileCorrectness(t *testing.T, bnkPath string,
 			"only reports %d bytes", actualLength, expectedLength)
 		failed = true
 	}
+	// The sound objects embedding the replaced wems must give their new lengths.
+	for _, p := range reread.Verify() {
+		t.Errorf("The replaced file is inconsistent: %s", p)
+		failed = true
+	}
 	return
 }
+
+func TestValidateReplacement(t *testing.T) {
+	util.SkipIfShort(t)
+
+	bnk, err := Open(filepath.Join(testDir, complexSoundBank))
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	wems := bnk.Wems()
+
+	// A wem replaced by itself is always valid.
+	same := &wwise.ReplacementWem{wems[1], 0, int64(wems[1].Descriptor.Length)}
+	if issues := wwise.ValidateReplacement(wems[0], same); len(issues) != 0 {
+		t.Errorf("Expected no issues when replacing with a valid wem, got: %v",
+			issues)
+	}
+
+	// A truncated wem should be rejected.
+	truncated := &wwise.ReplacementWem{wems[1], 0,
+		int64(wems[1].Descriptor.Length) / 2}
+	if !wwise.HasErrors(wwise.ValidateReplacement(wems[0], truncated)) {
+		t.Error("Expected a truncated replacement to fail validation")
+	}
+
+	// Arbitrary bytes are not a wem at all.
+	garbage := &wwise.ReplacementWem{util.NewConstantReader(512), 0, 512}
+	if !wwise.HasErrors(wwise.ValidateReplacement(wems[0], garbage)) {
+		t.Error("Expected a non-RIFF replacement to fail validation")
+	}
+}
+
+func TestDetectCodec(t *testing.T) {
+	util.SkipIfShort(t)
+
+	bnk, err := Open(filepath.Join(testDir, complexSoundBank))
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	for i, wem := range bnk.Wems() {
+		codec, err := wwise.DetectCodec(wem, int64(wem.Descriptor.Length))
+		if err != nil {
+			t.Errorf("Could not detect the codec of the wem at index %d: %s", i,
+				err)
+			continue
+		}
+		if codec != wwise.VorbisCodec {
+			t.Errorf("The wem at index %d was expected to be %s, but was %s", i,
+				wwise.VorbisCodec, codec)
+		}
+		_, err = wwise.Extract(new(bytes.Buffer), wem, int64(wem.Descriptor.Length))
+		if err != wwise.ErrExtractionUnsupported {
+			t.Errorf("Extracting the Vorbis wem at index %d should be unsupported, "+
+				"but got: %v", i, err)
+		}
+	}
+}
+
+func TestInspectWem(t *testing.T) {
+	util.SkipIfShort(t)
+
+	bnk, err := Open(filepath.Join(testDir, simpleSoundBank))
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	defer bnk.Close()
+	wem := bnk.Wems()[0]
+	hdr, err := wwise.InspectWem(wem, int64(wem.Descriptor.Length))
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	fields := inspectedFields(hdr.Chunks[0])
+	for _, expect := range []wwise.ChunkField{
+		{"FormatTag", 0x14, "0xFFFF (vorbis)"},
+		{"Channels", 0x16, "2"},
+		{"SampleRate", 0x18, "44100"},
+		{"AvgBytesPerSecond", 0x1C, "12310"},
+		{"ChannelConfig", 0x28, "0x00003102 (channels: 2, type: 1, mask: 0x3)"},
+		{"SampleCount", 0x2C, "267264"},
+		{"SetupPacketOffset", 0x3C, "64"},
+		{"FirstAudioPacketOffset", 0x40, "281"},
+		{"Uid", 0x50, "0xD54BA8E8"},
+		{"BlockSizes", 0x54, "256, 2048"},
+	} {
+		actual, ok := fields[expect.Name]
+		if !ok || *actual != expect {
+			t.Errorf("Expected the fmt field %+v but got %+v", expect, actual)
+		}
+	}
+
+	// Cut the fmt chunk short, so that only the fields that fit within it can
+	// be decoded.
+	data, err := ioutil.ReadAll(io.NewSectionReader(wem, 0,
+		int64(wem.Descriptor.Length)))
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	const fmtLength = 0x16
+	truncated := data[:wwise.RIFF_HEADER_BYTES+wwise.CHUNK_HEADER_BYTES+
+		fmtLength]
+	binary.LittleEndian.PutUint32(truncated[4:], uint32(len(truncated)-8))
+	binary.LittleEndian.PutUint32(truncated[wwise.RIFF_HEADER_BYTES+4:],
+		fmtLength)
+	hdr, err = wwise.InspectWem(bytes.NewReader(truncated),
+		int64(len(truncated)))
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	fields = inspectedFields(hdr.Chunks[0])
+	if _, ok := fields["SamplesPerBlock"]; !ok {
+		t.Error("Expected the fields within the truncated fmt chunk to be decoded")
+	}
+	for _, name := range []string{"ChannelConfig", "SampleCount", "Uid"} {
+		if _, ok := fields[name]; ok {
+			t.Errorf("Expected %s, which is past the end of the truncated fmt "+
+				"chunk, not to be decoded", name)
+		}
+	}
+}
+
+// inspectedFields returns the decoded fields of c, by their name.
+func inspectedFields(c *wwise.RiffChunk) map[string]*wwise.ChunkField {
+	fields := make(map[string]*wwise.ChunkField)
+	for _, f := range c.Fields {
+		fields[f.Name] = f
+	}
+	return fields
+}
+
+func TestEventWems(t *testing.T) {
+	util.SkipIfShort(t)
+
+	bnk, err := Open(filepath.Join(testDir, complexSoundBank))
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	if len(bnk.Events()) == 0 {
+		t.Error("Expected the SoundBank to contain events")
+	}
+	for _, e := range bnk.Events() {
+		if len(bnk.EventWems(e)) == 0 {
+			t.Errorf("Event %d was expected to play at least one wem",
+				e.Descriptor.ObjectId)
+		}
+	}
+}
+
+func TestEventPlayback(t *testing.T) {
+	util.SkipIfShort(t)
+
+	b, err := Open(filepath.Join(testDir, complexSoundBank))
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	// The number of containers found of each mode, with more than one child.
+	modes := make(map[PlaybackMode]int)
+	var wemsOf func(p *Playback, wems map[int]bool)
+	wemsOf = func(p *Playback, wems map[int]bool) {
+		if p.Wem >= 0 {
+			wems[p.Wem] = true
+			return
+		}
+		if len(p.Children) > 1 {
+			modes[p.Mode]++
+		}
+		for _, c := range p.Children {
+			wemsOf(c, wems)
+		}
+	}
+	for _, e := range b.Events() {
+		id := e.Descriptor.ObjectId
+		p := b.EventPlayback(e)
+		if p == nil {
+			t.Errorf("Event %d was expected to play at least one wem", id)
+			continue
+		}
+		wems := make(map[int]bool)
+		wemsOf(p, wems)
+		expected := b.EventWems(e)
+		if len(wems) != len(expected) {
+			t.Errorf("Event %d was expected to play %d wems but plays %d", id,
+				len(expected), len(wems))
+		}
+		for _, i := range expected {
+			if !wems[i] {
+				t.Errorf("Event %d was expected to play wem %d", id, i+1)
+			}
+		}
+	}
+	for _, mode := range []PlaybackMode{PlaySequence, PlayRandom} {
+		if modes[mode] == 0 {
+			t.Errorf("Expected a container of mode %d with several children",
+				mode)
+		}
+	}
+}
+
+func TestParsedLoopValueCases(t *testing.T) {
+	util.SkipIfShort(t)
+
+	cases := map[string]string{
+		"none":     loopNoneSoundBank,
+		"23":       loop23SoundBank,
+		"infinite": loopInfinitySoundBank,
+		"2":        loop2SoundBank,
+	}
+
+	for value, expected := range cases {
+		bnk, err := Open(filepath.Join(testDir, loop2SoundBank))
+		if err != nil {
+			t.Error(err)
+			t.FailNow()
+		}
+		if !bnk.CanLoop(0) {
+			t.Errorf("Expected the loop value of wem 0 to be editable")
+		}
+		loop, err := wwise.ParseLoopValue(value)
+		if err != nil {
+			t.Error(err)
+			t.FailNow()
+		}
+		bnk.ReplaceLoopOf(0, loop)
+
+		expect, err := os.Open(filepath.Join(testDir, expected))
+		if err != nil {
+			t.Error(err)
+			t.FailNow()
+		}
+
+		wwise.AssertContainerEqualToFile(t, expect, bnk)
+	}
+
+	for _, value := range []string{"0", "1", "-2", "forever"} {
+		if _, err := wwise.ParseLoopValue(value); err == nil {
+			t.Errorf("Expected %q to be an invalid loop value", value)
+		}
+	}
+}
+
+func TestListing(t *testing.T) {
+	util.SkipIfShort(t)
+
+	path := filepath.Join(testDir, complexSoundBank)
+	bnk, err := Open(path)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	stat, err := os.Stat(path)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+
+	l := bnk.Listing()
+	last := l.Sections[len(l.Sections)-1]
+	if end := last.Offset + SECTION_HEADER_BYTES + int64(last.Length); end !=
+		stat.Size() {
+		t.Errorf("Expected the sections to end at %d but they end at %d",
+			stat.Size(), end)
+	}
+	if len(l.Objects) != int(bnk.ObjectSection.ObjectCount) {
+		t.Errorf("Expected %d objects to be listed but got %d",
+			bnk.ObjectSection.ObjectCount, len(l.Objects))
+	}
+	for _, wem := range l.Wems {
+		if wem.Loop == nil {
+			t.Errorf("Expected wem %d to have a loop value", wem.Index)
+		}
+	}
+}
+
+func TestSelection(t *testing.T) {
+	util.SkipIfShort(t)
+
+	bnk, err := Open(filepath.Join(testDir, complexSoundBank))
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	wems := bnk.Wems()
+	ranges, err := wwise.ParseIndexRanges("2-3,5-")
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	var tests = []struct {
+		name string
+		sel  *wwise.Selection
+		want []int
+	}{
+		{"empty", &wwise.Selection{}, nil},
+		{"ranges", &wwise.Selection{Ranges: ranges}, nil},
+		{"ids", &wwise.Selection{Ids: []uint32{wems[1].Descriptor.WemId}},
+			[]int{1}},
+		{"ranges and ids", &wwise.Selection{Ranges: ranges[:1],
+			Ids: []uint32{wems[0].Descriptor.WemId}}, []int{}},
+		{"pattern", &wwise.Selection{Patterns: []string{
+			util.CanonicalWemName(0, len(wems))}}, []int{0}},
+		{"codec", &wwise.Selection{Codecs: []wwise.Codec{wwise.OpusCodec}},
+			[]int{}},
+		{"language", &wwise.Selection{Languages: []string{"sfx"}}, []int{}},
+	}
+	for _, test := range tests {
+		got, err := test.sel.Select(bnk)
+		if err != nil {
+			t.Errorf("%s: %s", test.name, err)
+			continue
+		}
+		want := test.want
+		if want == nil {
+			// Compute the expected indexes from the ranges alone.
+			for i := range wems {
+				if len(test.sel.Ranges) == 0 || ranges[0].Contains(i+1) ||
+					ranges[1].Contains(i+1) {
+					want = append(want, i)
+				}
+			}
+		}
+		if len(got) != len(want) {
+			t.Errorf("%s: expected indexes %v but got %v", test.name, want, got)
+			continue
+		}
+		for i := range got {
+			if got[i] != want[i] {
+				t.Errorf("%s: expected indexes %v but got %v", test.name, want, got)
+				break
+			}
+		}
+	}
+
+	_, err = wwise.ParseIndexRanges("3-1")
+	if err == nil {
+		t.Error("Expected a descending index range to be invalid")
+	}
+}
+
+func TestDiff(t *testing.T) {
+	util.SkipIfShort(t)
+
+	old, err := Open(filepath.Join(testDir, loopNoneSoundBank))
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	patched, err := Open(filepath.Join(testDir, loop2SoundBank))
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+
+	d, err := wwise.Diff(old, old)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	if !d.Empty() {
+		t.Errorf("Expected a SoundBank to be identical to itself, but got:\n%s", d)
+	}
+
+	d, err = wwise.Diff(old, patched)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	if len(d.Wems) != 1 || d.Wems[0].Change != wwise.Changed ||
+		d.Wems[0].Old.Hash != d.Wems[0].New.Hash ||
+		*d.Wems[0].New.Loop != (wwise.LoopValue{true, 2}) {
+		t.Errorf("Expected only the loop value of the wem to change, but got:\n%s",
+			d)
+	}
+	if len(d.ObjectTypes) != 1 || d.ObjectTypes[0].Changed != 1 {
+		t.Errorf("Expected a single sound object to change, but got:\n%s", d)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	util.SkipIfShort(t)
+
+	for _, name := range []string{simpleSoundBank, complexSoundBank,
+		loopNoneSoundBank, loop2SoundBank, loop23SoundBank,
+		loopInfinitySoundBank} {
+		bnk, err := Open(filepath.Join(testDir, name))
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		if problems := bnk.Verify(); problems != nil {
+			t.Errorf("%s: expected no problems but got %v", name, problems)
+		}
+		bnk.Close()
+	}
+
+	org, err := ioutil.ReadFile(filepath.Join(testDir, complexSoundBank))
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	// The second entry of the DIDX, whose fields are its ID, offset and length.
+	entry := bytes.Index(org, didxHeaderId[:]) + SECTION_HEADER_BYTES +
+		DIDX_ENTRY_BYTES
+
+	data := append([]byte(nil), org...)
+	copy(data[entry:entry+4], data[entry-DIDX_ENTRY_BYTES:])
+	_, err = NewFile(bytes.NewReader(data))
+	if err == nil {
+		t.Error("Expected a repeated wem ID in the DIDX to be an error")
+	}
+
+	data = append([]byte(nil), org...)
+	offset := binary.LittleEndian.Uint32(data[entry+4:])
+	binary.LittleEndian.PutUint32(data[entry+4:], offset+1)
+	bnk, err := NewFile(bytes.NewReader(data))
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	problems := bnk.Verify()
+	if len(problems) == 0 || problems[0].Offset != int64(entry) {
+		t.Errorf("Expected a misaligned wem to be reported at offset %d, but "+
+			"got %v", entry, problems)
+	}
+
+	bnk, err = NewFile(bytes.NewReader(org))
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	bnk.ObjectSection.Header.Length += 4
+	if problems := bnk.Verify(); len(problems) != 1 {
+		t.Errorf("Expected the HIRC section length to be reported, but got %v",
+			problems)
+	}
+}
+
+func TestRepair(t *testing.T) {
+	util.SkipIfShort(t)
+
+	for _, name := range []string{simpleSoundBank, complexSoundBank,
+		loopNoneSoundBank, loop23SoundBank} {
+		org, err := ioutil.ReadFile(filepath.Join(testDir, name))
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		assertRepaired(t, name, org, org, 0)
+	}
+
+	org, err := ioutil.ReadFile(filepath.Join(testDir, complexSoundBank))
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	didx := bytes.Index(org, didxHeaderId[:]) + SECTION_HEADER_BYTES
+	data := bytes.Index(org, dataHeaderId[:])
+	// The offset and length of the second and third DIDX entries.
+	offset := didx + DIDX_ENTRY_BYTES + 4
+	length := didx + 2*DIDX_ENTRY_BYTES + 8
+
+	var corruptions = []struct {
+		name  string
+		field int
+		delta int32
+	}{
+		{"DATA length", data + 4, 40},
+		{"DATA length", data + 4, -40},
+		{"wem offset", offset, 16},
+		{"wem length", length, -100},
+	}
+	for _, c := range corruptions {
+		corrupt := append([]byte(nil), org...)
+		value := int32(binary.LittleEndian.Uint32(corrupt[c.field:]))
+		binary.LittleEndian.PutUint32(corrupt[c.field:], uint32(value+c.delta))
+		assertRepaired(t, fmt.Sprintf("%s %+d", c.name, c.delta), corrupt, org, 1)
+	}
+
+	// The replaced wem is longer than the sound object embedding it gives.
+	stale, err := ioutil.ReadFile(filepath.Join(testDir,
+		"0_replaced_with_larger.bnk"))
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	bnk, corrections, err := Repair(bytes.NewReader(stale))
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	if len(corrections) != 1 {
+		t.Errorf("Expected the wem length of a sound object to be corrected, but "+
+			"got %v", corrections)
+	}
+	if problems := bnk.Verify(); problems != nil {
+		t.Errorf("Expected the repaired SoundBank to be consistent, but got %v",
+			problems)
+	}
+
+	// A music track gives a stale length for an embedded wem.
+	complex, err := NewFile(bytes.NewReader(org))
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	desc := complex.Wems()[0].Descriptor
+	sources := [][2]uint32{{desc.WemId, desc.Length - 100}}
+	stale = withMusicTrack(t, org, musicTrackData(sources, nil))
+	sources[0][1] = desc.Length
+	assertRepaired(t, "music track", stale,
+		withMusicTrack(t, org, musicTrackData(sources, nil)), 1)
+}
+
+// withMusicTrack returns the SoundBank org with a music track holding data
+// added to the end of its HIRC section.
+func withMusicTrack(t *testing.T, org, data []byte) []byte {
+	bnk, err := NewFile(bytes.NewReader(org))
+	if err != nil {
+		t.Fatal(err)
+	}
+	hrc := bnk.ObjectSection
+	track := musicTrack(1, data)
+	hrc.objects = append(hrc.objects, track.object)
+	hrc.ObjectCount++
+	hrc.Header.Length += OBJECT_DESCRIPTOR_BYTES + uint32(len(data))
+	b := new(bytes.Buffer)
+	if _, err = bnk.WriteTo(b); err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}
+
+// assertRepaired repairs the SoundBank org, and checks that the expected number
+// of corrections are made, and that the repaired SoundBank is written as
+// expect.
+func assertRepaired(t *testing.T, name string, org, expect []byte,
+	corrections int) {
+	bnk, got, err := Repair(bytes.NewReader(org))
+	if err != nil {
+		t.Errorf("%s: %s", name, err)
+		return
+	}
+	if len(got) != corrections {
+		t.Errorf("%s: expected %d correction(s) but got %v", name, corrections,
+			got)
+	}
+	written := new(bytes.Buffer)
+	_, err = bnk.WriteTo(written)
+	if err != nil {
+		t.Errorf("%s: %s", name, err)
+		return
+	}
+	if !bytes.Equal(written.Bytes(), expect) {
+		t.Errorf("%s: the repaired SoundBank was not written as expected", name)
+	}
+}
@@ -0,0 +1,234 @@
+// Package bnk implements access to the Wwise SoundBank file format.
+package bnk
+
+// Fuzz tests for the parsers of the bnk package, seeded from the SoundBanks
+// of the testdata directory.
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"path/filepath"
+	"testing"
+)
+
+import (
+	"github.com/hpxro7/wwiseutil/util"
+	"github.com/hpxro7/wwiseutil/wwise"
+)
+
+// The number of generated SoundBanks added to the seed corpus.
+const generatedSeeds = 8
+
+func FuzzNewFile(f *testing.F) {
+	for _, data := range seedSoundBanks(f) {
+		f.Add(data)
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		bnk, err := NewFile(bytes.NewReader(data))
+		if err != nil {
+			return
+		}
+		bnk.Verify()
+		bnk.Listing()
+		for i := range bnk.Wems() {
+			bnk.LoopOf(i)
+			bnk.ParameterTypesOf(i)
+		}
+		assertWriteTo(t, bnk)
+	})
+}
+
+func FuzzNewSfxVoiceSoundObject(f *testing.F) {
+	for _, sound := range seedSounds(f) {
+		f.Add(sound.Descriptor.Length, objectData(f, sound))
+	}
+	f.Fuzz(func(t *testing.T, length uint32, data []byte) {
+		desc := &ObjectDescriptor{soundObjectId, length, 0}
+		sound, err := desc.NewSfxVoiceSoundObject(newTestReader(data))
+		if err != nil {
+			return
+		}
+		assertWriteTo(t, sound)
+	})
+}
+
+func FuzzNewSoundStructure(f *testing.F) {
+	for _, sound := range seedSounds(f) {
+		// The structure follows the unknown bytes, wem descriptor and type of
+		// the sound.
+		skip := SFX_UNKNOWN_BYTES + OPTIONAL_WEM_DESCRIPTOR_BYTES + 1
+		data := objectData(f, sound)[skip:]
+		f.Add(int64(len(data)), data)
+	}
+	f.Fuzz(func(t *testing.T, length int64, data []byte) {
+		ss, err := NewSoundStructure(newTestReader(data), length)
+		if err != nil {
+			return
+		}
+		assertWriteTo(t, ss)
+	})
+}
+
+func FuzzNewEffectContainer(f *testing.F) {
+	for _, sound := range seedSounds(f) {
+		skip := SFX_UNKNOWN_BYTES + OPTIONAL_WEM_DESCRIPTOR_BYTES + 1 +
+			OVERRIDE_EFFECTS_BYTES
+		f.Add(objectData(f, sound)[skip:])
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		ctr, err := NewEffectContainer(newTestReader(data))
+		if err != nil {
+			return
+		}
+		assertWriteTo(t, ctr)
+	})
+}
+
+func TestCorruptLengths(t *testing.T) {
+	// An event whose count would allocate gigabytes of action IDs.
+	event := &ObjectDescriptor{eventObjectId, 0xFFFFFFFC, 1}
+	_, err := event.NewEventObject(newTestReader([]byte{0xFD, 0xFF, 0xFF, 0x3F,
+		1, 2, 3, 4}))
+	if err == nil {
+		t.Error("Expected an event with too many actions to be rejected")
+	}
+
+	// A sound declaring fewer bytes than its effects and parameters take.
+	data := objectData(t, seedSounds(t)[0])
+	sound := &ObjectDescriptor{soundObjectId, OBJECT_DESCRIPTOR_ID_BYTES + 8, 1}
+	_, err = sound.NewSfxVoiceSoundObject(newTestReader(data))
+	if err == nil {
+		t.Error("Expected a sound shorter than its parameters to be rejected")
+	}
+
+	// An object declaring fewer bytes than its id takes.
+	unknown := &ObjectDescriptor{0x01, 2, 1}
+	_, err = unknown.NewUnknownObject(newTestReader(data))
+	if err == nil {
+		t.Error("Expected an object shorter than its id to be rejected")
+	}
+
+	// A BKHD section declaring fewer bytes than its descriptor takes.
+	bkhd := &SectionHeader{bkhdHeaderId, 4}
+	_, err = bkhd.NewBankHeaderSection(newTestReader(data))
+	if err == nil {
+		t.Error("Expected a BKHD section shorter than its descriptor to be " +
+			"rejected")
+	}
+
+	// A section declaring more bytes than the file holds.
+	section := &SectionHeader{[4]byte{'S', 'T', 'I', 'D'}, uint32(len(data) + 1)}
+	_, err = section.NewUnknownSection(newTestReader(data))
+	if err == nil {
+		t.Error("Expected a section running past the end of the file to be " +
+			"rejected")
+	}
+
+	// A DATA section whose last wem is followed by more padding than the file
+	// holds.
+	idx := &DataIndexSection{WemCount: 1, WemIds: []uint32{1},
+		DescriptorMap: map[uint32]*wwise.WemDescriptor{
+			1: {WemId: 1, Offset: 0, Length: 4}}}
+	dataSection := &SectionHeader{dataHeaderId, uint32(len(data) + 16)}
+	_, err = dataSection.NewDataSection(newTestReader(data), idx)
+	if err == nil {
+		t.Error("Expected padding running past the end of the file to be " +
+			"rejected")
+	}
+}
+
+func TestOverlappingWems(t *testing.T) {
+	for _, org := range seedSoundBanks(t) {
+		bnk, err := NewFile(bytes.NewReader(org))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(bnk.Wems()) < 2 {
+			continue
+		}
+		// Lengthen the first wem so that it runs into the second, which would
+		// otherwise leave it with a negative, unbounded amount of padding.
+		var didx int64
+		for _, sec := range bnk.Listing().Sections {
+			if sec.Id == string(didxHeaderId[:]) {
+				didx = sec.Offset
+			}
+		}
+		next := bnk.Wems()[1].Descriptor.Offset
+		corrupt := append([]byte(nil), org...)
+		binary.LittleEndian.PutUint32(corrupt[didx+SECTION_HEADER_BYTES+8:],
+			next+1)
+		_, err = NewFile(bytes.NewReader(corrupt))
+		if err == nil {
+			t.Error("Expected a wem overlapping the next wem to be rejected")
+		}
+		return
+	}
+	t.Fatal("No seed SoundBank holds more than one wem")
+}
+
+// seedSoundBanks returns the contents of every SoundBank of the testdata
+// directory, and of a few generated SoundBanks.
+func seedSoundBanks(f testing.TB) [][]byte {
+	paths, err := filepath.Glob(filepath.Join(testDir, "*.bnk"))
+	if err != nil {
+		f.Fatal(err)
+	}
+	var banks [][]byte
+	for _, path := range paths {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			f.Fatal(err)
+		}
+		banks = append(banks, data)
+	}
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < generatedSeeds; i++ {
+		banks = append(banks, generateSoundBank(r))
+	}
+	return banks
+}
+
+// seedSounds returns every sound object of the seed SoundBanks.
+func seedSounds(f testing.TB) []*SfxVoiceSoundObject {
+	var sounds []*SfxVoiceSoundObject
+	for _, data := range seedSoundBanks(f) {
+		bnk, err := NewFile(bytes.NewReader(data))
+		if err != nil {
+			f.Fatal(err)
+		}
+		for _, obj := range bnk.ObjectSection.Objects() {
+			if sound, ok := obj.(*SfxVoiceSoundObject); ok {
+				sounds = append(sounds, sound)
+			}
+		}
+	}
+	return sounds
+}
+
+// objectData returns the data of sound, which follows its descriptor.
+func objectData(f testing.TB, sound *SfxVoiceSoundObject) []byte {
+	b := new(bytes.Buffer)
+	_, err := sound.WriteTo(b)
+	if err != nil {
+		f.Fatal(err)
+	}
+	return b.Bytes()[OBJECT_DESCRIPTOR_BYTES:]
+}
+
+// newTestReader returns a reader over data for the parsers to read from.
+func newTestReader(data []byte) util.ReadSeekerAt {
+	return util.NewResettingReader(bytes.NewReader(data), 0, int64(len(data)))
+}
+
+// assertWriteTo checks that w reports the number of bytes it writes.
+func assertWriteTo(t *testing.T, w io.WriterTo) {
+	counter := new(util.CountingWriter)
+	n, err := w.WriteTo(counter)
+	if err == nil && n != counter.Count {
+		t.Errorf("%d bytes were written, but %d bytes were reported to be "+
+			"written", counter.Count, n)
+	}
+}
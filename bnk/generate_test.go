@@ -0,0 +1,293 @@
+// Package bnk implements access to the Wwise SoundBank file format.
+package bnk
+
+// Generators of random, valid SoundBanks for the property-based and fuzz tests.
+import (
+	"bytes"
+	"encoding/binary"
+	"math/rand"
+)
+
+// The largest number of wems in a generated SoundBank.
+const maxGeneratedWems = 24
+
+// The largest number of bytes of a generated wem.
+const maxGeneratedWemBytes = 2048
+
+// The parameter types that the sound objects of a generated SoundBank choose
+// from, which include the loop parameter.
+var generatedParameterTypes = []byte{0x00, 0x02, 0x03, 0x06, 0x07, 0x0E,
+	parameterLoopType, 0x3B}
+
+// The types of the other objects of a generated SoundBank. None of them are
+// parsed, or hold the lengths of wems.
+var generatedObjectTypes = []byte{0x01, 0x08, 0x0E, 0x12, 0x13}
+
+// The largest number of each of the containers, actions, events and music
+// tracks of a generated SoundBank.
+const maxGeneratedObjects = 4
+
+// generateSoundBank returns a random, valid SoundBank built from r. It holds a
+// random number of wems of random lengths, each followed by a random amount of
+// padding that keeps the next wem aligned. Most wems are played by a sound
+// object with a random list of parameters, which may be the child of a
+// container. Streamed sounds, music tracks playing the wems, play and other
+// actions on the sounds and containers, events posting those actions and
+// objects of other types are mixed into the HIRC section in a random order.
+func generateSoundBank(r *rand.Rand) []byte {
+	count := 1 + r.Intn(maxGeneratedWems)
+	ids := generateIds(r, 2*count+8+4*maxGeneratedObjects)
+	wemIds, others := ids[:count], ids[count:]
+	// Returns an ID that no wem or object has yet.
+	nextId := func() uint32 {
+		id := others[0]
+		others = others[1:]
+		return id
+	}
+
+	didx := new(bytes.Buffer)
+	data := new(bytes.Buffer)
+	var lengths []uint32
+	for i, id := range wemIds {
+		wem := make([]byte, 1+r.Intn(maxGeneratedWemBytes))
+		r.Read(wem)
+		lengths = append(lengths, uint32(len(wem)))
+		put(didx, id, uint32(data.Len()), uint32(len(wem)))
+		data.Write(wem)
+
+		padding := alignmentPadding(int64(data.Len()))
+		if i == count-1 {
+			// The padding after the last wem does not have to align anything.
+			padding = int64(r.Intn(2 * wemAlignmentBytes))
+		} else {
+			padding += int64(r.Intn(3) * wemAlignmentBytes)
+		}
+		data.Write(make([]byte, padding))
+	}
+
+	var objects [][]byte
+	// Every container is the child of no container or of one generated before
+	// it, so that the hierarchy has no cycles.
+	var containers []uint32
+	parent := func() uint32 {
+		if len(containers) == 0 || r.Intn(3) == 0 {
+			return 0
+		}
+		return containers[r.Intn(len(containers))]
+	}
+	for i := r.Intn(maxGeneratedObjects); i > 0; i-- {
+		id := nextId()
+		objects = append(objects, generateContainer(r, id, parent()))
+		containers = append(containers, id)
+	}
+
+	targets := append([]uint32(nil), containers...)
+	for i, id := range wemIds {
+		if r.Intn(4) != 0 {
+			sound := nextId()
+			objects = append(objects,
+				generateSound(r, sound, parent(), id, lengths[i], true))
+			targets = append(targets, sound)
+		}
+	}
+	for i := r.Intn(3); i > 0; i-- {
+		objects = append(objects,
+			generateSound(r, nextId(), parent(), nextId(), r.Uint32(), false))
+	}
+	for i := r.Intn(maxGeneratedObjects); i > 0; i-- {
+		objects = append(objects,
+			generateMusicTrack(r, nextId(), wemIds, lengths))
+	}
+
+	var actions []uint32
+	for i := r.Intn(maxGeneratedObjects); i > 0 && len(targets) > 0; i-- {
+		id := nextId()
+		objects = append(objects,
+			generateAction(r, id, targets[r.Intn(len(targets))]))
+		actions = append(actions, id)
+	}
+	for i := r.Intn(maxGeneratedObjects); i > 0; i-- {
+		b := new(bytes.Buffer)
+		posted := r.Perm(len(actions))[:r.Intn(len(actions)+1)]
+		put(b, uint32(len(posted)))
+		for _, a := range posted {
+			put(b, actions[a])
+		}
+		objects = append(objects, generatedObject(eventObjectId, nextId(),
+			b.Bytes()))
+	}
+	for i := r.Intn(4); i > 0; i-- {
+		obj := make([]byte, r.Intn(32))
+		r.Read(obj)
+		objects = append(objects, generatedObject(
+			generatedObjectTypes[r.Intn(len(generatedObjectTypes))], nextId(),
+			obj))
+	}
+	r.Shuffle(len(objects), func(i, j int) {
+		objects[i], objects[j] = objects[j], objects[i]
+	})
+	hirc := new(bytes.Buffer)
+	put(hirc, uint32(len(objects)))
+	for _, obj := range objects {
+		hirc.Write(obj)
+	}
+
+	bkhd := new(bytes.Buffer)
+	put(bkhd, BankDescriptor{uint32(0x70 + r.Intn(0x30)), r.Uint32()})
+	extra := make([]byte, 4*r.Intn(4))
+	r.Read(extra)
+	bkhd.Write(extra)
+
+	bnk := new(bytes.Buffer)
+	for _, sec := range []struct {
+		id   [4]byte
+		data *bytes.Buffer
+	}{
+		{bkhdHeaderId, bkhd}, {didxHeaderId, didx}, {dataHeaderId, data},
+		{hircHeaderId, hirc},
+	} {
+		put(bnk, SectionHeader{sec.id, uint32(sec.data.Len())})
+		bnk.Write(sec.data.Bytes())
+	}
+	return bnk.Bytes()
+}
+
+// generateSound returns a random Voice/SFX sound object with the ID id and the
+// parent parent, which plays the wem with the ID wemId and the length
+// wemLength. The wem is embedded in the SoundBank if embedded is true, and
+// streamed otherwise.
+func generateSound(r *rand.Rand, id, parent, wemId, wemLength uint32,
+	embedded bool) []byte {
+	b := new(bytes.Buffer)
+	var unknown [SFX_UNKNOWN_BYTES]byte
+	r.Read(unknown[:])
+	unknown[SFX_UNKNOWN_BYTES-1] = streamSettingEmbedded
+	if !embedded {
+		unknown[SFX_UNKNOWN_BYTES-1] = byte(1 + r.Intn(2))
+	}
+	put(b, unknown, OptionalWemDescriptor{wemId, wemLength}, byte(r.Intn(2)))
+	putNodeParameters(r, b, parent)
+
+	types := r.Perm(len(generatedParameterTypes))[:r.Intn(
+		len(generatedParameterTypes)+1)]
+	put(b, byte(len(types)))
+	for _, t := range types {
+		put(b, generatedParameterTypes[t])
+	}
+	for _, t := range types {
+		value := r.Uint32()
+		if generatedParameterTypes[t] == parameterLoopType {
+			value = uint32(r.Intn(100))
+		}
+		put(b, value)
+	}
+	remaining := make([]byte, r.Intn(24))
+	r.Read(remaining)
+	b.Write(remaining)
+	return generatedObject(soundObjectId, id, b.Bytes())
+}
+
+// generateContainer returns a random container object with the ID id and the
+// parent parent.
+func generateContainer(r *rand.Rand, id, parent uint32) []byte {
+	b := new(bytes.Buffer)
+	putNodeParameters(r, b, parent)
+	rest := make([]byte, r.Intn(32))
+	r.Read(rest)
+	b.Write(rest)
+	return generatedObject(nodeObjectIds[r.Intn(len(nodeObjectIds))], id,
+		b.Bytes())
+}
+
+// generateMusicTrack returns a random music track with the ID id. Each of its
+// sources either plays one of the embedded wems with the IDs wemIds and the
+// lengths lengths, or a random streamed wem.
+func generateMusicTrack(r *rand.Rand, id uint32, wemIds,
+	lengths []uint32) []byte {
+	b := new(bytes.Buffer)
+	count := 1 + r.Intn(3)
+	put(b, byte(r.Intn(4)), uint32(count))
+	for i := 0; i < count; i++ {
+		put(b, r.Uint32())
+		if r.Intn(4) == 0 {
+			put(b, byte(1+r.Intn(2)), OptionalWemDescriptor{r.Uint32(),
+				r.Uint32()})
+		} else {
+			w := r.Intn(len(wemIds))
+			put(b, byte(streamSettingEmbedded),
+				OptionalWemDescriptor{wemIds[w], lengths[w]})
+		}
+		put(b, byte(r.Intn(2)))
+	}
+	// The playlist and other parameters that follow the sources.
+	rest := make([]byte, r.Intn(32))
+	r.Read(rest)
+	b.Write(rest)
+	return generatedObject(musicTrackObjectId, id, b.Bytes())
+}
+
+// generateAction returns a random action with the ID id, which targets the
+// object with the ID target. Most actions play their target.
+func generateAction(r *rand.Rand, id, target uint32) []byte {
+	b := new(bytes.Buffer)
+	actionType := uint16(actionTypePlay)
+	if r.Intn(3) == 0 {
+		actionType = uint16(r.Intn(0x1000))
+	}
+	put(b, actionType, target)
+	rest := make([]byte, r.Intn(16))
+	r.Read(rest)
+	b.Write(rest)
+	return generatedObject(actionObjectId, id, b.Bytes())
+}
+
+// putNodeParameters writes the random node parameters that start the
+// structure of a sound or container with the parent parent: whether it
+// overrides the effects of its parent, its effects and the unknown bytes
+// holding the ID of its parent.
+func putNodeParameters(r *rand.Rand, b *bytes.Buffer, parent uint32) {
+	put(b, byte(r.Intn(2)))
+	effects := r.Intn(3)
+	put(b, byte(effects))
+	if effects > 0 {
+		put(b, byte(r.Intn(2)))
+		effect := make([]byte, effects*EFFECT_BYTES)
+		r.Read(effect)
+		b.Write(effect)
+	}
+	var structure [STRUCTURE_UNKNOWN_BYTES]byte
+	r.Read(structure[:])
+	binary.LittleEndian.PutUint32(structure[5:9], parent)
+	b.Write(structure[:])
+}
+
+// generatedObject returns the HIRC object of type objectType with the ID id,
+// whose data follows its descriptor.
+func generatedObject(objectType byte, id uint32, data []byte) []byte {
+	b := new(bytes.Buffer)
+	put(b, ObjectDescriptor{objectType,
+		uint32(OBJECT_DESCRIPTOR_ID_BYTES + len(data)), id})
+	b.Write(data)
+	return b.Bytes()
+}
+
+// generateIds returns count unique, non-zero random IDs.
+func generateIds(r *rand.Rand, count int) []uint32 {
+	seen := make(map[uint32]bool)
+	var ids []uint32
+	for len(ids) < count {
+		id := r.Uint32()
+		if id != 0 && !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// put writes every value of vs to b in little endian byte order.
+func put(b *bytes.Buffer, vs ...interface{}) {
+	for _, v := range vs {
+		binary.Write(b, binary.LittleEndian, v)
+	}
+}
@@ -2,8 +2,13 @@
 package bnk
 
 import (
+	"bytes"
 	"encoding/binary"
+	"fmt"
 	"io"
+	"io/ioutil"
+	"strconv"
+	"strings"
 )
 
 import (
@@ -26,9 +31,70 @@ const STRUCTURE_UNKNOWN_BYTES = 10
 
 const parameterLoopType = 0x3A
 
+// The parameter types of a SoundStructure that can be referred to by name, and
+// whose values are 32-bit floating point numbers.
+var floatParameterTypes = map[string]byte{
+	"volume": 0x00,
+	"pitch":  0x02,
+	"lpf":    0x03,
+}
+
+// The number of bytes used to describe the type and target of an action.
+const ACTION_HEADER_BYTES = 6
+
+// The number of bytes used to describe the count of actions in an event.
+const ACTION_COUNT_BYTES = 4
+
 // The identifier for SFX or Voice sound objects.
 const soundObjectId = 0x02
 
+// The identifier for action objects.
+const actionObjectId = 0x03
+
+// The identifier for event objects.
+const eventObjectId = 0x04
+
+// The identifier for music track objects.
+const musicTrackObjectId = 0x0B
+
+// The number of bytes used to describe the flags and source count of a music
+// track.
+const MUSIC_TRACK_HEADER_BYTES = 5
+
+// The number of bytes used to describe a single source of a music track: its
+// plugin ID, stream type, wem ID, wem length and source bits.
+const MUSIC_TRACK_SOURCE_BYTES = 4 + 1 + OPTIONAL_WEM_DESCRIPTOR_BYTES + 1
+
+// The action type that plays its target object.
+const actionTypePlay = 0x0403
+
+// The identifiers for objects that contain other objects. Their data starts
+// with the same node parameters as a sound structure, which include the ID of
+// their parent.
+var nodeObjectIds = []byte{0x05, 0x06, 0x07, 0x09}
+
+// The identifiers for containers that play their children in sequence or at
+// random, that play one of their children chosen by a switch, and that play
+// all of their children at once.
+const randomSequenceObjectId = 0x05
+const switchObjectId = 0x06
+const blendObjectId = 0x09
+
+// The number of bytes used to describe the count of children of a container.
+const CHILD_COUNT_BYTES = 4
+
+// The number of bytes used to describe the count of items in the playlist of a
+// random or sequence container.
+const PLAYLIST_COUNT_BYTES = 2
+
+// The number of bytes used to describe a single item of the playlist of a
+// random or sequence container: the ID of the item and its weight.
+const PLAYLIST_ITEM_BYTES = 8
+
+// The modes of a random or sequence container.
+const containerModeRandom = 0x00
+const containerModeSequence = 0x01
+
 // The wem is embedded in this sound file.
 const streamSettingEmbedded = 0x00
 
@@ -37,6 +103,33 @@ type Object interface {
 	io.WriterTo
 }
 
+// The names of each type of HIRC object, by their type identifier.
+var objectTypeNames = map[byte]string{
+	0x01: "State",
+	0x02: "Sound",
+	0x03: "Action",
+	0x04: "Event",
+	0x05: "Random/Sequence Container",
+	0x06: "Switch Container",
+	0x07: "Actor-Mixer",
+	0x08: "Audio Bus",
+	0x09: "Blend Container",
+	0x0A: "Music Segment",
+	0x0B: "Music Track",
+	0x0C: "Music Switch Container",
+	0x0D: "Music Playlist Container",
+	0x0E: "Attenuation",
+	0x0F: "Dialogue Event",
+	0x10: "Motion Bus",
+	0x11: "Motion FX",
+	0x12: "Effect",
+	0x13: "Source",
+	0x14: "Auxiliary Bus",
+	0x15: "LFO Modulator",
+	0x16: "Envelope Modulator",
+	0x17: "Audio Device",
+}
+
 // A ObjectDescriptor describes a single object within a HIRC section.
 type ObjectDescriptor struct {
 	Type byte
@@ -69,6 +162,25 @@ type OptionalWemDescriptor struct {
 	WemLength uint32
 }
 
+// An ActionObject represents an action within the HIRC section, which is
+// performed on a target object when an event is posted.
+type ActionObject struct {
+	Descriptor *ObjectDescriptor
+	ActionType uint16
+	// The ID of the object that this action is performed on.
+	TargetId uint32
+	// A reader to read the remaining data of this action.
+	RemainingReader io.Reader
+}
+
+// An EventObject represents an event within the HIRC section, which triggers a
+// list of actions.
+type EventObject struct {
+	Descriptor  *ObjectDescriptor
+	ActionCount uint32
+	ActionIds   []uint32
+}
+
 // An UnknownObject represents an unknown object within the HIRC.
 type UnknownObject struct {
 	Descriptor *ObjectDescriptor
@@ -81,7 +193,7 @@ type UnknownObject struct {
 type SoundStructure struct {
 	OverrideParentEffects byte
 	EffectContainer       *EffectContainer
-	Unknown               *[10]byte
+	Unknown               *[STRUCTURE_UNKNOWN_BYTES]byte
 	ParameterCount        byte
 	ParameterTypes        []byte
 	ParameterValues       [][4]byte
@@ -109,6 +221,71 @@ type Effect struct {
 	Padding [2]byte
 }
 
+// ObjectTypeName returns the name of the HIRC object type t, or "Unknown" if
+// it is not a known type.
+func ObjectTypeName(t byte) string {
+	name, ok := objectTypeNames[t]
+	if !ok {
+		return "Unknown"
+	}
+	return name
+}
+
+// ParseParameterType parses the type of a SoundStructure parameter, written as
+// either a name such as "volume" or "loop", or a number. The returned bool is
+// true if the values of the parameter are known to be 32-bit unsigned
+// integers rather than floating point numbers.
+func ParseParameterType(s string) (paramType byte, integer bool, err error) {
+	name := strings.ToLower(s)
+	if name == "loop" {
+		return parameterLoopType, true, nil
+	}
+	if t, ok := floatParameterTypes[name]; ok {
+		return t, false, nil
+	}
+	t, err := strconv.ParseUint(s, 0, 8)
+	if err != nil {
+		return 0, false, fmt.Errorf("%s is not a known parameter type or a "+
+			"number between 0 and 255", s)
+	}
+	return byte(t), byte(t) == parameterLoopType, nil
+}
+
+// ParameterTypeName returns the name of the SoundStructure parameter type t, as
+// accepted by ParseParameterType.
+func ParameterTypeName(t byte) string {
+	if t == parameterLoopType {
+		return "loop"
+	}
+	for name, paramType := range floatParameterTypes {
+		if paramType == t {
+			return name
+		}
+	}
+	return fmt.Sprintf("0x%02X", t)
+}
+
+// DescriptorOf returns the descriptor of obj.
+func DescriptorOf(obj Object) *ObjectDescriptor {
+	switch o := obj.(type) {
+	case *SfxVoiceSoundObject:
+		return o.Descriptor
+	case *ActionObject:
+		return o.Descriptor
+	case *EventObject:
+		return o.Descriptor
+	case *UnknownObject:
+		return o.Descriptor
+	}
+	return nil
+}
+
+// Embedded returns true if the wem played by this sound is embedded in the
+// SoundBank, rather than streamed from another file.
+func (sound *SfxVoiceSoundObject) Embedded() bool {
+	return sound.Unknown[SFX_UNKNOWN_BYTES-1] == streamSettingEmbedded
+}
+
 // NewSfxVoiceSoundObject creates a new SfxVoiceSoundObject, reading from sr,
 // which must be seeked to the start of the object's data.
 func (desc *ObjectDescriptor) NewSfxVoiceSoundObject(sr util.ReadSeekerAt) (*SfxVoiceSoundObject, error) {
@@ -182,6 +359,154 @@ func (sound *SfxVoiceSoundObject) WriteTo(w io.Writer) (written int64, err error
 	return written, nil
 }
 
+// NewActionObject creates a new ActionObject, reading from sr, which must be
+// seeked to the start of the object's data.
+func (desc *ObjectDescriptor) NewActionObject(sr util.ReadSeekerAt) (*ActionObject, error) {
+	var actionType uint16
+	err := binary.Read(sr, binary.LittleEndian, &actionType)
+	if err != nil {
+		return nil, err
+	}
+
+	var target uint32
+	err = binary.Read(sr, binary.LittleEndian, &target)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create a reader over the remaining elements in this object, then seek past
+	// it.
+	currOffset, _ := sr.Seek(0, io.SeekCurrent)
+	remaining := int64(desc.Length) - OBJECT_DESCRIPTOR_ID_BYTES -
+		ACTION_HEADER_BYTES
+	if remaining < 0 {
+		return nil, fmt.Errorf("0x%08X: Action %d declares %d bytes, fewer than "+
+			"its header holds", currOffset, desc.ObjectId, desc.Length)
+	}
+	r := util.NewResettingReader(sr, currOffset, remaining)
+	sr.Seek(remaining, io.SeekCurrent)
+	return &ActionObject{desc, actionType, target, r}, nil
+}
+
+// WriteTo writes the full contents of this ActionObject to the Writer
+// specified by w.
+func (action *ActionObject) WriteTo(w io.Writer) (written int64, err error) {
+	err = binary.Write(w, binary.LittleEndian, action.Descriptor)
+	if err != nil {
+		return
+	}
+	written = OBJECT_DESCRIPTOR_BYTES
+
+	err = binary.Write(w, binary.LittleEndian, action.ActionType)
+	if err != nil {
+		return
+	}
+	err = binary.Write(w, binary.LittleEndian, action.TargetId)
+	if err != nil {
+		return
+	}
+	written += ACTION_HEADER_BYTES
+
+	n, err := io.Copy(w, action.RemainingReader)
+	if err != nil {
+		return written, err
+	}
+	written += n
+
+	return written, nil
+}
+
+// NewEventObject creates a new EventObject, reading from sr, which must be
+// seeked to the start of the object's data.
+func (desc *ObjectDescriptor) NewEventObject(sr util.ReadSeekerAt) (*EventObject, error) {
+	var count uint32
+	err := binary.Read(sr, binary.LittleEndian, &count)
+	if err != nil {
+		return nil, err
+	}
+
+	// Check that the IDs are there before allocating them, since a corrupt
+	// count could otherwise ask for gigabytes.
+	offset, _ := sr.Seek(0, io.SeekCurrent)
+	if !util.HasBytes(sr, offset, int64(count)*OBJECT_DESCRIPTOR_ID_BYTES) {
+		return nil, fmt.Errorf("0x%08X: Event %d lists %d actions, which run "+
+			"past the end of the file", offset, desc.ObjectId, count)
+	}
+	ids := make([]uint32, count)
+	err = binary.Read(sr, binary.LittleEndian, ids)
+	if err != nil {
+		return nil, err
+	}
+	return &EventObject{desc, count, ids}, nil
+}
+
+// WriteTo writes the full contents of this EventObject to the Writer
+// specified by w.
+func (event *EventObject) WriteTo(w io.Writer) (written int64, err error) {
+	err = binary.Write(w, binary.LittleEndian, event.Descriptor)
+	if err != nil {
+		return
+	}
+	written = OBJECT_DESCRIPTOR_BYTES
+
+	err = binary.Write(w, binary.LittleEndian, event.ActionCount)
+	if err != nil {
+		return
+	}
+	written += ACTION_COUNT_BYTES
+
+	err = binary.Write(w, binary.LittleEndian, event.ActionIds)
+	if err != nil {
+		return
+	}
+	written += int64(len(event.ActionIds)) * OBJECT_DESCRIPTOR_ID_BYTES
+
+	return written, nil
+}
+
+// isEventLayout returns true if the data of the object described by desc,
+// starting at the current offset of sr, has the layout of an event known to
+// this package. The layout of events differs between versions of Wwise.
+func (desc *ObjectDescriptor) isEventLayout(sr util.ReadSeekerAt) bool {
+	offset, _ := sr.Seek(0, io.SeekCurrent)
+	var bs [ACTION_COUNT_BYTES]byte
+	_, err := sr.ReadAt(bs[:], offset)
+	if err != nil {
+		return false
+	}
+	count := int64(binary.LittleEndian.Uint32(bs[:]))
+	return int64(desc.Length) == OBJECT_DESCRIPTOR_ID_BYTES+ACTION_COUNT_BYTES+
+		count*OBJECT_DESCRIPTOR_ID_BYTES
+}
+
+// readNodeParentId returns the ID of the parent of the object described by
+// desc, whose data must start with the common node parameters at the current
+// offset of sr. The offset of sr is not changed.
+func (desc *ObjectDescriptor) readNodeParentId(sr util.ReadSeekerAt) (uint32, bool) {
+	offset, _ := sr.Seek(0, io.SeekCurrent)
+	end := offset + int64(desc.Length) - OBJECT_DESCRIPTOR_ID_BYTES
+	// The override byte, followed by the effect container.
+	var fx [2]byte
+	_, err := sr.ReadAt(fx[:], offset)
+	if err != nil {
+		return 0, false
+	}
+	offset += 2
+	if fx[1] > 0 {
+		// The bypass mask, followed by every effect.
+		offset += 1 + int64(fx[1])*EFFECT_BYTES
+	}
+	var unknown [STRUCTURE_UNKNOWN_BYTES]byte
+	if offset+STRUCTURE_UNKNOWN_BYTES > end {
+		return 0, false
+	}
+	_, err = sr.ReadAt(unknown[:], offset)
+	if err != nil {
+		return 0, false
+	}
+	return parentIdOf(&unknown), true
+}
+
 // NewUnknownObject creates a new UnknownObject, reading from sr, which must
 // be seeked to the start of the unknown object's data.
 func (desc *ObjectDescriptor) NewUnknownObject(sr util.ReadSeekerAt) (*UnknownObject, error) {
@@ -190,6 +515,10 @@ func (desc *ObjectDescriptor) NewUnknownObject(sr util.ReadSeekerAt) (*UnknownOb
 	// The descriptor length includes the Object ID, which has already been
 	// written. Remove this from the remaining length
 	dataLength := int64(desc.Length) - OBJECT_DESCRIPTOR_ID_BYTES
+	if dataLength < 0 {
+		return nil, fmt.Errorf("0x%08X: Object %d declares %d bytes, fewer than "+
+			"its id holds", dataOffset, desc.ObjectId, desc.Length)
+	}
 	r := util.NewResettingReader(sr, dataOffset, dataLength)
 	sr.Seek(dataLength, io.SeekCurrent)
 	return &UnknownObject{desc, r}, nil
@@ -213,6 +542,116 @@ func (unknown *UnknownObject) WriteTo(w io.Writer) (written int64, err error) {
 	return written, nil
 }
 
+// updateSourceLengths updates the lengths of the embedded wems referenced by
+// the sources of this object, which must be a music track. oldLengths and
+// newLengths give the lengths of each replaced wem, by its ID, before and after
+// it was replaced. A source is only updated if it gives the old length of its
+// wem.
+func (unknown *UnknownObject) updateSourceLengths(oldLengths,
+	newLengths map[uint32]uint32) {
+	unknown.updateSources(func(id, length uint32) uint32 {
+		newLength, ok := newLengths[id]
+		if !ok || length != oldLengths[id] {
+			return length
+		}
+		return newLength
+	})
+}
+
+// updateSources calls update with the ID and length of the embedded wem
+// referenced by each source of this object, which must be a music track, and
+// stores the length that it returns. Only the sources at the start of the
+// track are read; the rest of its data is left as is. Tracks whose sources do
+// not fit within their data, such as those of older versions of Wwise, are not
+// changed.
+func (unknown *UnknownObject) updateSources(
+	update func(id, length uint32) uint32) {
+	data, err := ioutil.ReadAll(unknown.Reader)
+	if err != nil || len(data) < MUSIC_TRACK_HEADER_BYTES {
+		return
+	}
+	count := int64(binary.LittleEndian.Uint32(data[1:]))
+	if MUSIC_TRACK_HEADER_BYTES+count*MUSIC_TRACK_SOURCE_BYTES >
+		int64(len(data)) {
+		return
+	}
+	changed := false
+	for i := int64(0); i < count; i++ {
+		source := data[MUSIC_TRACK_HEADER_BYTES+i*MUSIC_TRACK_SOURCE_BYTES:]
+		// Each source starts with its plugin ID, which is not needed.
+		if source[4] != streamSettingEmbedded {
+			continue
+		}
+		id := binary.LittleEndian.Uint32(source[5:])
+		length := binary.LittleEndian.Uint32(source[9:])
+		if newLength := update(id, length); newLength != length {
+			binary.LittleEndian.PutUint32(source[9:], newLength)
+			changed = true
+		}
+	}
+	if changed {
+		unknown.Reader = util.NewResettingReader(bytes.NewReader(data), 0,
+			int64(len(data)))
+	}
+}
+
+// readPlaylist reads the playlist of this object, which must be a random or
+// sequence container. A container ends with its mode and a flag byte, its
+// children and its playlist, so these are found by working back from the end
+// of its data, and every child must be in children, the IDs of the objects
+// known to be its children. sequence is true if the container plays its
+// playlist in order, rather than at random. ok is false if the end of the
+// data does not have this layout.
+func (unknown *UnknownObject) readPlaylist(children map[uint32]bool) (
+	sequence bool, playlist []uint32, ok bool) {
+	data, err := ioutil.ReadAll(unknown.Reader)
+	if err != nil {
+		return false, nil, false
+	}
+	for count := 0; ; count++ {
+		end := len(data) - PLAYLIST_COUNT_BYTES - count*PLAYLIST_ITEM_BYTES
+		if end < 0 {
+			return false, nil, false
+		}
+		if int(binary.LittleEndian.Uint16(data[end:])) != count {
+			continue
+		}
+		for n := 0; ; n++ {
+			start := end - n*OBJECT_DESCRIPTOR_ID_BYTES - CHILD_COUNT_BYTES
+			// The mode is followed by a flag byte.
+			if start < 2 {
+				break
+			}
+			if int(binary.LittleEndian.Uint32(data[start:])) != n {
+				continue
+			}
+			mode := data[start-2]
+			items := data[end+PLAYLIST_COUNT_BYTES:]
+			if mode != containerModeRandom && mode != containerModeSequence ||
+				!listedChildren(data[start+CHILD_COUNT_BYTES:end],
+					OBJECT_DESCRIPTOR_ID_BYTES, children) ||
+				!listedChildren(items, PLAYLIST_ITEM_BYTES, children) {
+				continue
+			}
+			for i := 0; i < len(items); i += PLAYLIST_ITEM_BYTES {
+				playlist = append(playlist, binary.LittleEndian.Uint32(items[i:]))
+			}
+			return mode == containerModeSequence, playlist, true
+		}
+	}
+}
+
+// listedChildren returns true if the ID at the start of every entry of data,
+// each of the given size, is in children.
+func listedChildren(data []byte, size int, children map[uint32]bool) bool {
+	for i := 0; i+OBJECT_DESCRIPTOR_ID_BYTES <= len(data); i += size {
+		if !children[binary.LittleEndian.Uint32(data[i:])] {
+			return false
+		}
+	}
+	return true
+}
+
 // NewSoundStructure creates a new SoundStructure, reading from sr, which must be
 // seeked to the start of the structure's data.
 func NewSoundStructure(sr util.ReadSeekerAt, length int64) (*SoundStructure, error) {
@@ -229,7 +668,7 @@ func NewSoundStructure(sr util.ReadSeekerAt, length int64) (*SoundStructure, err
 		return nil, err
 	}
 
-	unknown := new([10]byte)
+	unknown := new([STRUCTURE_UNKNOWN_BYTES]byte)
 	err = binary.Read(sr, binary.LittleEndian, unknown)
 	if err != nil {
 		return nil, err
@@ -274,12 +713,29 @@ func NewSoundStructure(sr util.ReadSeekerAt, length int64) (*SoundStructure, err
 	// it.
 	currOffset, _ := sr.Seek(0, io.SeekCurrent)
 	remaining := length - (currOffset - startOffset)
+	if remaining < 0 {
+		return nil, fmt.Errorf("0x%08X: The sound structure is %d bytes long, "+
+			"but its effects and parameters take %d bytes", startOffset, length,
+			currOffset-startOffset)
+	}
 	r := util.NewResettingReader(sr, currOffset, remaining)
 	sr.Seek(remaining, io.SeekCurrent)
 	return &SoundStructure{override, ctr, unknown, count, types, values,
 		loops, loopCount, r}, nil
 }
 
+// ParentId returns the ID of the object that contains the audio object
+// described by this structure, or 0 if it has no parent.
+func (ss *SoundStructure) ParentId() uint32 {
+	return parentIdOf(ss.Unknown)
+}
+
+// parentIdOf returns the parent ID stored within the unknown portion of a
+// sound structure, after the ID of the overriding bus and a flag byte.
+func parentIdOf(unknown *[STRUCTURE_UNKNOWN_BYTES]byte) uint32 {
+	return binary.LittleEndian.Uint32(unknown[5:9])
+}
+
 func (ss *SoundStructure) WriteTo(w io.Writer) (written int64, err error) {
 	err = binary.Write(w, binary.LittleEndian, ss.OverrideParentEffects)
 	if err != nil {
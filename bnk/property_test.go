@@ -0,0 +1,166 @@
+// Package bnk implements access to the Wwise SoundBank file format.
+package bnk
+
+// Property-based tests for the bnk package, run against generated SoundBanks.
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"testing"
+	"testing/quick"
+)
+
+import (
+	"github.com/hpxro7/wwiseutil/wwise"
+)
+
+// The number of SoundBanks that each property is checked against.
+const propertyChecks = 200
+
+func TestGeneratedRoundTrip(t *testing.T) {
+	checkProperty(t, func(r *rand.Rand) error {
+		org := generateSoundBank(r)
+		bnk, err := NewFile(bytes.NewReader(org))
+		if err != nil {
+			return err
+		}
+		if problems := bnk.Verify(); len(problems) > 0 {
+			return fmt.Errorf("the generated SoundBank is inconsistent: %s",
+				problems[0])
+		}
+		for i := 0; i < 2; i++ {
+			err = assertWrites(bnk, org)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func TestGeneratedReplaceRestore(t *testing.T) {
+	checkProperty(t, func(r *rand.Rand) error {
+		org := generateSoundBank(r)
+		bnk, err := NewFile(bytes.NewReader(org))
+		if err != nil {
+			return err
+		}
+
+		// Replace a random selection of wems, recording their original contents
+		// and padding.
+		var originals, rs []*wwise.ReplacementWem
+		paddings := make(map[int]int64)
+		for _, i := range r.Perm(len(bnk.Wems()))[:1+r.Intn(len(bnk.Wems()))] {
+			wem := bnk.Wems()[i]
+			data, err := ioutil.ReadAll(io.NewSectionReader(wem, 0,
+				int64(wem.Descriptor.Length)))
+			if err != nil {
+				return err
+			}
+			originals = append(originals, &wwise.ReplacementWem{
+				bytes.NewReader(data), i, int64(len(data))})
+			paddings[i] = wem.Padding.Size()
+
+			replacement := make([]byte, 1+r.Intn(maxGeneratedWemBytes))
+			r.Read(replacement)
+			rs = append(rs, &wwise.ReplacementWem{bytes.NewReader(replacement), i,
+				int64(len(replacement))})
+		}
+		bnk.ReplaceWems(rs...)
+		replaced := new(bytes.Buffer)
+		_, err = bnk.WriteTo(replaced)
+		if err != nil {
+			return err
+		}
+		bnk, err = NewFile(bytes.NewReader(replaced.Bytes()))
+		if err != nil {
+			return err
+		}
+		if problems := bnk.Verify(); len(problems) > 0 {
+			return fmt.Errorf("the replaced SoundBank is inconsistent: %s",
+				problems[0])
+		}
+
+		bnk.ReplaceWems(originals...)
+		for i, padding := range paddings {
+			bnk.SetPaddingOf(i, padding)
+		}
+		return assertWrites(bnk, org)
+	})
+}
+
+func TestGeneratedLoopAddRemove(t *testing.T) {
+	checkProperty(t, func(r *rand.Rand) error {
+		org := generateSoundBank(r)
+		bnk, err := NewFile(bytes.NewReader(org))
+		if err != nil {
+			return err
+		}
+		for i := range bnk.Wems() {
+			if !bnk.CanLoop(i) {
+				continue
+			}
+			// Add a loop to wems that do not loop, which is undone by removing it.
+			// Change the value of wems that do loop instead, since a loop added
+			// back after being removed follows every other parameter.
+			loop := bnk.LoopOf(i)
+			changed := LoopValue{true, uint32(r.Intn(100))}
+			if loop.Loops && changed.Value == loop.Value {
+				changed.Value++
+			}
+			bnk.ReplaceLoopOf(i, changed)
+			written := new(bytes.Buffer)
+			_, err = bnk.WriteTo(written)
+			if err != nil {
+				return err
+			}
+			reread, err := NewFile(bytes.NewReader(written.Bytes()))
+			if err != nil {
+				return err
+			}
+			if got := reread.LoopOf(i); got != changed {
+				return fmt.Errorf("wem %d: expected the loop value %s after it was "+
+					"changed, but got %s", i+1, changed, got)
+			}
+			bnk.ReplaceLoopOf(i, loop)
+		}
+		return assertWrites(bnk, org)
+	})
+}
+
+// checkProperty checks that property returns no error for a fixed sequence of
+// random sources, reporting the seed of the source of any failure.
+func checkProperty(t *testing.T, property func(r *rand.Rand) error) {
+	check := func(seed int64) bool {
+		err := property(rand.New(rand.NewSource(seed)))
+		if err != nil {
+			t.Errorf("seed %d: %s", seed, err)
+		}
+		return err == nil
+	}
+	err := quick.Check(check, &quick.Config{MaxCount: propertyChecks,
+		Rand: rand.New(rand.NewSource(1))})
+	if _, ok := err.(*quick.CheckError); err != nil && !ok {
+		t.Error(err)
+	}
+}
+
+// assertWrites returns an error if bnk does not write exactly expect.
+func assertWrites(bnk *File, expect []byte) error {
+	written := new(bytes.Buffer)
+	total, err := bnk.WriteTo(written)
+	if err != nil {
+		return err
+	}
+	if total != int64(written.Len()) {
+		return fmt.Errorf("%d bytes were written, but %d bytes were reported to "+
+			"be written", written.Len(), total)
+	}
+	if !bytes.Equal(written.Bytes(), expect) {
+		return errors.New("the written SoundBank differs from the original")
+	}
+	return nil
+}
@@ -0,0 +1,313 @@
+// Package bnk implements access to the Wwise SoundBank file format.
+package bnk
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+)
+
+import (
+	"github.com/hpxro7/wwiseutil/util"
+	"github.com/hpxro7/wwiseutil/wwise"
+)
+
+// The number of bytes searched for the start of a wem when the DIDX gives the
+// wrong offset, starting from the end of the previous wem.
+const REPAIR_SEARCH_BYTES = 64 * 1024
+
+// The number of bytes past the end of the last wem that are searched for the
+// start of the next section, when the DATA section gives the wrong length.
+const REPAIR_SECTION_SEARCH_BYTES = 64
+
+// A repairer reads SoundBanks leniently, recording every correction made.
+type repairer struct {
+	// The offset into the file of the DIDX section.
+	indexOffset int64
+	corrections []*wwise.Problem
+}
+
+// correct records a correction to the data at offset.
+func (rep *repairer) correct(offset int64, format string, a ...interface{}) {
+	rep.corrections = append(rep.corrections,
+		wwise.NewProblem(offset, format, a...))
+}
+
+// Repair reads the SoundBank stored in r leniently, and corrects it so that it
+// is internally consistent. The data of each wem is found by its RIFF header
+// where the DIDX gives the wrong offset or length, and the wems are laid out
+// again at offsets aligned to 16 bytes. The lengths of the DIDX, DATA and HIRC
+// sections, and the lengths of the wems embedded by sound objects and music
+// tracks, are then corrected to match their contents. The lengths of HIRC
+// objects are used as they are given, since the objects are read by them.
+// Every correction is returned, at the offset of the
+// corrected data in the original file; a SoundBank that needs no corrections
+// is unchanged.
+func Repair(r io.ReaderAt) (*File, []*wwise.Problem, error) {
+	rep := new(repairer)
+	bnk, err := newFile(r, rep)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	offsets := make(map[Section]int64)
+	offset := int64(0)
+	for _, sec := range bnk.sections {
+		offsets[sec] = offset
+		offset += SECTION_HEADER_BYTES + int64(HeaderOf(sec).Length)
+	}
+	if idx := bnk.IndexSection; idx != nil {
+		length := uint32(len(idx.WemIds) * DIDX_ENTRY_BYTES)
+		if idx.Header.Length != length {
+			rep.correct(rep.indexOffset, "The length of the DIDX section was "+
+				"corrected from %d to %d bytes", idx.Header.Length, length)
+			idx.Header.Length = length
+		}
+	}
+	if hrc := bnk.ObjectSection; hrc != nil {
+		rep.repairObjects(bnk, offsets[hrc])
+	}
+	wwise.SortProblems(rep.corrections)
+	return bnk, rep.corrections, nil
+}
+
+// repairObjects corrects the lengths of the HIRC section of bnk, which starts
+// at offset start, and of the wems embedded by its sound objects and music
+// tracks.
+func (rep *repairer) repairObjects(bnk *File, start int64) {
+	hrc := bnk.ObjectSection
+	stored := make(map[uint32]uint32)
+	for _, wem := range bnk.Wems() {
+		stored[wem.Descriptor.WemId] = wem.Descriptor.Length
+	}
+
+	offset := start + SECTION_HEADER_BYTES + OBJECT_COUNT_BYTES
+	total := uint32(OBJECT_COUNT_BYTES)
+	for _, obj := range hrc.objects {
+		desc := DescriptorOf(obj)
+		switch o := obj.(type) {
+		case *SfxVoiceSoundObject:
+			wem := &o.WemDescriptor
+			storedLength, ok := stored[wem.WemId]
+			if ok && o.Embedded() && wem.WemLength != storedLength {
+				rep.correct(offset, "The length of wem %d embedded by sound %d was "+
+					"corrected from %d to %d bytes", wem.WemId, desc.ObjectId,
+					wem.WemLength, storedLength)
+				wem.WemLength = storedLength
+			}
+		case *UnknownObject:
+			if desc.Type != musicTrackObjectId {
+				break
+			}
+			o.updateSources(func(id, length uint32) uint32 {
+				storedLength, ok := stored[id]
+				if !ok || length == storedLength {
+					return length
+				}
+				rep.correct(offset, "The length of wem %d embedded by music track "+
+					"%d was corrected from %d to %d bytes", id, desc.ObjectId, length,
+					storedLength)
+				return storedLength
+			})
+		}
+		length := OBJECT_DESCRIPTOR_BYTES - OBJECT_DESCRIPTOR_ID_BYTES +
+			desc.Length
+		offset += int64(length)
+		total += length
+	}
+	if total != hrc.Header.Length {
+		rep.correct(start, "The length of the HIRC section was corrected from %d "+
+			"to %d bytes", hrc.Header.Length, total)
+		hrc.Header.Length = total
+	}
+}
+
+// readDataSection reads the DATA section described by hdr from sr, which must
+// be seeked to the start of the section data. The data of each wem described
+// by idx is located, and the wems are laid out again at aligned offsets.
+func (rep *repairer) readDataSection(sr util.ReadSeekerAt, hdr *SectionHeader,
+	idx *DataIndexSection) (*DataSection, error) {
+	start, _ := sr.Seek(0, io.SeekCurrent)
+	sec := &DataSection{hdr, uint32(start), nil}
+
+	// The offset of each wem's data, relative to the start of the section.
+	located := make([]int64, len(idx.WemIds))
+	cursor := int64(0)
+	for i, id := range idx.WemIds {
+		desc := idx.DescriptorMap[id]
+		entry := rep.indexOffset + SECTION_HEADER_BYTES +
+			int64(i*DIDX_ENTRY_BYTES)
+		offset, length, err := locateWem(sr, start, cursor, desc)
+		if err != nil {
+			return nil, fmt.Errorf("0x%08X: Could not find wem %d (id %d): %s",
+				entry, i+1, id, err)
+		}
+		if offset != int64(desc.Offset) {
+			rep.correct(entry, "The data of wem %d (id %d) was found at offset %d "+
+				"of the DATA section, rather than %d", i+1, id, offset, desc.Offset)
+		}
+		if length != desc.Length {
+			rep.correct(entry, "The length of wem %d (id %d) was corrected from %d "+
+				"to %d bytes", i+1, id, desc.Length, length)
+			desc.Length = length
+		}
+		located[i] = offset
+		sec.Wems = append(sec.Wems, &wwise.Wem{
+			Reader:     util.NewResettingReader(sr, start+offset, int64(length)),
+			Descriptor: desc,
+		})
+		cursor = offset + int64(length)
+	}
+
+	end, err := sectionEnd(sr, start, int64(hdr.Length), cursor)
+	if err != nil {
+		return nil, fmt.Errorf("0x%08X: Could not find the end of the DATA "+
+			"section: %s", start-SECTION_HEADER_BYTES, err)
+	}
+
+	offset := int64(0)
+	for i, wem := range sec.Wems {
+		desc := wem.Descriptor
+		if offset != located[i] {
+			rep.correct(rep.indexOffset+SECTION_HEADER_BYTES+
+				int64(i*DIDX_ENTRY_BYTES), "Wem %d (id %d) was moved from offset "+
+				"%d to %d of the DATA section", i+1, desc.WemId, located[i], offset)
+		}
+		desc.Offset = uint32(offset)
+		wemEnd := offset + int64(desc.Length)
+		padding := alignmentPadding(wemEnd)
+		if i == len(sec.Wems)-1 {
+			// Keep the padding after the last wem, if it is not too large.
+			if remaining := end - cursor; remaining >= 0 &&
+				remaining < wemAlignmentBytes {
+				padding = remaining
+			}
+		}
+		wem.Padding = util.NewResettingReader(&util.InfiniteReaderAt{0}, 0,
+			padding)
+		offset = wemEnd + padding
+	}
+
+	if int64(hdr.Length) != offset {
+		rep.correct(start-SECTION_HEADER_BYTES, "The length of the DATA section "+
+			"was corrected from %d to %d bytes", hdr.Length, offset)
+		hdr.Length = uint32(offset)
+	}
+	sr.Seek(start+end, io.SeekStart)
+	return sec, nil
+}
+
+// locateWem returns the offset and length of the data of the wem described by
+// desc, within the DATA section starting at start in sr. The data is expected
+// at or after cursor. If the DIDX does not give the offset of a RIFF header,
+// the first RIFF header found after cursor is used. Wems that are not stored
+// with a RIFF header are assumed to be where the DIDX gives.
+func locateWem(sr util.ReadSeekerAt, start, cursor int64,
+	desc *wwise.WemDescriptor) (int64, uint32, error) {
+	stated := int64(desc.Offset)
+	if stated >= cursor {
+		if length, ok := riffLength(sr, start+stated); ok {
+			return stated, length, nil
+		}
+	}
+
+	buf := make([]byte, REPAIR_SEARCH_BYTES)
+	n, _ := sr.ReadAt(buf, start+cursor)
+	found := -1
+	for _, id := range [][]byte{[]byte("RIFF"), []byte("RIFX")} {
+		if i := bytes.Index(buf[:n], id); i >= 0 && (found < 0 || i < found) {
+			found = i
+		}
+	}
+	if found >= 0 {
+		if length, ok := riffLength(sr, start+cursor+int64(found)); ok {
+			return cursor + int64(found), length, nil
+		}
+	}
+	if stated < cursor {
+		return 0, 0, fmt.Errorf("its data starts at offset %d of the DATA "+
+			"section, before the end of the previous wem at offset %d", stated,
+			cursor)
+	}
+	return stated, desc.Length, nil
+}
+
+// riffLength returns the length of the RIFF container starting at offset in
+// r, as declared by its header. The returned bool is false if there is no
+// RIFF header at offset.
+func riffLength(r io.ReaderAt, offset int64) (uint32, bool) {
+	var hdr [wwise.RIFF_HEADER_BYTES]byte
+	if n, _ := r.ReadAt(hdr[:], offset); n != len(hdr) ||
+		string(hdr[8:12]) != "WAVE" {
+		return 0, false
+	}
+	var length uint32
+	switch string(hdr[:4]) {
+	case "RIFF":
+		length = binary.LittleEndian.Uint32(hdr[4:])
+	case "RIFX":
+		length = binary.BigEndian.Uint32(hdr[4:])
+	default:
+		return 0, false
+	}
+	if length > math.MaxUint32-wwise.CHUNK_HEADER_BYTES {
+		return 0, false
+	}
+	return length + wwise.CHUNK_HEADER_BYTES, true
+}
+
+// sectionEnd returns the length of the section starting at start in sr, whose
+// header gives the length stated and whose contents end at contentEnd. The
+// stated length is used if a section header or the end of the file follows
+// it; otherwise the end of the contents, or the first position shortly after
+// it, that is followed by a section header or the end of the file is used.
+func sectionEnd(sr util.ReadSeekerAt, start, stated,
+	contentEnd int64) (int64, error) {
+	if stated >= contentEnd && isSectionBoundary(sr, start+stated) {
+		return stated, nil
+	}
+	aligned := contentEnd + alignmentPadding(contentEnd)
+	if isSectionBoundary(sr, start+aligned) {
+		return aligned, nil
+	}
+	for end := contentEnd; end <= contentEnd+REPAIR_SECTION_SEARCH_BYTES; end++ {
+		if isSectionBoundary(sr, start+end) {
+			return end, nil
+		}
+	}
+	return 0, fmt.Errorf("no section follows the last wem, which ends at "+
+		"offset %d", contentEnd)
+}
+
+// isSectionBoundary returns true if offset is the end of r, or the start of a
+// section header, whose identifier is made up of upper case letters and
+// digits.
+func isSectionBoundary(r io.ReaderAt, offset int64) bool {
+	var id [4]byte
+	if offset > 0 {
+		if n, _ := r.ReadAt(id[:1], offset-1); n != 1 {
+			return false
+		}
+	}
+	n, _ := r.ReadAt(id[:], offset)
+	if n == 0 {
+		return true
+	}
+	if n != len(id) {
+		return false
+	}
+	for _, c := range id {
+		if (c < 'A' || c > 'Z') && (c < '0' || c > '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// alignmentPadding returns the number of bytes of padding needed after offset
+// to align the next offset to wemAlignmentBytes.
+func alignmentPadding(offset int64) int64 {
+	return (wemAlignmentBytes - offset%wemAlignmentBytes) % wemAlignmentBytes
+}
@@ -96,6 +96,11 @@ type ObjectHierarchySection struct {
 	// infinity.
 	loopOf      map[uint32]uint32
 	wemToObject map[uint32]*SfxVoiceSoundObject
+	// A mapping from object ID to the ID of its parent object, for all objects
+	// whose parent is known.
+	parentOf map[uint32]uint32
+	actionOf map[uint32]*ActionObject
+	events   []*EventObject
 }
 
 // An UnknownSection represents an unknown section in a SoundBank file.
@@ -105,6 +110,29 @@ type UnknownSection struct {
 	Reader io.Reader
 }
 
+// HeaderOf returns the header of the section sec.
+func HeaderOf(sec Section) *SectionHeader {
+	switch s := sec.(type) {
+	case *BankHeaderSection:
+		return s.Header
+	case *DataIndexSection:
+		return s.Header
+	case *DataSection:
+		return s.Header
+	case *ObjectHierarchySection:
+		return s.Header
+	case *UnknownSection:
+		return s.Header
+	}
+	return nil
+}
+
+// Objects returns every object of this section, in the order that they are
+// stored.
+func (hrc *ObjectHierarchySection) Objects() []Object {
+	return hrc.objects
+}
+
 // NewBankHeaderSection creates a new BankHeaderSection, reading from sr, which
 // must be seeked to the start of the BKHD section data.
 // It is an error to call this method on a non-BKHD header.
@@ -112,6 +140,12 @@ func (hdr *SectionHeader) NewBankHeaderSection(sr util.ReadSeekerAt) (*BankHeade
 	if hdr.Identifier != bkhdHeaderId {
 		panic(fmt.Sprintf("Expected BKHD header but got: %s", hdr.Identifier))
 	}
+	if hdr.Length < BKHD_SECTION_BYTES {
+		offset, _ := sr.Seek(0, io.SeekCurrent)
+		return nil, fmt.Errorf("0x%08X: The BKHD section declares %d bytes, "+
+			"fewer than its descriptor holds", offset-SECTION_HEADER_BYTES,
+			hdr.Length)
+	}
 	sec := new(BankHeaderSection)
 	sec.Header = hdr
 	desc := BankDescriptor{}
@@ -156,10 +190,10 @@ func (hdr *BankHeaderSection) String() string {
 		hdr.Descriptor.BankId)
 }
 
-// NewDataIndexSection creates a new DataIndexSection, reading from r, which must
-// be seeked to the start of the DIDX section data.
+// NewDataIndexSection creates a new DataIndexSection, reading from sr, which
+// must be seeked to the start of the DIDX section data.
 // It is an error to call this method on a non-DIDX header.
-func (hdr *SectionHeader) NewDataIndexSection(r io.Reader) (*DataIndexSection, error) {
+func (hdr *SectionHeader) NewDataIndexSection(sr util.ReadSeekerAt) (*DataIndexSection, error) {
 	if hdr.Identifier != didxHeaderId {
 		panic(fmt.Sprintf("Expected DIDX header but got: %s", hdr.Identifier))
 	}
@@ -167,19 +201,22 @@ func (hdr *SectionHeader) NewDataIndexSection(r io.Reader) (*DataIndexSection, e
 	sec := DataIndexSection{hdr, wemCount, make([]uint32, 0),
 		make(map[uint32]*wwise.WemDescriptor)}
 	for i := 0; i < wemCount; i++ {
+		entryOffset, _ := sr.Seek(0, io.SeekCurrent)
 		var desc wwise.WemDescriptor
-		err := binary.Read(r, binary.LittleEndian, &desc)
+		err := binary.Read(sr, binary.LittleEndian, &desc)
 		if err != nil {
 			return nil, err
 		}
 
 		if _, ok := sec.DescriptorMap[desc.WemId]; ok {
-			panic(fmt.Sprintf(
-				"%d is an illegal repeated wem ID in the DIDX", desc.WemId))
+			return nil, fmt.Errorf("0x%08X: Wem %d of the DIDX repeats the id %d "+
+				"of an earlier wem", entryOffset, i+1, desc.WemId)
 		}
 		sec.WemIds = append(sec.WemIds, desc.WemId)
 		sec.DescriptorMap[desc.WemId] = &desc
 	}
+	// Skip any trailing bytes too few to hold another entry.
+	sr.Seek(int64(hdr.Length%DIDX_ENTRY_BYTES), io.SeekCurrent)
 
 	return &sec, nil
 }
@@ -249,6 +286,23 @@ func (hdr *SectionHeader) NewDataSection(sr util.ReadSeekerAt,
 				nextOffset = dataOffset + int64(nextDesc.Offset)
 			}
 			remaining := nextOffset - wemEndOffset
+			if remaining < 0 {
+				next := "the next wem"
+				if i == len(idx.WemIds)-1 {
+					next = "the end of the DATA section"
+				}
+				return nil, fmt.Errorf("0x%08X: Wem %d (id %d) ends at offset %d of "+
+					"the DATA section, past %s at offset %d", wemStartOffset, i+1,
+					desc.WemId, wemEndOffset-dataOffset, next, nextOffset-dataOffset)
+			}
+			// The padding is read from the file, so check that the file holds it;
+			// a corrupt length would otherwise be written with fewer bytes than its
+			// header gives.
+			if !util.HasBytes(sr, wemStartOffset, int64(desc.Length)+remaining) {
+				return nil, fmt.Errorf("0x%08X: Wem %d (id %d) and its padding run "+
+					"past the end of the file to offset %d", wemStartOffset, i+1,
+					desc.WemId, nextOffset)
+			}
 			// Pass a Reader over the remaining section if we have remaining bytes to
 			// read, or an empty Reader if remaining is 0 (no bytes will be read).
 			padding = util.NewResettingReader(sr, wemEndOffset, remaining)
@@ -301,6 +355,8 @@ func (hdr *SectionHeader) NewObjectHierarchySection(sr util.ReadSeekerAt) (*Obje
 	sec.Header = hdr
 	sec.loopOf = make(map[uint32]uint32)
 	sec.wemToObject = make(map[uint32]*SfxVoiceSoundObject)
+	sec.parentOf = make(map[uint32]uint32)
+	sec.actionOf = make(map[uint32]*ActionObject)
 
 	var count uint32
 	err := binary.Read(sr, binary.LittleEndian, &count)
@@ -326,8 +382,47 @@ func (hdr *SectionHeader) NewObjectHierarchySection(sr util.ReadSeekerAt) (*Obje
 			if obj.Structure.loops {
 				sec.loopOf[obj.WemDescriptor.WemId] = obj.Structure.loopCount
 			}
+			sec.parentOf[desc.ObjectId] = obj.Structure.ParentId()
+			sec.objects = append(sec.objects, obj)
+		case actionObjectId:
+			if desc.Length < OBJECT_DESCRIPTOR_ID_BYTES+ACTION_HEADER_BYTES {
+				obj, err := desc.NewUnknownObject(sr)
+				if err != nil {
+					return nil, err
+				}
+				sec.objects = append(sec.objects, obj)
+				break
+			}
+			obj, err := desc.NewActionObject(sr)
+			if err != nil {
+				return nil, err
+			}
+			sec.actionOf[desc.ObjectId] = obj
+			sec.objects = append(sec.objects, obj)
+		case eventObjectId:
+			if !desc.isEventLayout(sr) {
+				obj, err := desc.NewUnknownObject(sr)
+				if err != nil {
+					return nil, err
+				}
+				sec.objects = append(sec.objects, obj)
+				break
+			}
+			obj, err := desc.NewEventObject(sr)
+			if err != nil {
+				return nil, err
+			}
+			sec.events = append(sec.events, obj)
 			sec.objects = append(sec.objects, obj)
 		default:
+			for _, t := range nodeObjectIds {
+				if id != t {
+					continue
+				}
+				if parent, ok := desc.readNodeParentId(sr); ok {
+					sec.parentOf[desc.ObjectId] = parent
+				}
+			}
 			obj, err := desc.NewUnknownObject(sr)
 			if err != nil {
 				return nil, err
@@ -372,11 +467,37 @@ func (hrc *ObjectHierarchySection) String() string {
 	return b.String()
 }
 
+// updateWemLengths updates the lengths of the embedded wems referenced by the
+// sound objects and music tracks of this section. oldLengths and newLengths
+// give the lengths of each replaced wem, by its ID, before and after it was
+// replaced.
+func (hrc *ObjectHierarchySection) updateWemLengths(oldLengths,
+	newLengths map[uint32]uint32) {
+	for _, obj := range hrc.objects {
+		switch o := obj.(type) {
+		case *SfxVoiceSoundObject:
+			length, ok := newLengths[o.WemDescriptor.WemId]
+			if ok && o.Embedded() {
+				o.WemDescriptor.WemLength = length
+			}
+		case *UnknownObject:
+			if o.Descriptor.Type == musicTrackObjectId {
+				o.updateSourceLengths(oldLengths, newLengths)
+			}
+		}
+	}
+}
+
 // NewUnknownSection creates a new UnknownSection, reading from sr, which
 // must be seeked to the start of the unknown section data.
 func (hdr *SectionHeader) NewUnknownSection(sr util.ReadSeekerAt) (*UnknownSection, error) {
 	// Get the offset into the file where the data portion of this section begins.
 	dataOffset, _ := sr.Seek(0, io.SeekCurrent)
+	if !util.HasBytes(sr, dataOffset, int64(hdr.Length)) {
+		return nil, fmt.Errorf("0x%08X: The %s section declares %d bytes, which "+
+			"run past the end of the file", dataOffset-SECTION_HEADER_BYTES,
+			hdr.Identifier[:], hdr.Length)
+	}
 	r := util.NewResettingReader(sr, dataOffset, int64(hdr.Length))
 	sr.Seek(int64(hdr.Length), io.SeekCurrent)
 	return &UnknownSection{hdr, r}, nil
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+import (
+	"github.com/hpxro7/wwiseutil/wwise"
+)
+
+var analyzeFlags struct {
+	filePath   string
+	targetPath string
+	format     string
+	naming     namingFlags
+}
+
+// The loudness analysis of a single wem, as reported by analyze.
+type wemAnalysis struct {
+	Index int    `json:"index"`
+	Id    uint32 `json:"id"`
+	// The loudness of the wem, if it is not being compared to a replacement.
+	Loudness *wwise.Loudness `json:"loudness,omitempty"`
+	// The comparison between the wem and its replacement, if one was given.
+	Comparison *wwise.LoudnessComparison `json:"comparison,omitempty"`
+	// The reason this wem could not be analyzed, if any.
+	Error string `json:"error,omitempty"`
+}
+
+func init() {
+	fs := newFlagSet("analyze")
+	f := &analyzeFlags
+	stringFlag(fs, &f.filePath, "filepath", "f",
+		"the path to the .bnk or .pck to analyze.")
+	stringFlag(fs, &f.targetPath, "target", "t",
+		"if specified, each replacement wem in this directory is compared "+
+			"against the wem it replaces. "+targetUsage)
+	fs.StringVar(&f.format, "format", "table",
+		"the format to report results in. Either table or json.")
+	addNamingFlags(fs, &f.naming)
+	register(&command{
+		name: "analyze",
+		summary: "Analyze the peak, RMS and integrated loudness of each " +
+			"decodable wem in a .bnk or .pck",
+		flags: fs,
+		verify: func() flagError {
+			if err := f.naming.verify(); err != "" {
+				return err
+			}
+			if f.format != "table" && f.format != "json" {
+				return flagError(f.format + ", is not a supported format")
+			}
+			return requireFlags("filepath", f.filePath)
+		},
+		run: analyze,
+	})
+}
+
+func analyze() error {
+	f := &analyzeFlags
+	ctn, err := openContainer(f.filePath, false)
+	if err != nil {
+		return err
+	}
+	defer ctn.Close()
+
+	var results []*wemAnalysis
+	if f.targetPath == "" {
+		for i, wem := range ctn.Wems() {
+			a := &wemAnalysis{Index: i + 1, Id: wem.Descriptor.WemId}
+			a.Loudness, err = wwise.AnalyzeLoudness(wem,
+				int64(wem.Descriptor.Length))
+			if err != nil {
+				a.Error = err.Error()
+			}
+			results = append(results, a)
+		}
+	} else {
+		naming, err := f.naming.load()
+		if err != nil {
+			return err
+		}
+		targets, target, err := processTargetFiles(ctn, f.targetPath, false,
+			naming)
+		if err != nil {
+			return err
+		}
+		defer target.Close()
+		for _, r := range targets {
+			wem := ctn.Wems()[r.WemIndex]
+			a := &wemAnalysis{Index: r.WemIndex + 1, Id: wem.Descriptor.WemId}
+			org, err := wwise.AnalyzeLoudness(wem, int64(wem.Descriptor.Length))
+			if err != nil {
+				a.Error = "original: " + err.Error()
+				results = append(results, a)
+				continue
+			}
+			rep, err := wwise.AnalyzeLoudness(r.Wem, r.Length)
+			if err != nil {
+				a.Error = "replacement: " + err.Error()
+				results = append(results, a)
+				continue
+			}
+			a.Comparison = wwise.CompareLoudness(org, rep)
+			results = append(results, a)
+		}
+	}
+
+	if f.format == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(results)
+		if err != nil {
+			return fmt.Errorf("Could not write analysis: %s", err)
+		}
+		return nil
+	}
+	printAnalysisTable(results, f.targetPath != "")
+	return nil
+}
+
+func printAnalysisTable(results []*wemAnalysis, comparing bool) {
+	tableParams := []string{"%-7", "%-15", "%-12", "%-12", "%-12", "%-12", "\n"}
+	titleFmt := strings.Join(tableParams, "s|")
+	title := fmt.Sprintf(titleFmt,
+		"Index", "Id", "Peak (dB)", "RMS (dB)", "LUFS", "Length (s)")
+	if comparing {
+		title = fmt.Sprintf(titleFmt,
+			"Index", "Id", "Peak diff", "RMS diff", "LUFS diff", "Length (s)")
+	}
+	fmt.Print(title)
+	fmt.Println(strings.Repeat("-", len(title)-1))
+
+	rowFmt := strings.Join([]string{"%-7d", "%-15d", "%-12.2f", "%-12.2f",
+		"%-12.2f", "%-12.2f", "\n"}, "|")
+	for _, a := range results {
+		switch {
+		case a.Error != "":
+			fmt.Printf("%-7d|%-15d|%s\n", a.Index, a.Id, a.Error)
+		case a.Comparison != nil:
+			c := a.Comparison
+			fmt.Printf(rowFmt, a.Index, a.Id, c.PeakDifference, c.RMSDifference,
+				c.IntegratedDifference, c.Replacement.Duration)
+		default:
+			l := a.Loudness
+			fmt.Printf(rowFmt, a.Index, a.Id, l.Peak, l.RMS, l.Integrated,
+				l.Duration)
+		}
+	}
+}
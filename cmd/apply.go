@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"log"
+)
+
+import (
+	"github.com/hpxro7/wwiseutil/manifest"
+)
+
+var applyFlags struct {
+	manifestPath string
+	strict       bool
+	dryRun       bool
+	journal      bool
+}
+
+func init() {
+	fs := newFlagSet("apply")
+	f := &applyFlags
+	stringFlag(fs, &f.manifestPath, "manifest", "m",
+		"the path to a JSON manifest listing the .bnk and .pck files to change, "+
+			"with the wems to replace, the loop values to set and the sound "+
+			"object properties to change in each. Relative paths are resolved "+
+			"against the directory of the manifest.")
+	boolFlag(fs, &f.strict, "strict", "",
+		"refuse to write any output file if any replacement wem fails "+
+			"validation against the wem it replaces.")
+	boolFlag(fs, &f.dryRun, "dry-run", "n", dryRunUsage)
+	boolFlag(fs, &f.journal, "journal", "", journalUsage)
+	register(&command{
+		name: "apply",
+		summary: "Apply every change listed by a modding manifest, writing " +
+			"nothing if any change is invalid",
+		flags: fs,
+		verify: func() flagError {
+			return requireFlags("manifest", f.manifestPath)
+		},
+		run: apply,
+	})
+}
+
+func apply() error {
+	f := &applyFlags
+	m, err := manifest.Load(f.manifestPath)
+	if err != nil {
+		return err
+	}
+	results, err := manifest.Apply(m,
+		&manifest.Options{Strict: f.strict, DryRun: f.dryRun,
+			Journal: f.journal})
+	if err != nil {
+		return err
+	}
+	for _, r := range results {
+		for _, issue := range r.Issues {
+			log.Printf("%s: %s", r.Source, issue)
+		}
+		if f.dryRun {
+			fmt.Printf("%s -> %s: would replace %d wem(s), change %d loop "+
+				"value(s) and %d property value(s)\n%s\n", r.Source, r.Output,
+				r.Replaced, r.Loops, r.Properties, r.Layout)
+			continue
+		}
+		fmt.Printf("%s -> %s: replaced %d wem(s), changed %d loop value(s) and "+
+			"%d property value(s); wrote %d bytes\n", r.Source, r.Output, r.Replaced,
+			r.Loops, r.Properties, r.Written)
+		if r.Journal != "" {
+			fmt.Println("Successfully wrote journal:", r.Journal)
+		}
+	}
+	if f.dryRun {
+		fmt.Println("Dry run: nothing was written")
+		return nil
+	}
+	fmt.Printf("Successfully applied the manifest to %d file(s)\n", len(results))
+	return nil
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+import (
+	"github.com/hpxro7/wwiseutil/patch"
+)
+
+var applyPatchFlags struct {
+	filePath  string
+	patchPath string
+	output    string
+}
+
+func init() {
+	fs := newFlagSet("applypatch")
+	f := &applyPatchFlags
+	stringFlag(fs, &f.filePath, "filepath", "f",
+		"the path to the original .bnk or .pck that the patch was made for.")
+	stringFlag(fs, &f.patchPath, "patch", "p",
+		"the path to the patch written by makepatch.")
+	stringFlag(fs, &f.output, "output", "o",
+		"the path to write the patched .bnk or .pck to. This may be the same as "+
+			"filepath.")
+	register(&command{
+		name: "applypatch",
+		summary: "Apply a patch written by makepatch to the original .bnk or " +
+			".pck it was made for",
+		flags: fs,
+		verify: func() flagError {
+			return requireFlags("filepath", f.filePath, "patch", f.patchPath,
+				"output", f.output)
+		},
+		run: applyPatch,
+	})
+}
+
+func applyPatch() error {
+	f := &applyPatchFlags
+	in, err := os.Open(f.patchPath)
+	if err != nil {
+		return err
+	}
+	p, err := patch.Read(in)
+	in.Close()
+	if err != nil {
+		return fmt.Errorf("Could not read patch \"%s\": %s", f.patchPath, err)
+	}
+	total, err := p.Apply(f.filePath, f.output)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Applied %d replaced wem(s) and %d parameter edit(s)\n",
+		len(p.Replacements), len(p.Edits))
+	fmt.Println("Successfully wrote output file:", f.output)
+	fmt.Printf("Wrote %d bytes in total\n", total)
+	return nil
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+)
+
+import (
+	"github.com/hpxro7/wwiseutil/wwise"
+)
+
+var diffFlags struct {
+	oldPath string
+	newPath string
+	format  string
+}
+
+func init() {
+	fs := newFlagSet("diff")
+	f := &diffFlags
+	stringFlag(fs, &f.oldPath, "old", "a",
+		"the path to the older .bnk or .pck, such as one from before a game "+
+			"patch.")
+	stringFlag(fs, &f.newPath, "new", "b",
+		"the path to the newer .bnk or .pck to compare against the older one.")
+	fs.StringVar(&f.format, "format", "text",
+		"the format to report differences in. Either text or json.")
+	register(&command{
+		name: "diff",
+		summary: "Report the wems, loop values, HIRC objects and sections that " +
+			"differ between two versions of a .bnk or .pck",
+		flags: fs,
+		verify: func() flagError {
+			if f.format != "text" && f.format != "json" {
+				return flagError(f.format + ", is not a supported format")
+			}
+			return requireFlags("old", f.oldPath, "new", f.newPath)
+		},
+		run: diff,
+	})
+}
+
+func diff() error {
+	f := &diffFlags
+	a, err := openContainer(f.oldPath, false)
+	if err != nil {
+		return err
+	}
+	defer a.Close()
+	b, err := openContainer(f.newPath, false)
+	if err != nil {
+		return err
+	}
+	defer b.Close()
+
+	d, err := wwise.Diff(a, b)
+	if err != nil {
+		return err
+	}
+	if f.format == "json" {
+		return printJSON(d)
+	}
+	if d.Empty() {
+		fmt.Println("The files are identical")
+		return nil
+	}
+	fmt.Print(d)
+	return nil
+}
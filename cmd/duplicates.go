@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+import (
+	"github.com/hpxro7/wwiseutil/container"
+)
+
+var duplicatesFlags struct {
+	index  indexFlags
+	format string
+}
+
+func init() {
+	fs := newFlagSet("duplicates")
+	f := &duplicatesFlags
+	addIndexFlags(fs, &f.index)
+	fs.StringVar(&f.format, "format", "text",
+		"the format to report duplicates in. Either text or json.")
+	register(&command{
+		name: "duplicates",
+		summary: "Report the wem IDs and wem contents stored more than once " +
+			"across the .bnk and .pck files of a directory",
+		flags: fs,
+		verify: func() flagError {
+			if f.format != "text" && f.format != "json" {
+				return flagError(f.format + ", is not a supported format")
+			}
+			return requireFlags("dir", f.index.dir)
+		},
+		run: duplicates,
+	})
+}
+
+func duplicates() error {
+	f := &duplicatesFlags
+	idx, err := f.index.load()
+	if err != nil {
+		return err
+	}
+	report, err := idx.Duplicates(f.index.dir)
+	if err != nil {
+		return err
+	}
+	if f.format == "json" {
+		return printJSON(report)
+	}
+
+	if len(report.Ids) == 0 {
+		fmt.Println("No wem IDs are stored more than once")
+	} else {
+		fmt.Println("Wem IDs stored more than once:")
+	}
+	for _, d := range report.Ids {
+		contents := "identical contents"
+		if d.Differs {
+			contents = "differing contents"
+		}
+		fmt.Printf("%d: %d copies, %s\n", d.Id, len(d.Locations), contents)
+		printCopies(d.Locations)
+	}
+
+	if len(report.Contents) == 0 {
+		fmt.Println("No wem contents are stored under more than one wem ID")
+		return nil
+	}
+	fmt.Println("Wem contents stored under more than one wem ID:")
+	for _, d := range report.Contents {
+		var ids []string
+		for _, id := range d.Ids {
+			ids = append(ids, fmt.Sprint(id))
+		}
+		fmt.Printf("%s: ids %s\n", d.Hash[:12], strings.Join(ids, ", "))
+		printCopies(d.Locations)
+	}
+	return nil
+}
+
+// printCopies prints the path, index and ID of every location.
+func printCopies(locations []*container.WemLocation) {
+	for _, l := range locations {
+		fmt.Printf("  %s: wem %d (id %d)\n", relativePath(".", l.Path), l.Index,
+			l.Id)
+	}
+}
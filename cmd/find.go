@@ -0,0 +1,157 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+)
+
+import (
+	"github.com/hpxro7/wwiseutil/container"
+)
+
+var findFlags struct {
+	index  indexFlags
+	format string
+}
+
+// The flags of commands that search a directory for wems using the cached wem
+// index.
+type indexFlags struct {
+	dir       string
+	cachePath string
+	noCache   bool
+	workers   int
+}
+
+// addIndexFlags defines the flags searching a directory with the wem index.
+func addIndexFlags(fs *flag.FlagSet, f *indexFlags) {
+	stringFlag(fs, &f.dir, "dir", "d",
+		"the directory to search for .bnk and .pck files, such as a game's "+
+			"install directory.")
+	fs.StringVar(&f.cachePath, "cache", container.DefaultIndexPath(),
+		"the path to the cached index of wems. Only files whose size or "+
+			"modification time changed since they were last indexed are read "+
+			"again.")
+	fs.BoolVar(&f.noCache, "no-cache", false,
+		"read every file again, and do not update the cached index.")
+	workersFlag(fs, &f.workers)
+}
+
+// load brings the wem index up to date with the directory given by f, and
+// saves it unless caching is disabled. Files that could not be indexed are
+// logged.
+func (f *indexFlags) load() (*container.WemIndex, error) {
+	idx := container.NewWemIndex()
+	if !f.noCache {
+		cached, err := container.LoadIndex(f.cachePath)
+		if err != nil {
+			log.Printf("Ignoring the cached index %s: %s", f.cachePath, err)
+		} else {
+			idx = cached
+		}
+	}
+	failures, err := idx.Update(f.dir, f.workers)
+	if err != nil {
+		return nil, err
+	}
+	for _, failure := range failures {
+		log.Print(failure)
+	}
+	if !f.noCache {
+		err = idx.Save(f.cachePath)
+		if err != nil {
+			log.Printf("Could not save the cached index %s: %s", f.cachePath, err)
+		}
+	}
+	return idx, nil
+}
+
+func init() {
+	fs := newFlagSet("find")
+	f := &findFlags
+	addIndexFlags(fs, &f.index)
+	fs.StringVar(&f.format, "format", "table",
+		"the format to report results in. Either table or json.")
+	register(&command{
+		name:    "find",
+		summary: "Find the .bnk and .pck files holding wems, by wem ID",
+		args:    "<wem id>...",
+		flags:   fs,
+		verify: func() flagError {
+			if f.format != "table" && f.format != "json" {
+				return flagError(f.format + ", is not a supported format")
+			}
+			if fs.NArg() == 0 {
+				return "At least one wem ID must be specified"
+			}
+			return requireFlags("dir", f.index.dir)
+		},
+		run: find,
+	})
+}
+
+func find() error {
+	f := &findFlags
+	var ids []uint32
+	for _, arg := range commands["find"].flags.Args() {
+		id, err := strconv.ParseUint(arg, 10, 32)
+		if err != nil {
+			return fmt.Errorf("%s is not a valid wem ID", arg)
+		}
+		ids = append(ids, uint32(id))
+	}
+
+	idx, err := f.index.load()
+	if err != nil {
+		return err
+	}
+
+	found := 0
+	var results []*container.WemLocation
+	for _, id := range ids {
+		locations := idx.Find(id)
+		if len(locations) > 0 {
+			found++
+		}
+		results = append(results, locations...)
+	}
+	if f.format == "json" {
+		err = printJSON(results)
+		if err != nil {
+			return fmt.Errorf("Could not write results: %s", err)
+		}
+	} else {
+		printLocations(results)
+	}
+	if found < len(ids) {
+		return errors.New("Some wem IDs could not be found")
+	}
+	return nil
+}
+
+func printLocations(locations []*container.WemLocation) {
+	tableParams := []string{"%-15", "%-9", "%-7", "%-11", "%-9", "%s\n"}
+	titleFmt := strings.Join(tableParams, "s|")
+	title := fmt.Sprintf(titleFmt, "Id", "Storage", "Index", "Offset",
+		"Length", "Path")
+	fmt.Print(title)
+	fmt.Println(strings.Repeat("-", len(title)-1))
+	for _, l := range locations {
+		storage := "embedded"
+		if l.Streamed {
+			storage = "streamed"
+		}
+		index, offset, length := "-", "-", "-"
+		if l.Index != 0 {
+			index = strconv.Itoa(l.Index)
+			offset = strconv.FormatUint(uint64(l.Offset), 10)
+			length = strconv.FormatUint(uint64(l.Length), 10)
+		}
+		fmt.Printf(titleFmt, strconv.FormatUint(uint64(l.Id), 10), storage, index,
+			offset, length, l.Path)
+	}
+}
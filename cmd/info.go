@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"sort"
+)
+
+import (
+	"github.com/hpxro7/wwiseutil/bnk"
+	"github.com/hpxro7/wwiseutil/pck"
+	"github.com/hpxro7/wwiseutil/wwise"
+)
+
+var infoFlags struct {
+	filePath string
+}
+
+func init() {
+	fs := newFlagSet("info")
+	f := &infoFlags
+	stringFlag(fs, &f.filePath, "filepath", "f",
+		"the path to the .bnk or .pck to summarize.")
+	register(&command{
+		name:    "info",
+		summary: "Summarize the type, wems and codecs of a .bnk or .pck",
+		flags:   fs,
+		verify: func() flagError {
+			return requireFlags("filepath", f.filePath)
+		},
+		run: info,
+	})
+}
+
+func info() error {
+	ctn, err := openContainer(infoFlags.filePath, false)
+	if err != nil {
+		return err
+	}
+	defer ctn.Close()
+
+	switch c := ctn.(type) {
+	case *bnk.File:
+		fmt.Println("Type: SoundBank")
+		if c.ObjectSection != nil {
+			fmt.Printf("Objects: %d\n", c.ObjectSection.ObjectCount)
+		}
+		fmt.Printf("Events: %d\n", len(c.Events()))
+	case *pck.File:
+		fmt.Println("Type: File Package")
+	}
+
+	total := int64(0)
+	codecs := make(map[wwise.Codec]int)
+	for i, wem := range ctn.Wems() {
+		total += int64(wem.Descriptor.Length)
+		codec, err := wwise.DetectCodec(wem, int64(wem.Descriptor.Length))
+		if err != nil {
+			log.Printf("Could not detect the codec of wem %d: %s", i+1, err)
+		}
+		codecs[codec]++
+	}
+	fmt.Printf("Wems: %d\n", len(ctn.Wems()))
+	fmt.Printf("Total wem size: %d bytes\n", total)
+	fmt.Printf("Data start: 0x%X\n", ctn.DataStart())
+
+	var sorted []wwise.Codec
+	for codec := range codecs {
+		sorted = append(sorted, codec)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	for _, codec := range sorted {
+		fmt.Printf("  %-10s %d\n", codec.String()+":", codecs[codec])
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+import (
+	"github.com/hpxro7/wwiseutil/wwise"
+)
+
+var inspectFlags struct {
+	filePath string
+	wemIndex int
+	wemId    uint
+}
+
+func init() {
+	fs := newFlagSet("inspect-wem")
+	f := &inspectFlags
+	stringFlag(fs, &f.filePath, "filepath", "f",
+		"the path to a standalone .wem file, or to the .bnk or .pck holding the "+
+			"wem to inspect.")
+	fs.IntVar(&f.wemIndex, "index", 0,
+		"the index of the wem to inspect within a .bnk or .pck. The index of the "+
+			"first wem is 1.")
+	fs.UintVar(&f.wemId, "id", 0,
+		"the ID of the wem to inspect within a .bnk or .pck.")
+	register(&command{
+		name: "inspect-wem",
+		summary: "List every RIFF chunk of a single wem, with its offset, size " +
+			"and decoded fields",
+		flags: fs,
+		verify: func() flagError {
+			return requireFlags("filepath", f.filePath)
+		},
+		run: inspect,
+	})
+}
+
+func inspect() error {
+	f := &inspectFlags
+	var r io.ReaderAt
+	var size int64
+
+	if filepath.Ext(f.filePath) == wwise.WemExtension {
+		file, err := os.Open(f.filePath)
+		if err != nil {
+			return fmt.Errorf("Could not open .wem file: %s", err)
+		}
+		defer file.Close()
+		stat, err := file.Stat()
+		if err != nil {
+			return fmt.Errorf("Could not open .wem file: %s", err)
+		}
+		r, size = file, stat.Size()
+	} else {
+		ctn, err := openContainer(f.filePath, false)
+		if err != nil {
+			return err
+		}
+		defer ctn.Close()
+
+		wem, err := selectWem(ctn, f.wemIndex, f.wemId)
+		if err != nil {
+			return err
+		}
+		r, size = wem, int64(wem.Descriptor.Length)
+	}
+
+	hdr, err := wwise.InspectWem(r, size)
+	if err != nil {
+		return fmt.Errorf("Could not inspect wem: %s", err)
+	}
+	fmt.Print(hdr)
+	return nil
+}
+
+// Returns the wem in c selected by either its index, where the first wem is 1,
+// or its id. Exactly one of index and id must be non-zero.
+func selectWem(c wwise.Container, index int, id uint) (*wwise.Wem, error) {
+	i, err := wemIndexOf(c, index, id)
+	if err != nil {
+		return nil, err
+	}
+	return c.Wems()[i], nil
+}
+
+// Returns the index, where zero is the first wem, of the wem in c selected by
+// either its index, where the first wem is 1, or its id. Exactly one of index
+// and id must be non-zero.
+func wemIndexOf(c wwise.Container, index int, id uint) (int, error) {
+	wems := c.Wems()
+	switch {
+	case (index == 0) == (id == 0):
+		return 0, errors.New("Exactly one of index or id must be specified")
+	case index != 0:
+		if index < 1 || index > len(wems) {
+			return 0, fmt.Errorf("%d is not a valid index; the valid index range "+
+				"is %d to %d", index, 1, len(wems))
+		}
+		// Wems are indexed internally starting from 0, but the flag starts at 1.
+		return index - 1, nil
+	}
+	for i, wem := range wems {
+		if wem.Descriptor.WemId == uint32(id) {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("There is no wem with the ID %d", id)
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+import (
+	"github.com/hpxro7/wwiseutil/container"
+	"github.com/hpxro7/wwiseutil/wwise"
+)
+
+var listFlags struct {
+	filePath string
+	format   string
+	table    string
+	workers  int
+}
+
+// The listing of a single container found within a directory.
+type fileListing struct {
+	Path    string         `json:"path"`
+	Listing *wwise.Listing `json:"listing"`
+	// The text listing of the container, when listing as text.
+	text string
+}
+
+func init() {
+	fs := newFlagSet("list")
+	f := &listFlags
+	stringFlag(fs, &f.filePath, "filepath", "f",
+		"the path to the .bnk or .pck to list. If this is a directory, every "+
+			".bnk and .pck file within it is listed.")
+	fs.StringVar(&f.format, "format", "text",
+		"the format to list the contents in. Either text, json or csv. When "+
+			"listing a directory as csv, each record starts with the path of its "+
+			"file.")
+	fs.StringVar(&f.table, "table", wwise.WemsTable,
+		"When the format is csv, the table to list. One of "+wwise.WemsTable+
+			", "+wwise.SectionsTable+", "+wwise.ObjectsTable+" or "+
+			wwise.LanguagesTable+".")
+	workersFlag(fs, &f.workers)
+	register(&command{
+		name:    "list",
+		summary: "List the structure and wems of a .bnk or .pck",
+		flags:   fs,
+		verify: func() flagError {
+			switch f.format {
+			case "text", "json", "csv":
+			default:
+				return flagError(f.format + ", is not a supported format")
+			}
+			return requireFlags("filepath", f.filePath)
+		},
+		run: list,
+	})
+}
+
+func list() error {
+	f := &listFlags
+	paths, dir, err := inputPaths(f.filePath)
+	if err != nil {
+		return err
+	}
+
+	listings := make([]*fileListing, len(paths))
+	indexOf := make(map[string]int)
+	for i, path := range paths {
+		indexOf[path] = i
+	}
+	failures := container.ForEach(paths, f.workers, func(path string) error {
+		ctn, err := openContainer(path, false)
+		if err != nil {
+			return err
+		}
+		defer ctn.Close()
+		l := &fileListing{Path: path, Listing: ctn.Listing()}
+		if f.format == "text" {
+			l.text = ctn.String()
+		}
+		listings[indexOf[path]] = l
+		return nil
+	})
+	if !dir && len(failures) > 0 {
+		return failures[0].Err
+	}
+
+	var found []*fileListing
+	for _, l := range listings {
+		if l != nil {
+			found = append(found, l)
+		}
+	}
+	switch {
+	case f.format == "json" && !dir:
+		err = printJSON(found[0].Listing)
+	case f.format == "json":
+		err = printJSON(found)
+	case f.format == "csv":
+		err = printCSV(found, f.table, dir)
+	default:
+		for _, l := range found {
+			if dir {
+				fmt.Printf("%s:\n", l.Path)
+			}
+			fmt.Print(l.text)
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("Could not write listing: %s", err)
+	}
+	return summarizeFailures(failures, len(paths))
+}
+
+func printJSON(v interface{}) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// printCSV prints the table of every listing as a single CSV table. If
+// withPath is true, each record starts with the path of its listing.
+func printCSV(listings []*fileListing, table string, withPath bool) error {
+	w := csv.NewWriter(os.Stdout)
+	for i, l := range listings {
+		records, err := l.Listing.CSVRecords(table)
+		if err != nil {
+			return err
+		}
+		if i > 0 {
+			// Only the first listing's column names are written.
+			records = records[1:]
+		}
+		for j, record := range records {
+			if withPath {
+				path := l.Path
+				if i == 0 && j == 0 {
+					path = "path"
+				}
+				record = append([]string{path}, record...)
+			}
+			w.Write(record)
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
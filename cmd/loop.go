@@ -0,0 +1,227 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+import (
+	"github.com/hpxro7/wwiseutil/bnk"
+	"github.com/hpxro7/wwiseutil/wwise"
+)
+
+var loopFlags struct {
+	filePath  string
+	output    string
+	verbose   bool
+	wemIndex  int
+	wemId     uint
+	value     string
+	clear     bool
+	batchPath string
+	journal   bool
+}
+
+// The prefix used by batch files to select a wem by its ID rather than its
+// index.
+const batchIdPrefix = "id:"
+
+// A loopEdit is a single change to the loop value of a wem.
+type loopEdit struct {
+	// The index, where zero is the first wem, of the wem to change.
+	index int
+	loop  wwise.LoopValue
+}
+
+func init() {
+	fs := newFlagSet("loop")
+	f := &loopFlags
+	stringFlag(fs, &f.filePath, "filepath", "f",
+		"the path to the source .bnk. Loop values can only be edited in "+
+			"SoundBanks.")
+	stringFlag(fs, &f.output, "output", "o",
+		"the path to write the updated .bnk to. This is required when loop "+
+			"values are changed.")
+	boolFlag(fs, &f.verbose, "verbose", "v", verboseUsage)
+	fs.IntVar(&f.wemIndex, "index", 0,
+		"the index of the wem to read or change the loop value of. The index of "+
+			"the first wem is 1. If neither index nor id is specified, the loop "+
+			"values of every wem are listed.")
+	fs.UintVar(&f.wemId, "id", 0,
+		"the ID of the wem to read or change the loop value of.")
+	fs.StringVar(&f.value, "set", "",
+		"the loop value to set: none, infinite or the number of times the wem "+
+			"should play, which must be greater than 1.")
+	fs.BoolVar(&f.clear, "clear", false,
+		"remove looping from the wem. This is the same as -set none.")
+	fs.StringVar(&f.batchPath, "batch", "",
+		"the path to a file of loop changes to make, one per line. Each line "+
+			"holds a wem and a loop value, separated by whitespace. The wem is "+
+			"either its index, or its ID prefixed by \""+batchIdPrefix+"\". Lines "+
+			"starting with # are ignored.")
+	fs.BoolVar(&f.journal, "journal", false, journalUsage)
+	register(&command{
+		name:    "loop",
+		summary: "Read, set or clear the loop values of the wems in a .bnk",
+		flags:   fs,
+		verify: func() flagError {
+			edits := 0
+			for _, edit := range []bool{f.value != "", f.clear, f.batchPath != ""} {
+				if edit {
+					edits++
+				}
+			}
+			switch {
+			case edits > 1:
+				return "Only one of set, clear or batch can be specified"
+			case edits == 1 && f.output == "":
+				return "output cannot be empty when changing loop values"
+			case f.batchPath != "" && (f.wemIndex != 0 || f.wemId != 0):
+				return "index and id cannot be used with batch"
+			case (f.value != "" || f.clear) && f.wemIndex == 0 && f.wemId == 0:
+				return "Either index or id must be specified"
+			}
+			return requireFlags("filepath", f.filePath)
+		},
+		run: loop,
+	})
+}
+
+func loop() error {
+	f := &loopFlags
+	ctn, err := openContainer(f.filePath, f.verbose)
+	if err != nil {
+		return err
+	}
+	defer ctn.Close()
+	soundBank, ok := ctn.(*bnk.File)
+	if !ok {
+		return fmt.Errorf("%s is not a SoundBank; loop values can only be "+
+			"edited in SoundBanks", f.filePath)
+	}
+
+	var edits []*loopEdit
+	switch {
+	case f.batchPath != "":
+		edits, err = readLoopBatch(ctn, f.batchPath)
+	case f.value != "" || f.clear:
+		edit := new(loopEdit)
+		edit.index, err = wemIndexOf(ctn, f.wemIndex, f.wemId)
+		if err == nil && !f.clear {
+			edit.loop, err = wwise.ParseLoopValue(f.value)
+		}
+		edits = append(edits, edit)
+	default:
+		return printLoops(soundBank, f.wemIndex, f.wemId)
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, edit := range edits {
+		if !soundBank.CanLoop(edit.index) {
+			return fmt.Errorf("The wem at index %d is not played by a sound "+
+				"effect, so its loop value cannot be changed", edit.index+1)
+		}
+	}
+	for _, edit := range edits {
+		soundBank.ReplaceLoopOf(edit.index, edit.loop)
+		fmt.Printf("Wem %d: %s\n", edit.index+1, edit.loop)
+	}
+	err = writeContainer(ctn, f.output)
+	if err != nil || !f.journal {
+		return err
+	}
+	return writeJournal(f.filePath, f.output)
+}
+
+// printLoops prints the loop value of the wem selected by index or id, or of
+// every wem if neither is specified.
+func printLoops(b *bnk.File, index int, id uint) error {
+	indexes := make([]int, len(b.Wems()))
+	for i := range indexes {
+		indexes[i] = i
+	}
+	if index != 0 || id != 0 {
+		i, err := wemIndexOf(b, index, id)
+		if err != nil {
+			return err
+		}
+		indexes = []int{i}
+	}
+
+	fmt.Printf("%-7s|%-15s|%s\n", "Index", "Id", "Loops")
+	fmt.Println(strings.Repeat("-", 40))
+	for _, i := range indexes {
+		fmt.Printf("%-7d|%-15d|%s\n", i+1, b.Wems()[i].Descriptor.WemId,
+			b.LoopOf(i))
+	}
+	return nil
+}
+
+// readLoopBatch reads the loop changes to make to the wems of c from the batch
+// file at path.
+func readLoopBatch(c wwise.Container, path string) ([]*loopEdit, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("Could not open batch file: %s", err)
+	}
+	defer f.Close()
+
+	var edits []*loopEdit
+	s := bufio.NewScanner(f)
+	for line := 1; s.Scan(); line++ {
+		text := strings.TrimSpace(s.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		edit, err := parseLoopEdit(c, text)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %s", path, line, err)
+		}
+		edits = append(edits, edit)
+	}
+	if err := s.Err(); err != nil {
+		return nil, fmt.Errorf("Could not read batch file: %s", err)
+	}
+	if len(edits) == 0 {
+		return nil, errors.New("The batch file has no loop changes")
+	}
+	return edits, nil
+}
+
+// parseLoopEdit parses a single line of a batch file.
+func parseLoopEdit(c wwise.Container, text string) (*loopEdit, error) {
+	fields := strings.Fields(text)
+	if len(fields) != 2 {
+		return nil, fmt.Errorf("Expected a wem and a loop value, but got %q", text)
+	}
+
+	var index int
+	var id uint64
+	var err error
+	if strings.HasPrefix(fields[0], batchIdPrefix) {
+		id, err = strconv.ParseUint(strings.TrimPrefix(fields[0], batchIdPrefix),
+			10, 32)
+	} else {
+		index, err = strconv.Atoi(fields[0])
+	}
+	if err != nil || (index == 0 && id == 0) {
+		return nil, fmt.Errorf("%s is not a valid wem index or ID", fields[0])
+	}
+
+	edit := new(loopEdit)
+	edit.index, err = wemIndexOf(c, index, uint(id))
+	if err != nil {
+		return nil, err
+	}
+	edit.loop, err = wwise.ParseLoopValue(fields[1])
+	if err != nil {
+		return nil, err
+	}
+	return edit, nil
+}
@@ -3,289 +3,291 @@ package main
 import (
 	"flag"
 	"fmt"
-	"io"
-	"io/ioutil"
 	"log"
 	"os"
 	"path/filepath"
-	"strconv"
+	"runtime"
+	"sort"
 	"strings"
 )
 
 import (
-	"github.com/hpxro7/wwiseutil/bnk"
-	"github.com/hpxro7/wwiseutil/pck"
-	"github.com/hpxro7/wwiseutil/util"
+	"github.com/hpxro7/wwiseutil/container"
 	"github.com/hpxro7/wwiseutil/wwise"
 )
 
-const shorthandSuffix = " (shorthand)"
-const wemExtension = ".wem"
+const programName = "wwiseutil"
 
-var shouldUnpack bool
-var shouldReplace bool
-var filePath string
-var output string
-var targetPath string
-var verbose bool
+// The usage of the verbose flag, which is shared by every command that opens
+// a container.
+const verboseUsage = "Shows additional information about the strcuture of " +
+	"the parsed SoundBank or File Package file."
 
 type flagError string
 
-func init() {
-	const (
-		usage    = "unpack a .bnk or .pck into seperate .wem files"
-		flagName = "unpack"
-	)
-	flag.BoolVar(&shouldUnpack, flagName, false, usage)
-	flag.BoolVar(&shouldUnpack, "u", false, shorthandDesc(flagName))
+// A command is a single operation of this tool, with its own set of flags.
+type command struct {
+	name string
+	// A one line description of this command, shown in the list of commands.
+	summary string
+	// The positional arguments of this command, shown in its usage.
+	args  string
+	flags *flag.FlagSet
+	// Verifies the parsed flags of this command, returning an error if they are
+	// invalid.
+	verify func() flagError
+	// Runs this command after its flags have been parsed and verified.
+	run func() error
 }
 
-func init() {
-	const (
-		usage = "replace a set of .wem files from a source .bnk or .pck file, " +
-			"outputing a fully usable .bnk or .pck with wems, offsets and lengths " +
-			"updated."
-		flagName = "replace"
-	)
-	flag.BoolVar(&shouldReplace, flagName, false, usage)
-	flag.BoolVar(&shouldReplace, "r", false, shorthandDesc(flagName))
+// The commands of this tool, by name.
+var commands = make(map[string]*command)
+
+// register adds c to the commands of this tool. It should be called from the
+// init function of the file implementing c.
+func register(c *command) {
+	c.flags.Usage = func() {
+		out := c.flags.Output()
+		line := strings.TrimSpace(fmt.Sprintf("%s %s [flags] %s", programName,
+			c.name, c.args))
+		fmt.Fprintf(out, "Usage: %s\n\n%s.\n\nFlags:\n", line, c.summary)
+		c.flags.PrintDefaults()
+	}
+	commands[c.name] = c
 }
 
-func init() {
-	const (
-		usage = "the path to the source .bnk or .pck. When unpack is used, this " +
-			"is the bnk or pck file to unpack. When replace is used, this .bnk or " +
-			".pck is used as a source; the wem files, offsets and lengths of this " +
-			".bnk or .pck will updated and written to the file specified by output."
-		flagName = "filepath"
-	)
-	flag.StringVar(&filePath, flagName, "", usage)
-	flag.StringVar(&filePath, "f", "", shorthandDesc(flagName))
+// newFlagSet creates the flag set for the command called name.
+func newFlagSet(name string) *flag.FlagSet {
+	return flag.NewFlagSet(name, flag.ExitOnError)
 }
 
-func init() {
-	const (
-		usage = "When unpack is used, this is the directory to output unpacked " +
-			".wem files. When replace is used, this is the directory to output the " +
-			"updated .bnk or .pck."
-		flagName = "output"
-	)
-	flag.StringVar(&output, flagName, "", usage)
-	flag.StringVar(&output, "o", "", shorthandDesc(flagName))
+func stringFlag(fs *flag.FlagSet, p *string, name, shorthand, usage string) {
+	fs.StringVar(p, name, "", usage)
+	if shorthand != "" {
+		fs.StringVar(p, shorthand, "", shorthandDesc(name))
+	}
 }
 
-func init() {
-	const (
-		usage = "The directory to find .wem files in for replacing. Each wem " +
-			"file's name must be a number corresponding to the index of the wem " +
-			"file to replace from the source SoundBank or File Package. The index " +
-			"of the first wem file is 1. The wems in the source SoundBank will be " +
-			"replaced with the wems in this directory. These wems must not be " +
-			"padded ahead of time; this tool will automatically add any padding " +
-			"needed."
-		flagName = "target"
-	)
-	flag.StringVar(&targetPath, flagName, "", usage)
-	flag.StringVar(&targetPath, "t", "", shorthandDesc(flagName))
+func boolFlag(fs *flag.FlagSet, p *bool, name, shorthand, usage string) {
+	fs.BoolVar(p, name, false, usage)
+	if shorthand != "" {
+		fs.BoolVar(p, shorthand, false, shorthandDesc(name))
+	}
 }
 
-func init() {
-	const (
-		usage = "Shows additional information about the strcuture of the parsed " +
-			"SoundBank or File Package file."
-		flagName = "verbose"
-	)
-	flag.BoolVar(&verbose, flagName, false, usage)
-	flag.BoolVar(&verbose, "v", false, shorthandDesc(flagName))
+// requireFlags returns an error for the first flag that is empty, given a list
+// of alternating flag names and values.
+func requireFlags(namesAndValues ...string) flagError {
+	for i := 0; i+1 < len(namesAndValues); i += 2 {
+		if namesAndValues[i+1] == "" {
+			return flagError(namesAndValues[i] + " cannot be empty")
+		}
+	}
+	return ""
 }
 
-func shorthandDesc(flagName string) string {
-	return "(shorthand for -" + flagName + ")"
+// workersFlag defines the flag controlling how many files a command processes
+// at once when given a directory.
+func workersFlag(fs *flag.FlagSet, p *int) {
+	fs.IntVar(p, "workers", runtime.NumCPU(),
+		"When the input is a directory, the number of files to process at once.")
 }
 
-func verifyFlags() {
-	var err flagError
-	switch {
-	case !(shouldUnpack || shouldReplace):
-		err = "Either unpack or replace should be specified"
-	case shouldUnpack && shouldReplace:
-		err = "Both unpack and replace cannot be specified"
-	case filePath == "":
-		err = "bnkpath cannot be empty"
-	case output == "":
-		err = "output cannot be empty"
-	}
-
-	if err != "" {
-		flag.Usage()
-		log.Fatal(err)
-	}
+// The flags naming the .wem files of a directory, which are shared by every
+// command that reads or writes directories of wems.
+type namingFlags struct {
+	template  string
+	namesPath string
+	// The parsed template, set by verify.
+	parsed *wwise.NameTemplate
 }
 
-func verifyReplaceFlags() {
-	var err flagError
-	switch {
-	case targetPath == "":
-		err = "target cannot be empty"
-	}
-
-	if err != "" {
-		flag.Usage()
-		log.Fatal(err)
-	}
+// addNamingFlags defines the flags naming the .wem files of a directory.
+func addNamingFlags(fs *flag.FlagSet, n *namingFlags) {
+	fs.StringVar(&n.template, "name", wwise.DefaultNameTemplate,
+		"the template naming each .wem file. The placeholders {index}, {id}, "+
+			"{name}, {lang} and {ext} are replaced with the index of the wem, "+
+			"where 1 is the first wem, its ID, its name from the names file (or "+
+			"its ID if it has none), its language and its file extension.")
+	fs.StringVar(&n.namesPath, "names", "",
+		"the path to a file naming wems for {name}. Each line holds either a "+
+			"wem ID followed by its name, or a name alone, whose ID is its Wwise "+
+			"hash.")
 }
 
-// Verifies that the extension of the input file is supported. Returns true if
-// the file is a SoundBank file and false if it is a File Package file.
-func verifyInputType() bool {
-	fileType, ext := util.GetFileType(filePath)
-	isSoundBank := fileType == util.SoundBankFileType
-	isFilePath := fileType == util.FilePackageFileType
-	if !(isSoundBank || isFilePath) {
-		flag.Usage()
-		log.Fatal(ext, ", is not a supported input file type")
-	}
-	return isSoundBank
+// A wemNaming names the .wem files of a directory.
+type wemNaming struct {
+	template *wwise.NameTemplate
+	// The names of wems by their ID, as used for {name}.
+	names map[uint32]string
 }
 
-func unpack(isSoundBank bool) {
-	var ctn wwise.Container
+// verify parses the naming template given by n, returning an error if it is
+// invalid.
+func (n *namingFlags) verify() flagError {
 	var err error
-
-	if isSoundBank {
-		ctn, err = bnk.Open(filePath)
-	} else { // Input is file package
-		ctn, err = pck.Open(filePath)
-	}
-	defer ctn.Close()
-
+	n.parsed, err = wwise.ParseNameTemplate(n.template)
 	if err != nil {
-		log.Fatalln("Could not parse .bnk or .pck file:", err)
-	}
-	if verbose {
-		fmt.Println(ctn)
+		return flagError(err.Error())
 	}
+	return ""
+}
 
-	err = createDirIfEmpty(output)
-	if err != nil {
-		log.Fatalln("Could not create output directory:", err)
-	}
-	total := int64(0)
-	for i, wem := range ctn.Wems() {
-		filename := util.CanonicalWemName(i, len(ctn.Wems()))
-		f, err := os.Create(filepath.Join(output, filename))
+// load reads the names file given by n. It must be called after verify.
+func (n *namingFlags) load() (*wemNaming, error) {
+	naming := &wemNaming{template: n.parsed}
+	if n.namesPath != "" {
+		var err error
+		naming.names, err = wwise.LoadNames(n.namesPath)
 		if err != nil {
-			log.Fatalf("Could not create wem file \"%s\": %s", filename, err)
+			return nil, fmt.Errorf("Could not read names file: %s", err)
 		}
-		n, err := io.Copy(f, wem)
-		if err != nil {
-			log.Fatalf("Could not write wem file \"%s\": %s", filename, err)
-		}
-		total += n
 	}
-	fmt.Printf("Successfully wrote %d wem(s) to %s\n", len(ctn.Wems()),
-		output)
-	fmt.Printf("Wrote %d bytes in total\n", total)
+	return naming, nil
 }
 
-func replace(isSoundBank bool) {
-	var ctn wwise.Container
-	var err error
+// nameOf returns the file name of the wem at index i of the container described
+// by listing, written with the extension ext.
+func (n *wemNaming) nameOf(listing *wwise.Listing, i int, ext string) string {
+	wem := listing.Wems[i]
+	return n.template.Format(&wwise.WemName{
+		Index:    i,
+		Count:    len(listing.Wems),
+		Id:       wem.Id,
+		Name:     n.names[wem.Id],
+		Language: wem.Language,
+		Ext:      ext,
+	})
+}
+
+func shorthandDesc(flagName string) string {
+	return "(shorthand for -" + flagName + ")"
+}
 
-	if isSoundBank {
-		ctn, err = bnk.Open(filePath)
-	} else { // Input is file package
-		ctn, err = pck.Open(filePath)
+func usage() {
+	out := flag.CommandLine.Output()
+	fmt.Fprintf(out, "Usage: %s <command> [flags]\n\nCommands:\n", programName)
+	var names []string
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(out, "  %-12s %s\n", name, commands[name].summary)
 	}
-	defer ctn.Close()
+	fmt.Fprintf(out, "\nRun \"%s help <command>\" for the flags of a command.\n",
+		programName)
+}
 
+// openContainer opens the SoundBank or File Package at path. If verbose is
+// true, the structure of the parsed file is printed.
+func openContainer(path string, verbose bool) (wwise.Container, error) {
+	ctn, err := container.Open(path)
 	if err != nil {
-		log.Fatalln("Could not parse .bnk or .pck file:", err)
+		return nil, fmt.Errorf("Could not parse .bnk or .pck file: %s", err)
 	}
 	if verbose {
 		fmt.Println(ctn)
 	}
+	return ctn, nil
+}
 
-	targetFileInfos, err := ioutil.ReadDir(targetPath)
+// inputPaths returns the paths of the containers to process for the input
+// path. If path is a directory, these are all of the containers within it, and
+// dir is true.
+func inputPaths(path string) (paths []string, dir bool, err error) {
+	info, err := os.Stat(path)
 	if err != nil {
-		log.Fatalf("Could not open target directory, \"%s\": %s\n", targetPath, err)
+		return nil, false, err
 	}
-	targets := processTargetFiles(ctn, targetFileInfos)
-
-	ctn.ReplaceWems(targets...)
-
-	outputFile, err := os.Create(output)
-	if err != nil {
-		log.Fatalf("Could not create output file \"%s\": %s\n", output, err)
+	if !info.IsDir() {
+		return []string{path}, false, nil
 	}
-	total, err := ctn.WriteTo(outputFile)
+	paths, err = container.Walk(path)
 	if err != nil {
-		log.Fatalln("Could not write output to file: ", err)
+		return nil, true, fmt.Errorf("Could not search %s: %s", path, err)
 	}
-	fmt.Println("Sucessfuly replaced! Output file written to:", output)
-	fmt.Printf("Wrote %d bytes in total\n", total)
+	if len(paths) == 0 {
+		return nil, true, fmt.Errorf("There are no .bnk or .pck files in %s", path)
+	}
+	return paths, true, nil
 }
 
-func processTargetFiles(c wwise.Container,
-	fis []os.FileInfo) []*wwise.ReplacementWem {
-	var targets []*wwise.ReplacementWem
-	var names []string
-	for _, fi := range fis {
-		name := fi.Name()
-		ext := filepath.Ext(name)
-		if ext != wemExtension {
-			log.Printf("Ignoring %s: It does not have a .wem file extension",
-				name)
-			continue
-		}
-		wemIndex, err := strconv.Atoi(strings.TrimSuffix(name, ext))
-		// Wems are indexed internally starting from 0, but the file names start
-		// at 1.
-		wemIndex--
-		if err != nil {
-			log.Printf("Ignoring %s: It does not have a valid integer name",
-				name)
-			continue
-		}
-		if wemIndex < 0 || wemIndex >= len(c.Wems()) {
-			log.Printf("Ignoring %s: This files's valid index range is "+
-				"%d to %d", name, 1, len(c.Wems()))
-			continue
-		}
-		f, err := os.Open(filepath.Join(targetPath, name))
-		if err != nil {
-			log.Printf("Ignoring %s: Could not open file: %s", name, err)
-			continue
-		}
-
-		names = append(names, fi.Name())
-		targets = append(targets, &wwise.ReplacementWem{f, wemIndex, fi.Size()})
+// summarizeFailures prints every failure to process a file, and returns an
+// error if there were any.
+func summarizeFailures(failures []*container.FileError, total int) error {
+	if len(failures) == 0 {
+		return nil
 	}
-	if len(targets) == 0 {
-		log.Fatal("There are no replacement wems")
+	for _, f := range failures {
+		log.Print(f)
 	}
-	fmt.Printf("Using %d replacement wem(s): %s\n", len(targets),
-		strings.Join(names, ", "))
-	return targets
+	return fmt.Errorf("%d of %d file(s) could not be processed", len(failures),
+		total)
 }
 
 func createDirIfEmpty(path string) error {
 	if _, err := os.Stat(path); os.IsNotExist(err) {
-		return os.Mkdir(output, os.ModePerm)
+		return os.MkdirAll(path, os.ModePerm)
 	}
 	return nil
 }
 
+// Returns the path to target relative to the directory dir, or the absolute
+// path to target if there is no relative path.
+func relativePath(dir, target string) string {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return target
+	}
+	absTarget, err := filepath.Abs(target)
+	if err != nil {
+		return target
+	}
+	rel, err := filepath.Rel(absDir, absTarget)
+	if err != nil {
+		return absTarget
+	}
+	return rel
+}
+
 func main() {
+	flag.Usage = usage
 	flag.Parse()
-	verifyFlags()
-	isSoundBank := verifyInputType()
+	if flag.NArg() == 0 {
+		usage()
+		os.Exit(2)
+	}
 
-	switch {
-	case shouldUnpack:
-		unpack(isSoundBank)
-	case shouldReplace:
-		verifyReplaceFlags()
-		replace(isSoundBank)
+	name, args := flag.Arg(0), flag.Args()[1:]
+	if name == "help" {
+		if len(args) == 0 {
+			usage()
+			return
+		}
+		c, ok := commands[args[0]]
+		if !ok {
+			usage()
+			log.Fatalf("%s is not a command", args[0])
+		}
+		c.flags.SetOutput(os.Stdout)
+		c.flags.Usage()
+		return
+	}
+
+	c, ok := commands[name]
+	if !ok {
+		usage()
+		log.Fatalf("%s is not a command", name)
+	}
+	c.flags.Parse(args)
+	if c.verify != nil {
+		if err := c.verify(); err != "" {
+			c.flags.Usage()
+			log.Fatal(err)
+		}
+	}
+	if err := c.run(); err != nil {
+		log.Fatal(err)
 	}
 }
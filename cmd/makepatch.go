@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+import (
+	"github.com/hpxro7/wwiseutil/patch"
+)
+
+var makePatchFlags struct {
+	filePath     string
+	modifiedPath string
+	output       string
+}
+
+func init() {
+	fs := newFlagSet("makepatch")
+	f := &makePatchFlags
+	stringFlag(fs, &f.filePath, "filepath", "f",
+		"the path to the original .bnk or .pck.")
+	stringFlag(fs, &f.modifiedPath, "modified", "m",
+		"the path to the modified .bnk or .pck. It must hold the same wems as "+
+			"the original, and may only differ by the contents of its wems and "+
+			"the parameters of its sound objects.")
+	stringFlag(fs, &f.output, "output", "o",
+		"the path to write the patch to.")
+	register(&command{
+		name: "makepatch",
+		summary: "Create a patch recording the wems and sound object " +
+			"parameters changed in a modified .bnk or .pck",
+		flags: fs,
+		verify: func() flagError {
+			return requireFlags("filepath", f.filePath, "modified", f.modifiedPath,
+				"output", f.output)
+		},
+		run: makePatch,
+	})
+}
+
+func makePatch() error {
+	f := &makePatchFlags
+	p, err := patch.Make(f.filePath, f.modifiedPath)
+	if err != nil {
+		return fmt.Errorf("Could not create patch: %s", err)
+	}
+	out, err := os.Create(f.output)
+	if err != nil {
+		return fmt.Errorf("Could not create patch file \"%s\": %s", f.output, err)
+	}
+	defer out.Close()
+	total, err := p.WriteTo(out)
+	if err != nil {
+		return fmt.Errorf("Could not write patch: %s", err)
+	}
+	fmt.Printf("Recorded %d replaced wem(s) and %d parameter edit(s)\n",
+		len(p.Replacements), len(p.Edits))
+	fmt.Println("Successfully wrote patch file:", f.output)
+	fmt.Printf("Wrote %d bytes in total\n", total)
+	return nil
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+)
+
+import (
+	"github.com/hpxro7/wwiseutil/wwise"
+)
+
+var packFlags struct {
+	filePath   string
+	output     string
+	targetPath string
+	verbose    bool
+	strict     bool
+	naming     namingFlags
+}
+
+func init() {
+	fs := newFlagSet("pack")
+	f := &packFlags
+	stringFlag(fs, &f.filePath, "filepath", "f",
+		"the path to the source .bnk or .pck that was unpacked. Its layout is "+
+			"used as the template for the packed file.")
+	stringFlag(fs, &f.output, "output", "o",
+		"the path to write the packed .bnk or .pck to.")
+	stringFlag(fs, &f.targetPath, "target", "t",
+		"the directory holding a .wem file for every wem of the source .bnk or "+
+			".pck, named as written by unpack with the same naming template.")
+	boolFlag(fs, &f.verbose, "verbose", "v", verboseUsage)
+	boolFlag(fs, &f.strict, "strict", "",
+		"refuse to write the output file if any wem fails validation against "+
+			"the wem it replaces.")
+	addNamingFlags(fs, &f.naming)
+	register(&command{
+		name: "pack",
+		summary: "Pack a directory of unpacked .wem files back into the .bnk or " +
+			".pck they were unpacked from",
+		flags: fs,
+		verify: func() flagError {
+			if err := f.naming.verify(); err != "" {
+				return err
+			}
+			return requireFlags("filepath", f.filePath, "output", f.output,
+				"target", f.targetPath)
+		},
+		run: pack,
+	})
+}
+
+func pack() error {
+	f := &packFlags
+	ctn, err := openContainer(f.filePath, f.verbose)
+	if err != nil {
+		return err
+	}
+	defer ctn.Close()
+
+	naming, err := f.naming.load()
+	if err != nil {
+		return err
+	}
+	targets, target, err := processTargetFiles(ctn, f.targetPath, f.strict,
+		naming)
+	if err != nil {
+		return err
+	}
+	defer target.Close()
+	// Unlike replace, every wem of the container must be given.
+	found := make([]bool, len(ctn.Wems()))
+	for _, t := range targets {
+		found[t.WemIndex] = true
+	}
+	listing := ctn.Listing()
+	for i, ok := range found {
+		if !ok {
+			return fmt.Errorf("%s is missing from %s",
+				naming.nameOf(listing, i, wwise.WemExtension), f.targetPath)
+		}
+	}
+
+	ctn.ReplaceWems(targets...)
+	return writeContainer(ctn, f.output)
+}
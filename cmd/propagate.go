@@ -0,0 +1,321 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+import (
+	"github.com/hpxro7/wwiseutil/container"
+	"github.com/hpxro7/wwiseutil/wwise"
+)
+
+var propagateFlags struct {
+	index       indexFlags
+	targetPath  string
+	output      string
+	sameContent bool
+	strict      bool
+}
+
+func init() {
+	fs := newFlagSet("propagate")
+	f := &propagateFlags
+	addIndexFlags(fs, &f.index)
+	stringFlag(fs, &f.targetPath, "target", "t",
+		"the directory, or .zip, .tar or .tar.gz archive, of replacement .wem "+
+			"files. Each file must be named by the ID of the wem it replaces, "+
+			"such as 123456.wem.")
+	stringFlag(fs, &f.output, "output", "o",
+		"the directory to write every updated .bnk and .pck to, mirroring "+
+			"their paths within dir.")
+	boolFlag(fs, &f.sameContent, "content", "",
+		"also replace the wems stored under other IDs whose contents are "+
+			"identical to a copy of a replaced wem.")
+	boolFlag(fs, &f.strict, "strict", "",
+		"refuse to write any file if a replacement wem fails validation "+
+			"against a wem it replaces.")
+	register(&command{
+		name: "propagate",
+		summary: "Replace every copy of a set of wems across the .bnk and .pck " +
+			"files of a directory",
+		flags: fs,
+		verify: func() flagError {
+			return requireFlags("dir", f.index.dir, "target", f.targetPath,
+				"output", f.output)
+		},
+		run: propagate,
+	})
+}
+
+func propagate() error {
+	f := &propagateFlags
+	target, err := container.OpenTarget(f.targetPath)
+	if err != nil {
+		return fmt.Errorf("Could not open target \"%s\": %s", f.targetPath, err)
+	}
+	defer target.Close()
+	idx, err := f.index.load()
+	if err != nil {
+		return err
+	}
+
+	// The replacement for each copy of a wem, by the path of the container
+	// holding the copy.
+	replacements := make(map[string][]*copyReplacement)
+	for _, tf := range target.Files {
+		ext := filepath.Ext(tf.Name)
+		if ext != wwise.WemExtension {
+			log.Printf("Ignoring %s: It does not have a .wem file extension",
+				tf.Name)
+			continue
+		}
+		id, err := strconv.ParseUint(strings.TrimSuffix(tf.Name, ext), 10, 32)
+		if err != nil {
+			log.Printf("Ignoring %s: It is not named by a wem ID", tf.Name)
+			continue
+		}
+		copies, err := idx.Copies(f.index.dir, uint32(id), f.sameContent)
+		if err != nil {
+			return err
+		}
+		if len(copies) == 0 {
+			log.Printf("Ignoring %s: There are no copies of wem %d", tf.Name, id)
+			continue
+		}
+		for _, l := range copies {
+			replacements[l.Path] = append(replacements[l.Path],
+				&copyReplacement{tf, l})
+		}
+	}
+	if len(replacements) == 0 {
+		return errors.New("There are no wems to replace")
+	}
+
+	var paths []string
+	for path := range replacements {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	// Every container is changed and validated before any output is written.
+	var outputs []*propagation
+	closeAll := func() {
+		for _, o := range outputs {
+			o.ctn.Close()
+		}
+	}
+	defer closeAll()
+	for _, path := range paths {
+		o, err := prepareCopies(path, replacements[path])
+		if err != nil {
+			return fmt.Errorf("%s: %s", path, err)
+		}
+		outputs = append(outputs, o)
+	}
+
+	// Write every container to a temporary file next to its output, so that no
+	// output is changed unless all of them could be written.
+	removeTemps := func() {
+		for _, o := range outputs {
+			if o.temp != "" {
+				os.Remove(o.temp)
+			}
+		}
+	}
+	for _, o := range outputs {
+		fmt.Printf("Replacing %d wem(s) of %s\n", o.count, o.rel)
+		err = o.writeTemp()
+		if err != nil {
+			removeTemps()
+			return fmt.Errorf("Could not write %s: %s", o.output, err)
+		}
+	}
+
+	// Sources must be closed before they can be overwritten.
+	closeAll()
+	target.Close()
+	var committed []*propagation
+	for _, o := range outputs {
+		err = o.commit()
+		if err != nil {
+			for i := len(committed) - 1; i >= 0; i-- {
+				committed[i].restore()
+			}
+			removeTemps()
+			return fmt.Errorf("Could not move %s to %s: %s", o.temp, o.output,
+				err)
+		}
+		committed = append(committed, o)
+	}
+	for _, o := range outputs {
+		if o.backup != "" {
+			os.Remove(o.backup)
+		}
+		fmt.Println("Successfully wrote output file:", o.output)
+	}
+	return nil
+}
+
+// A copyReplacement replaces a single copy of a wem.
+type copyReplacement struct {
+	file     *container.TargetFile
+	location *container.WemLocation
+}
+
+// A propagation is a container whose copies of wems have been replaced, and
+// which is yet to be written to its output.
+type propagation struct {
+	ctn wwise.Container
+	// The path of the container, relative to the indexed directory.
+	rel    string
+	output string
+	// The number of wems replaced.
+	count int
+	// The temporary file that the container is written to before it is moved
+	// to its output, and the backup of any output that it replaced.
+	temp   string
+	backup string
+}
+
+// prepareCopies replaces the copies of wems in the container at path with rs.
+// The container must be closed once it has been written.
+func prepareCopies(path string, rs []*copyReplacement) (*propagation, error) {
+	f := &propagateFlags
+	ctn, err := openContainer(path, false)
+	if err != nil {
+		return nil, err
+	}
+	o, err := replaceCopies(ctn, path, rs)
+	if err != nil {
+		ctn.Close()
+		return nil, err
+	}
+	absDir, err := filepath.Abs(f.index.dir)
+	if err == nil {
+		o.rel, err = filepath.Rel(absDir, path)
+	}
+	if err != nil {
+		ctn.Close()
+		return nil, err
+	}
+	o.output = filepath.Join(f.output, o.rel)
+	return o, nil
+}
+
+// replaceCopies replaces the copies of wems in ctn, the container at path,
+// with rs, validating each replacement against the wem it replaces.
+func replaceCopies(ctn wwise.Container, path string,
+	rs []*copyReplacement) (*propagation, error) {
+	f := &propagateFlags
+	var targets []*wwise.ReplacementWem
+	// The name of the file replacing each wem, by its index.
+	used := make(map[int]string)
+	invalid := false
+	for _, r := range rs {
+		i := r.location.Index - 1
+		if used[i] != "" {
+			log.Printf("Ignoring %s for wem %d: %s already replaces it",
+				r.file.Name, i+1, used[i])
+			continue
+		}
+		used[i] = r.file.Name
+		if i >= len(ctn.Wems()) || ctn.Wems()[i].Descriptor.WemId !=
+			r.location.Id {
+			return nil, errors.New("The file changed since it was indexed")
+		}
+		reader, err := r.file.Open()
+		if err != nil {
+			return nil, err
+		}
+		t := &wwise.ReplacementWem{reader, i, r.file.Size}
+		issues := wwise.ValidateReplacement(ctn.Wems()[i], t)
+		for _, issue := range issues {
+			log.Printf("%s, wem %d: %s", r.file.Name, i+1, issue)
+		}
+		invalid = invalid || wwise.HasErrors(issues)
+		targets = append(targets, t)
+	}
+	if f.strict && invalid {
+		return nil, errors.New(
+			"Refusing to write output: some replacement wems are invalid")
+	}
+	ctn.ReplaceWems(targets...)
+	return &propagation{ctn: ctn, count: len(targets)}, nil
+}
+
+// writeTemp writes the changed container of this propagation to a new
+// temporary file in the directory of its output.
+func (o *propagation) writeTemp() error {
+	dir := filepath.Dir(o.output)
+	err := createDirIfEmpty(dir)
+	if err != nil {
+		return err
+	}
+	// Keep the extension of the output, so that the file can be opened as a
+	// container.
+	f, err := ioutil.TempFile(dir, ".*."+filepath.Base(o.output))
+	if err != nil {
+		return err
+	}
+	o.temp = f.Name()
+	// Temporary files are only readable by their owner; keep the mode of any
+	// existing output instead.
+	mode := os.FileMode(0644)
+	if stat, err := os.Stat(o.output); err == nil {
+		mode = stat.Mode()
+	}
+	err = f.Chmod(mode)
+	if err == nil {
+		_, err = o.ctn.WriteTo(f)
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// commit moves the temporary file of this propagation to its output. Any
+// existing output is first moved to a backup next to it, from which restore
+// can put it back.
+func (o *propagation) commit() error {
+	if _, err := os.Lstat(o.output); err == nil {
+		f, err := ioutil.TempFile(filepath.Dir(o.output),
+			".*."+filepath.Base(o.output)+".bak")
+		if err != nil {
+			return err
+		}
+		f.Close()
+		err = os.Rename(o.output, f.Name())
+		if err != nil {
+			os.Remove(f.Name())
+			return err
+		}
+		o.backup = f.Name()
+	}
+	err := os.Rename(o.temp, o.output)
+	if err != nil {
+		o.restore()
+		return err
+	}
+	o.temp = ""
+	return nil
+}
+
+// restore undoes commit, putting back the output that the temporary file of
+// this propagation replaced, or removing the output if there was none.
+func (o *propagation) restore() {
+	if o.backup == "" {
+		os.Remove(o.output)
+		return
+	}
+	os.Rename(o.backup, o.output)
+	o.backup = ""
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+)
+
+import (
+	"github.com/hpxro7/wwiseutil/patch"
+)
+
+var rebaseFlags struct {
+	oldPath      string
+	modifiedPath string
+	newPath      string
+	output       string
+	force        bool
+}
+
+func init() {
+	fs := newFlagSet("rebase")
+	f := &rebaseFlags
+	stringFlag(fs, &f.oldPath, "old", "a",
+		"the path to the original .bnk or .pck that the modifications were made "+
+			"to.")
+	stringFlag(fs, &f.modifiedPath, "modified", "m",
+		"the path to the modified version of the original .bnk or .pck.")
+	stringFlag(fs, &f.newPath, "new", "b",
+		"the path to the updated .bnk or .pck, such as one from after a game "+
+			"patch, to replay the modifications onto.")
+	stringFlag(fs, &f.output, "output", "o",
+		"the path to write the rebased .bnk or .pck to.")
+	boolFlag(fs, &f.force, "force", "",
+		"if set, changes to wems or parameters that the updated version also "+
+			"changed are replayed anyway, overwriting the changes of the update.")
+	register(&command{
+		name: "rebase",
+		summary: "Replay the replaced wems and loop changes of a modified .bnk " +
+			"or .pck onto an updated version of the original",
+		flags: fs,
+		verify: func() flagError {
+			return requireFlags("old", f.oldPath, "modified", f.modifiedPath,
+				"new", f.newPath, "output", f.output)
+		},
+		run: rebase,
+	})
+}
+
+func rebase() error {
+	f := &rebaseFlags
+	oldBase, err := openContainer(f.oldPath, false)
+	if err != nil {
+		return err
+	}
+	defer oldBase.Close()
+	modified, err := openContainer(f.modifiedPath, false)
+	if err != nil {
+		return err
+	}
+	defer modified.Close()
+	newBase, err := openContainer(f.newPath, false)
+	if err != nil {
+		return err
+	}
+	defer newBase.Close()
+
+	result, err := patch.Rebase(oldBase, modified, newBase, f.force)
+	if err != nil {
+		return err
+	}
+	skipped := 0
+	for _, c := range result.Conflicts {
+		fmt.Println("Conflict:", c)
+		if !c.Forced {
+			skipped++
+		}
+	}
+	fmt.Printf("Replayed %d replaced wem(s) and %d parameter edit(s)\n",
+		result.Replaced, result.Edited)
+	if skipped > 0 {
+		fmt.Printf("Skipped %d conflicting change(s)", skipped)
+		if !f.force {
+			fmt.Print("; rerun with -force to replay those the update also " +
+				"changed")
+		}
+		fmt.Println()
+	}
+	return writeContainer(newBase, f.output)
+}
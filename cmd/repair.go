@@ -0,0 +1,77 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+)
+
+import (
+	"github.com/hpxro7/wwiseutil/bnk"
+	"github.com/hpxro7/wwiseutil/util"
+)
+
+var repairFlags struct {
+	filePath string
+	output   string
+	dryRun   bool
+}
+
+func init() {
+	fs := newFlagSet("repair")
+	f := &repairFlags
+	stringFlag(fs, &f.filePath, "filepath", "f",
+		"the path to the .bnk to repair, such as one written by an older mod "+
+			"tool.")
+	stringFlag(fs, &f.output, "output", "o",
+		"the path to write the repaired .bnk to.")
+	boolFlag(fs, &f.dryRun, "dry-run", "n",
+		"report every correction without writing anything.")
+	register(&command{
+		name: "repair",
+		summary: "Rebuild the wem offsets, section lengths and embedded wem " +
+			"lengths of a .bnk whose headers disagree with its contents",
+		flags: fs,
+		verify: func() flagError {
+			if f.dryRun {
+				return requireFlags("filepath", f.filePath)
+			}
+			return requireFlags("filepath", f.filePath, "output", f.output)
+		},
+		run: repair,
+	})
+}
+
+func repair() error {
+	f := &repairFlags
+	if t, _ := util.GetFileType(f.filePath); t != util.SoundBankFileType {
+		return errors.New("Only .bnk files can be repaired")
+	}
+	in, err := os.Open(f.filePath)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	soundBank, corrections, err := bnk.Repair(in)
+	if err != nil {
+		return fmt.Errorf("Could not repair %s: %s", f.filePath, err)
+	}
+
+	for _, c := range corrections {
+		fmt.Println(c)
+	}
+	if len(corrections) == 0 {
+		fmt.Println("No corrections were needed")
+	} else {
+		fmt.Printf("Made %d correction(s)\n", len(corrections))
+	}
+	for _, p := range soundBank.Verify() {
+		log.Printf("Could not repair: %s", p)
+	}
+	if f.dryRun {
+		fmt.Println("Dry run: nothing was written")
+		return nil
+	}
+	return writeContainer(soundBank, f.output)
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+)
+
+import (
+	"github.com/hpxro7/wwiseutil/container"
+	"github.com/hpxro7/wwiseutil/wwise"
+)
+
+var replaceFlags struct {
+	filePath   string
+	output     string
+	targetPath string
+	verbose    bool
+	strict     bool
+	dryRun     bool
+	journal    bool
+	naming     namingFlags
+}
+
+// The usage of the target flag, which is shared by every command that reads
+// replacement wems.
+const targetUsage = "The directory, or .zip, .tar or .tar.gz archive, to " +
+	"find .wem files in for replacing. Archives are read without being " +
+	"extracted. Each wem file's name must match the naming template given by " +
+	"-name, which by default is a number corresponding to the index of the " +
+	"wem file to replace from the source SoundBank or File Package. The index " +
+	"of the first wem file is 1. The wems in the source SoundBank will be " +
+	"replaced with the wems in this target. These wems must not be padded " +
+	"ahead of time; this tool will automatically add any padding needed."
+
+// The usage of the dry-run flag, which is shared by every command that can
+// preview its changes.
+const dryRunUsage = "make every change in memory and report the resulting " +
+	"offsets, lengths and padding of each wem, the change in section lengths " +
+	"and the final output size, without writing anything."
+
+func init() {
+	fs := newFlagSet("replace")
+	f := &replaceFlags
+	stringFlag(fs, &f.filePath, "filepath", "f",
+		"the path to the source .bnk or .pck. The wem files, offsets and "+
+			"lengths of this .bnk or .pck will updated and written to the file "+
+			"specified by output.")
+	stringFlag(fs, &f.output, "output", "o",
+		"the path to write the updated .bnk or .pck to.")
+	stringFlag(fs, &f.targetPath, "target", "t", targetUsage)
+	boolFlag(fs, &f.verbose, "verbose", "v", verboseUsage)
+	boolFlag(fs, &f.strict, "strict", "",
+		"refuse to write the output file if any replacement wem fails "+
+			"validation against the wem it replaces.")
+	boolFlag(fs, &f.dryRun, "dry-run", "n", dryRunUsage)
+	boolFlag(fs, &f.journal, "journal", "", journalUsage)
+	addNamingFlags(fs, &f.naming)
+	register(&command{
+		name: "replace",
+		summary: "Replace a set of .wem files from a source .bnk or .pck file, " +
+			"outputing a fully usable .bnk or .pck with wems, offsets and lengths " +
+			"updated",
+		flags: fs,
+		verify: func() flagError {
+			if err := f.naming.verify(); err != "" {
+				return err
+			}
+			if f.dryRun {
+				return requireFlags("filepath", f.filePath, "target", f.targetPath)
+			}
+			return requireFlags("filepath", f.filePath, "output", f.output,
+				"target", f.targetPath)
+		},
+		run: replace,
+	})
+}
+
+func replace() error {
+	f := &replaceFlags
+	ctn, err := openContainer(f.filePath, f.verbose)
+	if err != nil {
+		return err
+	}
+	defer ctn.Close()
+
+	naming, err := f.naming.load()
+	if err != nil {
+		return err
+	}
+	targets, target, err := processTargetFiles(ctn, f.targetPath, f.strict,
+		naming)
+	if err != nil {
+		return err
+	}
+	defer target.Close()
+	before := ctn.Listing()
+	ctn.ReplaceWems(targets...)
+	if f.dryRun {
+		fmt.Print(wwise.CompareLayouts(before, ctn.Listing()))
+		fmt.Println("Dry run: nothing was written")
+		return nil
+	}
+	err = writeContainer(ctn, f.output)
+	if err != nil || !f.journal {
+		return err
+	}
+	return writeJournal(f.filePath, f.output)
+}
+
+// writeContainer writes ctn to a new file at path.
+func writeContainer(ctn wwise.Container, path string) error {
+	outputFile, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("Could not create output file \"%s\": %s", path, err)
+	}
+	defer outputFile.Close()
+	total, err := ctn.WriteTo(outputFile)
+	if err != nil {
+		return fmt.Errorf("Could not write output to file: %s", err)
+	}
+	fmt.Println("Successfully wrote output file:", path)
+	fmt.Printf("Wrote %d bytes in total\n", total)
+	return nil
+}
+
+// processTargetFiles opens the replacement wems in the target at path, which is
+// either a directory or a .zip, .tar or .tar.gz archive. The wems are named by
+// naming, and are validated against the wems of c that they replace. If strict
+// is true, an error is returned if any replacement fails validation. The
+// returned target must be closed once the replacements have been written.
+func processTargetFiles(c wwise.Container, path string, strict bool,
+	naming *wemNaming) ([]*wwise.ReplacementWem, io.Closer, error) {
+	target, err := container.OpenTarget(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Could not open target \"%s\": %s", path,
+			err)
+	}
+
+	var targets []*wwise.ReplacementWem
+	var names []string
+	// The name of the file replacing each wem, by its index.
+	used := make(map[int]string)
+	invalid := false
+	listing := c.Listing()
+	for _, tf := range target.Files {
+		name := tf.Name
+		wemIndex, ext, err := naming.template.IndexOf(name, listing,
+			naming.names)
+		if ext != "" && ext != wwise.WemExtension {
+			log.Printf("Ignoring %s: It does not have a .wem file extension",
+				name)
+			continue
+		}
+		if err != nil {
+			log.Printf("Ignoring %s: %s", name, err)
+			continue
+		}
+		if used[wemIndex] != "" {
+			log.Printf("Ignoring %s: %s already replaces the same wem", name,
+				used[wemIndex])
+			continue
+		}
+		f, err := tf.Open()
+		if err != nil {
+			log.Printf("Ignoring %s: Could not open file: %s", name, err)
+			continue
+		}
+
+		r := &wwise.ReplacementWem{f, wemIndex, tf.Size}
+		issues := wwise.ValidateReplacement(c.Wems()[wemIndex], r)
+		for _, issue := range issues {
+			log.Printf("%s: %s", name, issue)
+		}
+		invalid = invalid || wwise.HasErrors(issues)
+
+		used[wemIndex] = name
+		names = append(names, name)
+		targets = append(targets, r)
+	}
+	if len(targets) == 0 {
+		target.Close()
+		return nil, nil, errors.New("There are no replacement wems")
+	}
+	if strict && invalid {
+		target.Close()
+		return nil, nil, errors.New(
+			"Refusing to write output: some replacement wems are invalid")
+	}
+	fmt.Printf("Using %d replacement wem(s): %s\n", len(targets),
+		strings.Join(names, ", "))
+	return targets, target, nil
+}
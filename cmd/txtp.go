@@ -0,0 +1,157 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+import (
+	"github.com/hpxro7/wwiseutil/bnk"
+	"github.com/hpxro7/wwiseutil/util"
+	"github.com/hpxro7/wwiseutil/wwise"
+)
+
+var txtpFlags struct {
+	filePath string
+	output   string
+	wemsPath string
+	naming   namingFlags
+}
+
+func init() {
+	fs := newFlagSet("txtp")
+	f := &txtpFlags
+	stringFlag(fs, &f.filePath, "filepath", "f",
+		"the path to the .bnk or .pck to write playlists for.")
+	stringFlag(fs, &f.output, "output", "o",
+		"the directory to output .txtp playlists to.")
+	fs.StringVar(&f.wemsPath, "wems", "",
+		"the directory holding the unpacked .wem files that playlists should "+
+			"play. If this is empty, playlists play the wems from within the "+
+			"source .bnk or .pck instead.")
+	addNamingFlags(fs, &f.naming)
+	register(&command{
+		name: "txtp",
+		summary: "Write a vgmstream .txtp playlist for every wem and event in a " +
+			".bnk or .pck",
+		flags: fs,
+		verify: func() flagError {
+			if err := f.naming.verify(); err != "" {
+				return err
+			}
+			return requireFlags("filepath", f.filePath, "output", f.output)
+		},
+		run: exportTxtp,
+	})
+}
+
+func exportTxtp() error {
+	f := &txtpFlags
+	ctn, err := openContainer(f.filePath, false)
+	if err != nil {
+		return err
+	}
+	defer ctn.Close()
+
+	err = createDirIfEmpty(f.output)
+	if err != nil {
+		return fmt.Errorf("Could not create output directory: %s", err)
+	}
+
+	naming, err := f.naming.load()
+	if err != nil {
+		return err
+	}
+	listing := ctn.Listing()
+	count := len(ctn.Wems())
+	soundBank, _ := ctn.(*bnk.File)
+	// Returns the playlist entry that plays the wem at index i.
+	entryOf := func(i int) *wwise.TxtpEntry {
+		e := new(wwise.TxtpEntry)
+		if f.wemsPath != "" {
+			name := naming.nameOf(listing, i, wwise.WemExtension)
+			e.Path = relativePath(f.output, filepath.Join(f.wemsPath, name))
+		} else {
+			e.Path, e.Subsong = relativePath(f.output, f.filePath), i+1
+		}
+		if soundBank != nil {
+			loop := soundBank.LoopOf(i)
+			e.Loop = &loop
+		}
+		return e
+	}
+
+	base := strings.TrimSuffix(filepath.Base(f.filePath),
+		filepath.Ext(f.filePath))
+	var names []string
+	txtps := make(map[string]*wwise.Txtp)
+	for i, wem := range ctn.Wems() {
+		index := strings.TrimSuffix(util.CanonicalWemName(i, count),
+			wwise.WemExtension)
+		name := fmt.Sprintf("%s_%s", base, index)
+		names = append(names, name)
+		txtps[name] = &wwise.Txtp{
+			Comment: fmt.Sprintf("wem %d", wem.Descriptor.WemId),
+			Lines:   []wwise.TxtpLine{entryOf(i)},
+		}
+	}
+	if soundBank != nil {
+		for _, event := range soundBank.Events() {
+			p := soundBank.EventPlayback(event)
+			if p == nil {
+				continue
+			}
+			id := event.Descriptor.ObjectId
+			name := fmt.Sprintf("%s_event_%d", base, id)
+			t := &wwise.Txtp{Comment: fmt.Sprintf("event %d", id)}
+			addPlayback(t, p, entryOf)
+			names = append(names, name)
+			txtps[name] = t
+		}
+	}
+
+	for _, name := range names {
+		filename := name + wwise.TxtpExtension
+		out, err := os.Create(filepath.Join(f.output, filename))
+		if err != nil {
+			return fmt.Errorf("Could not create playlist \"%s\": %s", filename, err)
+		}
+		_, err = txtps[name].WriteTo(out)
+		out.Close()
+		if err != nil {
+			return fmt.Errorf("Could not write playlist \"%s\": %s", filename, err)
+		}
+	}
+	fmt.Printf("Successfully wrote %d playlist(s) to %s\n", len(names), f.output)
+	return nil
+}
+
+// addPlayback appends the lines that play p to t, where entryOf returns the
+// entry that plays the wem at index i. Every container is written as a group
+// of its children. vgmstream cannot follow switches, so switch containers play
+// their first child.
+func addPlayback(t *wwise.Txtp, p *bnk.Playback,
+	entryOf func(i int) *wwise.TxtpEntry) {
+	if p.Wem >= 0 {
+		t.Lines = append(t.Lines, entryOf(p.Wem))
+		return
+	}
+	for _, c := range p.Children {
+		addPlayback(t, c, entryOf)
+	}
+	if len(p.Children) < 2 {
+		return
+	}
+	g := &wwise.TxtpGroup{Type: wwise.TxtpSequence, Count: len(p.Children)}
+	switch p.Mode {
+	case bnk.PlayRandom:
+		g.Type = wwise.TxtpRandom
+	case bnk.PlaySwitch:
+		g.Type, g.Selected = wwise.TxtpRandom, 1
+	case bnk.PlayLayered:
+		g.Type = wwise.TxtpLayer
+	}
+	t.Lines = append(t.Lines, g)
+}
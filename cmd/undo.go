@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+import (
+	"github.com/hpxro7/wwiseutil/patch"
+)
+
+var undoFlags struct {
+	filePath    string
+	journalPath string
+	output      string
+}
+
+// The usage of the journal flag, which is shared by every command that writes
+// a modified container.
+const journalUsage = "also write a journal next to the output, named by " +
+	"adding " + patch.JournalExtension + " to its path, which holds what was " +
+	"overwritten so that the undo command can restore the original file."
+
+func init() {
+	fs := newFlagSet("undo")
+	f := &undoFlags
+	stringFlag(fs, &f.filePath, "filepath", "f",
+		"the path to the modified .bnk or .pck to restore.")
+	stringFlag(fs, &f.journalPath, "journal", "j",
+		"the path to the journal written when the file was modified. By "+
+			"default, this is filepath with "+patch.JournalExtension+" added.")
+	stringFlag(fs, &f.output, "output", "o",
+		"the path to write the original .bnk or .pck to. This may be the same "+
+			"as filepath.")
+	register(&command{
+		name: "undo",
+		summary: "Restore the original .bnk or .pck from a modified file and " +
+			"the journal written when it was modified",
+		flags: fs,
+		verify: func() flagError {
+			return requireFlags("filepath", f.filePath, "output", f.output)
+		},
+		run: undo,
+	})
+}
+
+func undo() error {
+	f := &undoFlags
+	if f.journalPath == "" {
+		f.journalPath = f.filePath + patch.JournalExtension
+	}
+	in, err := os.Open(f.journalPath)
+	if err != nil {
+		return err
+	}
+	j, err := patch.ReadJournal(in)
+	in.Close()
+	if err != nil {
+		return fmt.Errorf("Could not read journal \"%s\": %s", f.journalPath, err)
+	}
+	total, err := j.Undo(f.filePath, f.output)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Restored %d wem(s) and %d HIRC object(s)\n", len(j.Wems),
+		len(j.Objects))
+	fmt.Println("Successfully wrote output file:", f.output)
+	fmt.Printf("Wrote %d bytes in total\n", total)
+	return nil
+}
+
+// writeJournal writes the journal that restores the original file at
+// originalPath from the modified file at modifiedPath, next to the modified
+// file.
+func writeJournal(originalPath, modifiedPath string) error {
+	j, err := patch.NewJournal(originalPath, modifiedPath)
+	if err != nil {
+		return fmt.Errorf("Could not create journal: %s", err)
+	}
+	path := modifiedPath + patch.JournalExtension
+	out, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("Could not create journal file \"%s\": %s", path, err)
+	}
+	defer out.Close()
+	_, err = j.WriteTo(out)
+	if err != nil {
+		return fmt.Errorf("Could not write journal: %s", err)
+	}
+	fmt.Println("Successfully wrote journal:", path)
+	return nil
+}
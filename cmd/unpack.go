@@ -0,0 +1,229 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+)
+
+import (
+	"github.com/hpxro7/wwiseutil/container"
+	"github.com/hpxro7/wwiseutil/wwise"
+)
+
+var unpackFlags struct {
+	filePath string
+	output   string
+	verbose  bool
+	extract  bool
+	workers  int
+	// The flags selecting which wems to unpack.
+	indexes   string
+	ids       string
+	match     string
+	languages string
+	codecs    string
+	naming    namingFlags
+}
+
+// The wems chosen by the selection flags of unpack.
+var unpackSelection = new(wwise.Selection)
+
+func init() {
+	fs := newFlagSet("unpack")
+	f := &unpackFlags
+	stringFlag(fs, &f.filePath, "filepath", "f",
+		"the path to the .bnk or .pck file to unpack. If this is a directory, "+
+			"every .bnk and .pck file within it is unpacked.")
+	stringFlag(fs, &f.output, "output", "o",
+		"the directory to output unpacked .wem files. When unpacking a "+
+			"directory, the wems of each file are written to a directory named "+
+			"after the file, mirroring the input directory tree.")
+	boolFlag(fs, &f.verbose, "verbose", "v", verboseUsage)
+	boolFlag(fs, &f.extract, "extract", "e",
+		"wems encoded with Opus or XMA2 are extracted into standard Ogg Opus "+
+			"(.opus) or XMA2 RIFF (.xma) files instead of being written as .wem "+
+			"files.")
+	workersFlag(fs, &f.workers)
+	addNamingFlags(fs, &f.naming)
+	fs.StringVar(&f.indexes, "indexes", "",
+		"only unpack the wems within these comma separated indexes or ranges of "+
+			"indexes, such as 1-10,15,20-.")
+	fs.StringVar(&f.ids, "ids", "",
+		"only unpack the wems with these comma separated wem IDs.")
+	fs.StringVar(&f.match, "match", "",
+		"only unpack the wems whose ID or file name, such as 001.wem, matches "+
+			"one of these comma separated patterns, such as 3036*.")
+	fs.StringVar(&f.languages, "lang", "",
+		"only unpack the wems of a .pck with one of these comma separated "+
+			"languages, such as sfx.")
+	fs.StringVar(&f.codecs, "codec", "",
+		"only unpack the wems encoded with one of these comma separated codecs, "+
+			"such as vorbis or opus.")
+	register(&command{
+		name:    "unpack",
+		summary: "Unpack a .bnk or .pck into seperate .wem files",
+		flags:   fs,
+		verify: func() flagError {
+			if err := f.naming.verify(); err != "" {
+				return err
+			}
+			err := parseSelection(unpackSelection, f.indexes, f.ids, f.match,
+				f.languages, f.codecs)
+			if err != nil {
+				return flagError(err.Error())
+			}
+			return requireFlags("filepath", f.filePath, "output", f.output)
+		},
+		run: unpack,
+	})
+}
+
+func unpack() error {
+	f := &unpackFlags
+	paths, dir, err := inputPaths(f.filePath)
+	if err != nil {
+		return err
+	}
+	naming, err := f.naming.load()
+	if err != nil {
+		return err
+	}
+	if !dir {
+		count, total, err := unpackFile(f.filePath, f.output, naming, f.extract,
+			f.verbose)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Successfully wrote %d wem(s) to %s\n", count, f.output)
+		fmt.Printf("Wrote %d bytes in total\n", total)
+		return nil
+	}
+
+	var count, total int64
+	failures := container.ForEach(paths, f.workers, func(path string) error {
+		rel := relativePath(f.filePath, path)
+		output := filepath.Join(f.output,
+			strings.TrimSuffix(rel, filepath.Ext(rel)))
+		n, written, err := unpackFile(path, output, naming, f.extract, false)
+		atomic.AddInt64(&count, int64(n))
+		atomic.AddInt64(&total, written)
+		return err
+	})
+	fmt.Printf("Wrote %d wem(s) from %d file(s) to %s\n", count,
+		len(paths)-len(failures), f.output)
+	fmt.Printf("Wrote %d bytes in total\n", total)
+	return summarizeFailures(failures, len(paths))
+}
+
+// parseSelection fills in sel from the comma separated lists of index ranges,
+// IDs, patterns, languages and codecs. Empty lists are ignored.
+func parseSelection(sel *wwise.Selection, indexes, ids, patterns, languages,
+	codecs string) error {
+	var err error
+	if indexes != "" {
+		sel.Ranges, err = wwise.ParseIndexRanges(indexes)
+		if err != nil {
+			return err
+		}
+	}
+	if ids != "" {
+		sel.Ids, err = wwise.ParseWemIds(ids)
+		if err != nil {
+			return err
+		}
+	}
+	if patterns != "" {
+		sel.Patterns = strings.Split(patterns, ",")
+	}
+	if languages != "" {
+		sel.Languages = strings.Split(languages, ",")
+	}
+	if codecs != "" {
+		for _, name := range strings.Split(codecs, ",") {
+			codec, err := wwise.ParseCodec(strings.TrimSpace(name))
+			if err != nil {
+				return err
+			}
+			sel.Codecs = append(sel.Codecs, codec)
+		}
+	}
+	return nil
+}
+
+// unpackFile writes every wem of the container at path chosen by the unpack
+// selection to the directory output, naming each with naming. The number of
+// wems and bytes written is returned.
+func unpackFile(path, output string, naming *wemNaming, extract,
+	verbose bool) (count int, total int64, err error) {
+	ctn, err := openContainer(path, verbose)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer ctn.Close()
+
+	selected, err := unpackSelection.Select(ctn)
+	if err != nil {
+		return 0, 0, err
+	}
+	if len(selected) == 0 {
+		return 0, 0, nil
+	}
+
+	// Name every wem before writing any, so that nothing is written if two wems
+	// would be given the same name.
+	listing := ctn.Listing()
+	filenames := make([]string, len(selected))
+	codecs := make([]wwise.Codec, len(selected))
+	indexOfName := make(map[string]int)
+	for j, i := range selected {
+		ext := wwise.WemExtension
+		if extract {
+			wem := ctn.Wems()[i]
+			codecs[j], err = wwise.DetectCodec(wem, int64(wem.Descriptor.Length))
+			if err != nil {
+				log.Printf("Could not detect the codec of wem %d in %s: %s", i+1,
+					path, err)
+			}
+			ext = codecs[j].Extension()
+		}
+		filenames[j] = naming.nameOf(listing, i, ext)
+		if other, ok := indexOfName[filenames[j]]; ok {
+			return 0, 0, fmt.Errorf("The naming template %s gives both wem %d and "+
+				"wem %d the name %s", naming.template, other+1, i+1, filenames[j])
+		}
+		indexOfName[filenames[j]] = i
+	}
+
+	err = createDirIfEmpty(output)
+	if err != nil {
+		return 0, 0, fmt.Errorf("Could not create output directory: %s", err)
+	}
+	for j, i := range selected {
+		wem := ctn.Wems()[i]
+		filename, codec := filenames[j], codecs[j]
+		out, err := os.Create(filepath.Join(output, filename))
+		if err != nil {
+			return count, total, fmt.Errorf("Could not create wem file \"%s\": %s",
+				filename, err)
+		}
+		var n int64
+		if codec.Extractable() {
+			n, err = wwise.Extract(out, wem, int64(wem.Descriptor.Length))
+		} else {
+			n, err = io.Copy(out, wem)
+		}
+		out.Close()
+		total += n
+		if err != nil {
+			return count, total, fmt.Errorf("Could not write wem file \"%s\": %s",
+				filename, err)
+		}
+		count++
+	}
+	return count, total, nil
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+)
+
+import (
+	"github.com/hpxro7/wwiseutil/wwise"
+)
+
+var verifyFlags struct {
+	filePath string
+	format   string
+}
+
+func init() {
+	fs := newFlagSet("verify")
+	f := &verifyFlags
+	stringFlag(fs, &f.filePath, "filepath", "f",
+		"the path to the .bnk or .pck to check.")
+	fs.StringVar(&f.format, "format", "text",
+		"the format to report problems in. Either text or json.")
+	register(&command{
+		name: "verify",
+		summary: "Check that the indexes, wems and HIRC objects of a .bnk or .pck " +
+			"are consistent with each other",
+		flags: fs,
+		verify: func() flagError {
+			if f.format != "text" && f.format != "json" {
+				return flagError(f.format + ", is not a supported format")
+			}
+			return requireFlags("filepath", f.filePath)
+		},
+		run: verify,
+	})
+}
+
+func verify() error {
+	f := &verifyFlags
+	ctn, err := openContainer(f.filePath, false)
+	if err != nil {
+		return err
+	}
+	defer ctn.Close()
+
+	v, ok := ctn.(wwise.Verifier)
+	if !ok {
+		return fmt.Errorf("%s can not be verified", f.filePath)
+	}
+	problems := v.Verify()
+	if f.format == "json" {
+		if problems == nil {
+			problems = []*wwise.Problem{}
+		}
+		err = printJSON(problems)
+		if err != nil {
+			return err
+		}
+	} else {
+		for _, p := range problems {
+			fmt.Println(p)
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("Found %d problem(s) in %s", len(problems), f.filePath)
+	}
+	if f.format == "text" {
+		fmt.Println("No problems were found in", f.filePath)
+	}
+	return nil
+}
@@ -0,0 +1,35 @@
+// Package container opens Wwise containers of any supported file type.
+package container
+
+import (
+	"fmt"
+)
+
+import (
+	"github.com/hpxro7/wwiseutil/bnk"
+	"github.com/hpxro7/wwiseutil/pck"
+	"github.com/hpxro7/wwiseutil/util"
+	"github.com/hpxro7/wwiseutil/wwise"
+)
+
+// Open opens the SoundBank or File Package at path, choosing the format based
+// on the extension of path.
+func Open(path string) (wwise.Container, error) {
+	switch t, ext := util.GetFileType(path); t {
+	case util.SoundBankFileType:
+		// Avoid returning a nil *bnk.File as a non-nil Container.
+		b, err := bnk.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		return b, nil
+	case util.FilePackageFileType:
+		p, err := pck.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		return p, nil
+	default:
+		return nil, fmt.Errorf("%s(%s) is not a supported file format", path, ext)
+	}
+}
@@ -0,0 +1,140 @@
+// Package container opens Wwise containers of any supported file type.
+package container
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// A DuplicateReport describes the wems that are stored more than once across
+// the indexed containers.
+type DuplicateReport struct {
+	// Every wem ID stored in more than one place, ordered by ID.
+	Ids []*DuplicateId `json:"ids"`
+	// Every wem content stored under more than one wem ID, ordered by hash.
+	Contents []*DuplicateContent `json:"contents"`
+}
+
+// A DuplicateId describes a wem ID stored in more than one place.
+type DuplicateId struct {
+	Id uint32 `json:"id"`
+	// True if the copies of this wem do not all have the same contents.
+	Differs   bool           `json:"differs"`
+	Locations []*WemLocation `json:"locations"`
+}
+
+// A DuplicateContent describes a wem content stored under more than one wem
+// ID.
+type DuplicateContent struct {
+	// The SHA-256 hash, in hexadecimal, of the contents.
+	Hash string `json:"hash"`
+	// The IDs that the contents are stored under, in ascending order.
+	Ids       []uint32       `json:"ids"`
+	Locations []*WemLocation `json:"locations"`
+}
+
+// Duplicates reports the wems stored more than once within the containers of
+// the directory root. Only wems stored within a container are considered;
+// SoundBanks referencing a streamed wem do not count as copies of it.
+func (idx *WemIndex) Duplicates(root string) (*DuplicateReport, error) {
+	stored, err := idx.storedUnder(root)
+	if err != nil {
+		return nil, err
+	}
+	byId := make(map[uint32][]*WemLocation)
+	byHash := make(map[string][]*WemLocation)
+	for _, l := range stored {
+		byId[l.Id] = append(byId[l.Id], l)
+		byHash[l.Hash] = append(byHash[l.Hash], l)
+	}
+
+	report := new(DuplicateReport)
+	for id, locations := range byId {
+		if len(locations) < 2 {
+			continue
+		}
+		d := &DuplicateId{Id: id, Locations: locations}
+		for _, l := range locations {
+			d.Differs = d.Differs || l.Hash != locations[0].Hash
+		}
+		report.Ids = append(report.Ids, d)
+	}
+	sort.Slice(report.Ids, func(i, j int) bool {
+		return report.Ids[i].Id < report.Ids[j].Id
+	})
+
+	for hash, locations := range byHash {
+		seen := make(map[uint32]bool)
+		var ids []uint32
+		for _, l := range locations {
+			if !seen[l.Id] {
+				seen[l.Id] = true
+				ids = append(ids, l.Id)
+			}
+		}
+		if len(ids) < 2 {
+			continue
+		}
+		sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+		report.Contents = append(report.Contents,
+			&DuplicateContent{hash, ids, locations})
+	}
+	sort.Slice(report.Contents, func(i, j int) bool {
+		return report.Contents[i].Hash < report.Contents[j].Hash
+	})
+	return report, nil
+}
+
+// Copies returns every location within the containers of the directory root
+// where the wem with the ID id is stored. If sameContent is true, the
+// locations of wems stored under other IDs, whose contents are identical to
+// any copy of the wem, are included as well.
+func (idx *WemIndex) Copies(root string, id uint32,
+	sameContent bool) ([]*WemLocation, error) {
+	stored, err := idx.storedUnder(root)
+	if err != nil {
+		return nil, err
+	}
+	hashes := make(map[string]bool)
+	for _, l := range stored {
+		if l.Id == id {
+			hashes[l.Hash] = true
+		}
+	}
+	var copies []*WemLocation
+	for _, l := range stored {
+		if l.Id == id || sameContent && hashes[l.Hash] {
+			copies = append(copies, l)
+		}
+	}
+	return copies, nil
+}
+
+// storedUnder returns the location of every wem stored within the containers
+// of the directory root, ordered by path and then index.
+func (idx *WemIndex) storedUnder(root string) ([]*WemLocation, error) {
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+	prefix := root + string(filepath.Separator)
+	var stored []*WemLocation
+	for path, f := range idx.Files {
+		if !strings.HasPrefix(path, prefix) {
+			continue
+		}
+		for _, l := range f.Wems {
+			if l.Index != 0 {
+				stored = append(stored, l)
+			}
+		}
+	}
+	sort.Slice(stored, func(i, j int) bool {
+		if stored[i].Path != stored[j].Path {
+			return stored[i].Path < stored[j].Path
+		}
+		return stored[i].Index < stored[j].Index
+	})
+	return stored, nil
+}
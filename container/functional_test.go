@@ -0,0 +1,342 @@
+// Package container opens Wwise containers of any supported file type.
+package container
+
+// Large system tests for the container package.
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+)
+
+const testDir = "../bnk/testdata"
+
+func TestWalkAndForEach(t *testing.T) {
+	paths, err := Walk(testDir)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	matches, err := filepath.Glob(filepath.Join(testDir, "*.bnk"))
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	if len(paths) != len(matches) {
+		t.Errorf("Expected %d containers but found %d", len(matches), len(paths))
+	}
+
+	var opened int32
+	failures := ForEach(paths, 3, func(path string) error {
+		ctn, err := Open(path)
+		if err != nil {
+			return err
+		}
+		atomic.AddInt32(&opened, 1)
+		return ctn.Close()
+	})
+	if len(failures) != 0 || int(opened) != len(paths) {
+		t.Errorf("Expected every container to open, but got %v", failures)
+	}
+
+	failures = ForEach(paths, 0, func(path string) error {
+		return errors.New("failed")
+	})
+	if len(failures) != len(paths) {
+		t.Errorf("Expected %d failures but got %d", len(paths), len(failures))
+	}
+}
+
+func TestWemIndex(t *testing.T) {
+	dir, err := ioutil.TempDir("", "wwiseutil")
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	defer os.RemoveAll(dir)
+	cachePath := filepath.Join(dir, "index.json")
+
+	idx, err := LoadIndex(cachePath)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	failures, err := idx.Update(testDir, 0)
+	if err != nil || len(failures) != 0 {
+		t.Errorf("Expected every container to be indexed, but got %v, %v",
+			failures, err)
+	}
+	wantPath, _ := filepath.Abs(filepath.Join(testDir, "complex.bnk"))
+	var found *WemLocation
+	for _, l := range idx.Find(303605) {
+		if l.Path == wantPath {
+			found = l
+		}
+	}
+	if found == nil || found.Index != 1 || found.Streamed {
+		t.Errorf("Expected wem 303605 to be the first wem of %s, but got %v",
+			wantPath, found)
+	}
+
+	err = idx.Save(cachePath)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	cached, err := LoadIndex(cachePath)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	if len(cached.Files) != len(idx.Files) {
+		t.Errorf("Expected %d cached files but got %d", len(idx.Files),
+			len(cached.Files))
+	}
+	for path, f := range cached.Files {
+		f.Wems = nil
+		cached.Files[path] = f
+	}
+	// Unchanged containers are not read again, so their cleared wems stay
+	// cleared.
+	cached.Update(testDir, 0)
+	if len(cached.Find(303605)) != 0 {
+		t.Error("Expected unchanged containers not to be indexed again")
+	}
+}
+
+func TestIndexClearsFailedWems(t *testing.T) {
+	path := filepath.Join(testDir, "complex.bnk")
+	ctn, err := Open(path)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	defer ctn.Close()
+	wems := ctn.Wems()
+	id := wems[0].Descriptor.WemId
+	wems[1].Reader = failingReader{}
+
+	f := new(IndexedFile)
+	if err = f.addWems(path, ctn); err == nil {
+		t.Error("Expected a wem that cannot be read to fail indexing")
+	}
+	if f.Error == "" || len(f.Wems) != 0 {
+		t.Errorf("Expected the failed file to record its error and no wems, "+
+			"but got %q and %d wems", f.Error, len(f.Wems))
+	}
+	idx := &WemIndex{Files: map[string]*IndexedFile{path: f}}
+	if l := idx.Find(id); len(l) != 0 {
+		t.Errorf("Expected no wems to be found in a failed file, but got %v", l)
+	}
+}
+
+func TestDuplicates(t *testing.T) {
+	idx := NewWemIndex()
+	failures, err := idx.Update(testDir, 0)
+	if err != nil || len(failures) != 0 {
+		t.Errorf("Expected every container to be indexed, but got %v, %v",
+			failures, err)
+	}
+	report, err := idx.Duplicates(testDir)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+
+	// The first wem was replaced in some of the SoundBanks, while the second is
+	// the same in every SoundBank holding it.
+	differs := make(map[uint32]bool)
+	for _, d := range report.Ids {
+		differs[d.Id] = d.Differs
+	}
+	if got, ok := differs[303605]; !ok || !got {
+		t.Error("Expected wem 303605 to be stored with differing contents")
+	}
+	if got, ok := differs[4164517]; !ok || got {
+		t.Error("Expected wem 4164517 to be stored with identical contents")
+	}
+
+	copies, err := idx.Copies(testDir, 303605, false)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	for _, l := range copies {
+		if l.Id != 303605 || l.Index == 0 || l.Hash == "" {
+			t.Errorf("Expected only stored copies of wem 303605, but got %v", l)
+		}
+	}
+	sameContent, err := idx.Copies(testDir, 303605, true)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	if len(copies) < 2 || len(sameContent) < len(copies) {
+		t.Errorf("Expected at least 2 copies of wem 303605, but got %d and %d",
+			len(copies), len(sameContent))
+	}
+}
+
+func TestOpenTarget(t *testing.T) {
+	dir, err := ioutil.TempDir("", "wwiseutil")
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	defer os.RemoveAll(dir)
+	contents := map[string][]byte{
+		"001.wem": []byte("RIFF first wem"),
+		"002.wem": bytes.Repeat([]byte("second wem "), 100),
+	}
+
+	var zipBuf bytes.Buffer
+	zw := zip.NewWriter(&zipBuf)
+	var tarBuf bytes.Buffer
+	tw := tar.NewWriter(&tarBuf)
+	for _, name := range []string{"001.wem", "002.wem"} {
+		data := contents[name]
+		method := zip.Store
+		if name == "002.wem" {
+			method = zip.Deflate
+		}
+		w, err := zw.CreateHeader(&zip.FileHeader{Name: "mod/" + name,
+			Method: method})
+		if err == nil {
+			_, err = w.Write(data)
+		}
+		if err == nil {
+			err = tw.WriteHeader(&tar.Header{Name: "mod/" + name, Mode: 0644,
+				Size: int64(len(data)), Typeflag: tar.TypeReg})
+		}
+		if err == nil {
+			_, err = tw.Write(data)
+		}
+		if err != nil {
+			t.Error(err)
+			t.FailNow()
+		}
+	}
+	zw.Close()
+	tw.Close()
+	for name, buf := range map[string][]byte{"mod.zip": zipBuf.Bytes(),
+		"mod.tar": tarBuf.Bytes()} {
+		err = ioutil.WriteFile(filepath.Join(dir, name), buf, 0644)
+		if err != nil {
+			t.Error(err)
+			t.FailNow()
+		}
+	}
+
+	for _, name := range []string{"mod.zip", "mod.tar"} {
+		target, err := OpenTarget(filepath.Join(dir, name))
+		if err != nil {
+			t.Errorf("%s: %s", name, err)
+			continue
+		}
+		if len(target.Files) != len(contents) {
+			t.Errorf("%s: expected %d files but got %d", name, len(contents),
+				len(target.Files))
+		}
+		for _, f := range target.Files {
+			r, err := f.Open()
+			if err != nil {
+				t.Errorf("%s: %s", name, err)
+				continue
+			}
+			got, err := ioutil.ReadAll(io.NewSectionReader(r, 0, f.Size))
+			if err != nil || !bytes.Equal(got, contents[f.Name]) {
+				t.Errorf("%s: %s was not read correctly: %v", name, f.Name, err)
+			}
+		}
+		target.Close()
+	}
+}
+
+func TestOpenCompressedTarget(t *testing.T) {
+	dir, err := ioutil.TempDir("", "wwiseutil")
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	defer os.RemoveAll(dir)
+	wem := []byte("RIFF only wem")
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	files := []struct {
+		name string
+		data []byte
+	}{
+		{"mod/readme.txt", bytes.Repeat([]byte("not a wem "), 1000)},
+		{"mod/001.wem", wem},
+	}
+	for _, f := range files {
+		err = tw.WriteHeader(&tar.Header{Name: f.name, Mode: 0644,
+			Size: int64(len(f.data)), Typeflag: tar.TypeReg})
+		if err == nil {
+			_, err = tw.Write(f.data)
+		}
+		if err != nil {
+			t.Error(err)
+			t.FailNow()
+		}
+	}
+	tw.Close()
+	gz.Close()
+	p := filepath.Join(dir, "mod.tgz")
+	if err = ioutil.WriteFile(p, buf.Bytes(), 0644); err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+
+	target, err := OpenTarget(p)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	defer target.Close()
+	if len(target.Files) != len(files) {
+		t.Fatalf("Expected %d files but got %d", len(files), len(target.Files))
+	}
+	// The files are sorted by name, so the wem is listed first.
+	f, other := target.Files[0], target.Files[1]
+	if f.Name != "001.wem" || other.Name != "readme.txt" {
+		t.Fatalf("Expected 001.wem and readme.txt but got %s and %s", f.Name,
+			other.Name)
+	}
+	r, err := f.Open()
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	got, err := ioutil.ReadAll(io.NewSectionReader(r, 0, f.Size))
+	if err != nil || !bytes.Equal(got, wem) {
+		t.Errorf("%s was not read correctly: %v", f.Name, err)
+	}
+	if other.Size != int64(len(files[0].data)) {
+		t.Errorf("Expected %s to have size %d but got %d", other.Name,
+			len(files[0].data), other.Size)
+	}
+	if _, err := other.Open(); err == nil {
+		t.Errorf("Expected the skipped contents of %s not to be opened",
+			other.Name)
+	}
+}
+
+// A failingReader fails every read.
+type failingReader struct{}
+
+func (failingReader) Read(p []byte) (int, error) {
+	return 0, errors.New("Read failed")
+}
+
+func (failingReader) ReadAt(p []byte, off int64) (int, error) {
+	return 0, errors.New("Read failed")
+}
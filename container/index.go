@@ -0,0 +1,242 @@
+// Package container opens Wwise containers of any supported file type.
+package container
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+import (
+	"github.com/hpxro7/wwiseutil/bnk"
+	"github.com/hpxro7/wwiseutil/util"
+	"github.com/hpxro7/wwiseutil/wwise"
+)
+
+// The name of the file that caches the wem index, within the .wwiseutil
+// directory of the user's home directory.
+const indexCacheName = "index.json"
+
+// The version of the cached index format. Cached indexes of any other version
+// are discarded, and every container is indexed again.
+const indexVersion = 2
+
+// A WemIndex maps wem IDs to the containers holding them, for every container
+// within a set of directories.
+type WemIndex struct {
+	Version int `json:"version"`
+	// The indexed containers, by their absolute path.
+	Files map[string]*IndexedFile `json:"files"`
+	// The locations of every wem, by its ID. This is rebuilt from Files.
+	locations map[uint32][]*WemLocation
+}
+
+// An IndexedFile holds the wems of a single container, as of the time it was
+// indexed.
+type IndexedFile struct {
+	// The size and modification time of the container when it was indexed. The
+	// container is indexed again if either changes.
+	Size    int64          `json:"size"`
+	ModTime time.Time      `json:"mod_time"`
+	Wems    []*WemLocation `json:"wems"`
+	// The reason the container could not be indexed, if any.
+	Error string `json:"error,omitempty"`
+}
+
+// A WemLocation describes where a single wem is stored.
+type WemLocation struct {
+	Id uint32 `json:"id"`
+	// The absolute path to the container holding this wem.
+	Path string `json:"path"`
+	// The index, where 1 is the first wem, of this wem in the container. This is
+	// 0 if the wem is streamed from another file, and is only referenced by the
+	// container.
+	Index int `json:"index"`
+	// The number of bytes from the start of the container that this wem begins.
+	Offset uint32 `json:"offset"`
+	Length uint32 `json:"length"`
+	// True if the wem is streamed, either as a file within a File Package or
+	// from outside of the SoundBank referencing it, and false if it is embedded
+	// within a SoundBank.
+	Streamed bool `json:"streamed"`
+	// The SHA-256 hash, in hexadecimal, of the contents of this wem, or an empty
+	// string if the wem is not stored in the container.
+	Hash string `json:"hash,omitempty"`
+}
+
+// DefaultIndexPath returns the path where the wem index is cached by default.
+func DefaultIndexPath() string {
+	return filepath.Join(util.UserHome(), ".wwiseutil", indexCacheName)
+}
+
+// NewWemIndex creates an empty index.
+func NewWemIndex() *WemIndex {
+	return &WemIndex{Version: indexVersion,
+		Files: make(map[string]*IndexedFile)}
+}
+
+// LoadIndex reads the wem index cached at path. An empty index is returned if
+// there is no file at path, or if it was cached by another version.
+func LoadIndex(path string) (*WemIndex, error) {
+	idx := NewWemIndex()
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return idx, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	err = json.NewDecoder(f).Decode(idx)
+	if err != nil {
+		return nil, err
+	}
+	if idx.Version != indexVersion || idx.Files == nil {
+		return NewWemIndex(), nil
+	}
+	return idx, nil
+}
+
+// Save writes this index to path, creating its directory if needed.
+func (idx *WemIndex) Save(path string) error {
+	err := os.MkdirAll(filepath.Dir(path), os.ModePerm)
+	if err != nil {
+		return err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	err = json.NewEncoder(f).Encode(idx)
+	if err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Update brings the index up to date with every container within the directory
+// root, using at most workers goroutines. Only containers that are new, or
+// whose size or modification time changed, are read. Containers that no longer
+// exist within root are removed from the index. The containers that could not
+// be read are returned; they are kept in the index with their error, so that
+// they are not read again until they change.
+func (idx *WemIndex) Update(root string, workers int) ([]*FileError, error) {
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+	paths, err := Walk(root)
+	if err != nil {
+		return nil, err
+	}
+
+	found := make(map[string]bool)
+	var stale []string
+	for _, path := range paths {
+		found[path] = true
+		info, err := os.Stat(path)
+		if err != nil {
+			stale = append(stale, path)
+			continue
+		}
+		cached, ok := idx.Files[path]
+		if !ok || cached.Size != info.Size() ||
+			!cached.ModTime.Equal(info.ModTime()) {
+			stale = append(stale, path)
+		}
+	}
+	prefix := root + string(filepath.Separator)
+	for path := range idx.Files {
+		if strings.HasPrefix(path, prefix) && !found[path] {
+			delete(idx.Files, path)
+		}
+	}
+
+	var mu sync.Mutex
+	failures := ForEach(stale, workers, func(path string) error {
+		f, err := indexFile(path)
+		mu.Lock()
+		idx.Files[path] = f
+		mu.Unlock()
+		return err
+	})
+	idx.locations = nil
+	return failures, nil
+}
+
+// indexFile reads the locations of every wem of the container at path.
+func indexFile(path string) (*IndexedFile, error) {
+	f := new(IndexedFile)
+	info, err := os.Stat(path)
+	if err != nil {
+		f.Error = err.Error()
+		return f, err
+	}
+	f.Size, f.ModTime = info.Size(), info.ModTime()
+
+	ctn, err := Open(path)
+	if err != nil {
+		f.Error = err.Error()
+		return f, err
+	}
+	defer ctn.Close()
+
+	return f, f.addWems(path, ctn)
+}
+
+// addWems adds the location of every wem of ctn, the container at path, to f.
+// If a wem cannot be read, no wem is added, and the error is recorded in f.
+func (f *IndexedFile) addWems(path string, ctn wwise.Container) error {
+	soundBank, isSoundBank := ctn.(*bnk.File)
+	for i, wem := range ctn.Wems() {
+		desc := wem.Descriptor
+		hash, err := wwise.HashWem(wem)
+		if err != nil {
+			err = fmt.Errorf("Could not read wem %d: %s", i+1, err)
+			// Wems listed before the failure must not be found in a file the
+			// index records as failed.
+			f.Wems, f.Error = nil, err.Error()
+			return err
+		}
+		f.Wems = append(f.Wems, &WemLocation{desc.WemId, path, i + 1,
+			ctn.DataStart() + desc.Offset, desc.Length, !isSoundBank, hash})
+	}
+	if isSoundBank {
+		for _, id := range soundBank.StreamedWemIds() {
+			f.Wems = append(f.Wems, &WemLocation{Id: id, Path: path,
+				Streamed: true})
+		}
+	}
+	return nil
+}
+
+// Find returns every location of the wem with the ID id, ordered by path and
+// then index.
+func (idx *WemIndex) Find(id uint32) []*WemLocation {
+	if idx.locations == nil {
+		idx.locations = make(map[uint32][]*WemLocation)
+		for _, f := range idx.Files {
+			if f.Error != "" {
+				continue
+			}
+			for _, wem := range f.Wems {
+				idx.locations[wem.Id] = append(idx.locations[wem.Id], wem)
+			}
+		}
+	}
+	locations := idx.locations[id]
+	sort.Slice(locations, func(i, j int) bool {
+		if locations[i].Path != locations[j].Path {
+			return locations[i].Path < locations[j].Path
+		}
+		return locations[i].Index < locations[j].Index
+	})
+	return locations
+}
@@ -0,0 +1,229 @@
+// Package container opens Wwise containers of any supported file type.
+package container
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+import (
+	"github.com/hpxro7/wwiseutil/wwise"
+)
+
+// A Target is a directory, or a .zip, .tar or .tar.gz archive, holding
+// replacement wems. The files of archives are read in place, without being
+// extracted to disk.
+type Target struct {
+	// The regular files of this target, sorted by name. Files within
+	// subdirectories of an archive are named by their base name alone.
+	Files []*TargetFile
+	// The files to close once this target is no longer needed.
+	closers []io.Closer
+}
+
+// A TargetFile is a single file within a Target.
+type TargetFile struct {
+	Name string
+	Size int64
+	// Opens a reader over the contents of this file.
+	open func() (io.ReaderAt, error)
+}
+
+// OpenTarget lists the files of the directory or archive at path. Archives are
+// recognised by a .zip, .tar, .tar.gz or .tgz extension.
+func OpenTarget(path string) (*Target, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	t := new(Target)
+	lower := strings.ToLower(path)
+	switch {
+	case info.IsDir():
+		err = t.listDir(path)
+	case strings.HasSuffix(lower, ".zip"):
+		err = t.listZip(path)
+	case strings.HasSuffix(lower, ".tar"):
+		err = t.listTar(path, false)
+	case strings.HasSuffix(lower, ".tar.gz"), strings.HasSuffix(lower, ".tgz"):
+		err = t.listTar(path, true)
+	default:
+		return nil, fmt.Errorf("%s is not a directory, or a .zip, .tar or .tar.gz "+
+			"archive", path)
+	}
+	if err != nil {
+		t.Close()
+		return nil, err
+	}
+	sort.SliceStable(t.Files, func(i, j int) bool {
+		return t.Files[i].Name < t.Files[j].Name
+	})
+	return t, nil
+}
+
+// Close closes every file opened by this target.
+func (t *Target) Close() error {
+	var err error
+	for _, c := range t.closers {
+		if cerr := c.Close(); err == nil {
+			err = cerr
+		}
+	}
+	t.closers = nil
+	return err
+}
+
+// Open returns a reader over the contents of this file. The reader remains
+// valid until its Target is closed.
+func (f *TargetFile) Open() (io.ReaderAt, error) {
+	return f.open()
+}
+
+func (t *Target) add(name string, size int64,
+	open func() (io.ReaderAt, error)) {
+	t.Files = append(t.Files, &TargetFile{name, size, open})
+}
+
+// listDir adds every regular file directly within the directory dir.
+func (t *Target) listDir(dir string) error {
+	fis, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, fi := range fis {
+		if !fi.Mode().IsRegular() {
+			continue
+		}
+		p := filepath.Join(dir, fi.Name())
+		t.add(fi.Name(), fi.Size(), func() (io.ReaderAt, error) {
+			f, err := os.Open(p)
+			if err != nil {
+				return nil, err
+			}
+			t.closers = append(t.closers, f)
+			return f, nil
+		})
+	}
+	return nil
+}
+
+// listZip adds every regular file of the zip archive at p. Stored files are
+// read directly from the archive, and compressed files are decompressed into
+// memory when they are opened.
+func (t *Target) listZip(p string) error {
+	f, err := os.Open(p)
+	if err != nil {
+		return err
+	}
+	t.closers = append(t.closers, f)
+	stat, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	zr, err := zip.NewReader(f, stat.Size())
+	if err != nil {
+		return err
+	}
+	for _, zf := range zr.File {
+		if !zf.Mode().IsRegular() {
+			continue
+		}
+		zf := zf
+		size := int64(zf.UncompressedSize64)
+		t.add(path.Base(zf.Name), size, func() (io.ReaderAt, error) {
+			if zf.Method == zip.Store {
+				offset, err := zf.DataOffset()
+				if err != nil {
+					return nil, err
+				}
+				return io.NewSectionReader(f, offset, size), nil
+			}
+			rc, err := zf.Open()
+			if err != nil {
+				return nil, err
+			}
+			defer rc.Close()
+			return readAll(rc)
+		})
+	}
+	return nil
+}
+
+// listTar adds every regular file of the tar archive at p, which is gzipped if
+// compressed is true. Files of an uncompressed archive are read directly from
+// the archive. A compressed archive can only be read in order, so its wems are
+// decompressed into memory as they are listed. The contents of its other files
+// are skipped, and cannot be opened.
+func (t *Target) listTar(p string, compressed bool) error {
+	f, err := os.Open(p)
+	if err != nil {
+		return err
+	}
+	t.closers = append(t.closers, f)
+	var r io.Reader = f
+	if compressed {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if !hdr.FileInfo().Mode().IsRegular() {
+			continue
+		}
+		name, size := path.Base(hdr.Name), hdr.Size
+		if compressed && path.Ext(name) != wwise.WemExtension {
+			t.add(name, size, func() (io.ReaderAt, error) {
+				return nil, fmt.Errorf("%s is not a wem, so its contents were not "+
+					"kept", name)
+			})
+			continue
+		}
+		if compressed {
+			data, err := readAll(tr)
+			if err != nil {
+				return err
+			}
+			t.add(name, size, func() (io.ReaderAt, error) { return data, nil })
+			continue
+		}
+		// The tar reader seeks past the contents of each file, so the archive is
+		// positioned at the start of the contents of the current file.
+		offset, err := f.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return err
+		}
+		t.add(name, size, func() (io.ReaderAt, error) {
+			return io.NewSectionReader(f, offset, size), nil
+		})
+	}
+}
+
+func readAll(r io.Reader) (*bytes.Reader, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.NewReader(data), nil
+}
@@ -0,0 +1,83 @@
+// Package container opens Wwise containers of any supported file type.
+package container
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+)
+
+import (
+	"github.com/hpxro7/wwiseutil/util"
+)
+
+// A FileError describes a failure to process a single file.
+type FileError struct {
+	Path string
+	Err  error
+}
+
+func (e *FileError) Error() string {
+	return e.Path + ": " + e.Err.Error()
+}
+
+// Walk returns the paths of every SoundBank and File Package within the
+// directory root and its subdirectories, in lexical order. Files are recognised
+// by their extension, as with util.GetFileType.
+func Walk(root string) ([]string, error) {
+	var paths []string
+	err := filepath.Walk(root, func(path string, info os.FileInfo,
+		err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		if t, _ := util.GetFileType(path); t != util.UnknownFileType {
+			paths = append(paths, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return paths, nil
+}
+
+// ForEach calls fn with every path in paths, running at most workers calls at
+// once. If workers is not positive, one worker is used per CPU. Every failure
+// is collected rather than stopping the remaining calls, and is returned in
+// the order of paths.
+func ForEach(paths []string, workers int,
+	fn func(path string) error) []*FileError {
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	errs := make([]error, len(paths))
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				errs[i] = fn(paths[i])
+			}
+		}()
+	}
+	for i := range paths {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	var failures []*FileError
+	for i, err := range errs {
+		if err != nil {
+			failures = append(failures, &FileError{paths[i], err})
+		}
+	}
+	return failures
+}
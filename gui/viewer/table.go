@@ -242,14 +242,7 @@ func (m *WemModel) wemLoops(index int) string {
 	str := "None"
 	switch ctn := m.ctn.(type) {
 	case *bnk.File:
-		loop := ctn.LoopOf(index)
-		if loop.Loops {
-			if loop.Value == bnk.InfiniteLoops {
-				str = "Infinity"
-			} else {
-				str = fmt.Sprintf("%d times", loop.Value)
-			}
-		}
+		str = ctn.LoopOf(index).String()
 	}
 
 	return str
@@ -54,6 +54,7 @@ type WwiseViewerWindow struct {
 	actionSave    *widgets.QAction
 	actionReplace *widgets.QAction
 	actionExport  *widgets.QAction
+	actionStrict  *widgets.QAction
 
 	loopToolBar      *widgets.QToolBar
 	checkboxLoop     *widgets.QCheckBox
@@ -76,6 +77,7 @@ func New() *WwiseViewerWindow {
 	wv.setupSave(tb)
 	wv.setupReplace(tb)
 	wv.setupExport(tb)
+	wv.setupStrict(tb)
 
 	tb.AddSeparator()
 	wv.AddToolBarBreak(core.Qt__TopToolBarArea)
@@ -204,6 +206,15 @@ func (wv *WwiseViewerWindow) addReplacement(index int, path string) {
 		return
 	}
 	r := &wwise.ReplacementWem{wem, index, stat.Size()}
+	issues := wwise.ValidateReplacement(wv.table.GetContainer().Wems()[index], r)
+	if len(issues) > 0 {
+		rejected := wv.actionStrict.IsChecked() && wwise.HasErrors(issues)
+		wv.showValidationIssues(stat.Name(), issues, rejected)
+		if rejected {
+			wem.Close()
+			return
+		}
+	}
 	wv.table.AddWemReplacement(stat.Name(), r)
 }
 
@@ -218,13 +229,34 @@ func (wv *WwiseViewerWindow) setupExport(toolbar *widgets.QToolBar) {
 			widgets.QFileDialog__DontResolveSymlinks
 		dir := widgets.QFileDialog_GetExistingDirectory(
 			wv, "Choose directory to unpack into", home, opts)
-		if dir != "" {
-			wv.exportCtn(dir)
+		if dir == "" {
+			return
+		}
+		ok := false
+		text := widgets.QInputDialog_GetText(wv, "Name exported wems",
+			"Naming template, using {index}, {id}, {name}, {lang} and {ext}:",
+			widgets.QLineEdit__Normal, wwise.DefaultNameTemplate, &ok, 0, 0)
+		if !ok {
+			return
+		}
+		template, err := wwise.ParseNameTemplate(text)
+		if err != nil {
+			widgets.QMessageBox_Critical4(wv, errorTitle, err.Error(), 0, 0)
+			return
 		}
+		wv.exportCtn(dir, template)
 	})
 	toolbar.QWidget.AddAction(wv.actionExport)
 }
 
+func (wv *WwiseViewerWindow) setupStrict(toolbar *widgets.QToolBar) {
+	wv.actionStrict = widgets.NewQAction2("S&trict Validation", wv)
+	wv.actionStrict.SetCheckable(true)
+	wv.actionStrict.SetToolTip("Reject replacement wems that fail validation " +
+		"against the wem they replace")
+	toolbar.QWidget.AddAction(wv.actionStrict)
+}
+
 func (wv *WwiseViewerWindow) setupLoopOptionsToolbar() {
 	ltb := widgets.NewQToolBar("Loop Toolbar", nil)
 	ltb.SetToolButtonStyle(core.Qt__ToolButtonTextOnly)
@@ -314,11 +346,18 @@ func (wv *WwiseViewerWindow) setLoopValues(b *bnk.File, wemIndex int) {
 	}
 }
 
-func (wv *WwiseViewerWindow) exportCtn(dir string) {
+func (wv *WwiseViewerWindow) exportCtn(dir string,
+	template *wwise.NameTemplate) {
 	total := int64(0)
 	ctn := wv.table.GetContainer()
+	listing := ctn.Listing()
 	for i, wem := range ctn.Wems() {
-		filename := util.CanonicalWemName(i, len(ctn.Wems()))
+		filename := template.Format(&wwise.WemName{
+			Index:    i,
+			Count:    len(ctn.Wems()),
+			Id:       wem.Descriptor.WemId,
+			Language: listing.Wems[i].Language,
+		})
 		f, err := os.Create(filepath.Join(dir, filename))
 		if err != nil {
 			wv.showExportError(filename, dir, err)
@@ -383,6 +422,22 @@ func (wv *WwiseViewerWindow) showOpenError(path string, err error) {
 	widgets.QMessageBox_Critical4(wv, errorTitle, msg, 0, 0)
 }
 
+func (wv *WwiseViewerWindow) showValidationIssues(name string,
+	issues []*wwise.ValidationIssue, rejected bool) {
+	var lines []string
+	for _, issue := range issues {
+		lines = append(lines, issue.String())
+	}
+	msg := fmt.Sprintf("%s may not be a valid replacement:\n%s", name,
+		strings.Join(lines, "\n"))
+	if rejected {
+		msg += "\n\nStrict validation is enabled; the replacement was rejected."
+		widgets.QMessageBox_Critical4(wv, errorTitle, msg, 0, 0)
+		return
+	}
+	widgets.QMessageBox_Warning(wv, "Validation issues found", msg, 0, 0)
+}
+
 func (wv *WwiseViewerWindow) showLoopUpdateError(value string) {
 	msg := fmt.Sprintf("\"%s\" is not a valid looping value.\n "+
 		"The loop value must be an integer >= 2.", value)
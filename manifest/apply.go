@@ -0,0 +1,466 @@
+// Package manifest implements modding manifests, which describe every change
+// to make to a set of SoundBanks and File Packages, so that they can be applied
+// in a single pass.
+package manifest
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+)
+
+import (
+	"github.com/hpxro7/wwiseutil/bnk"
+	"github.com/hpxro7/wwiseutil/container"
+	"github.com/hpxro7/wwiseutil/patch"
+	"github.com/hpxro7/wwiseutil/wwise"
+)
+
+// Options control how a Manifest is applied.
+type Options struct {
+	// If true, nothing is written if any replacement wem fails validation
+	// against the wem it replaces.
+	Strict bool
+	// If true, every change is made in memory and its effect on the layout of
+	// each container is reported, but nothing is written.
+	DryRun bool
+	// If true, a journal that restores the original source is written next to
+	// each output, named by adding patch.JournalExtension to its path.
+	Journal bool
+}
+
+// A Result describes the changes made to a single container.
+type Result struct {
+	Source string `json:"source"`
+	Output string `json:"output"`
+	// The number of wems that were replaced.
+	Replaced int `json:"replaced"`
+	// The number of loop values that were changed.
+	Loops int `json:"loops"`
+	// The number of sound object parameters that were changed.
+	Properties int `json:"properties"`
+	// Problems found while validating replacement wems, which did not prevent
+	// the container from being written.
+	Issues []string `json:"issues,omitempty"`
+	// The number of bytes written to Output.
+	Written int64 `json:"written"`
+	// How the layout of the container changed, if this was a dry run.
+	Layout *wwise.LayoutChange `json:"layout,omitempty"`
+	// The path to the journal written for Output, if one was requested.
+	Journal string `json:"journal,omitempty"`
+}
+
+// A target is a container that has been opened to apply its changes to.
+type target struct {
+	changes      *FileChanges
+	ctn          wwise.Container
+	replacements []*wwise.ReplacementWem
+	loops        []*loopEdit
+	properties   []*propertyEdit
+	result       *Result
+	// The path to the temporary file that the changed container is written to,
+	// before it is moved to its output path.
+	temp string
+	// The path that any existing output was moved to before the temporary file
+	// replaced it, so that it can be restored.
+	backup string
+}
+
+// A loopEdit is a single change to the loop value of a wem.
+type loopEdit struct {
+	// The index, where zero is the first wem, of the wem to change.
+	index int
+	loop  wwise.LoopValue
+}
+
+// A propertyEdit is a single change to a parameter of the sound object playing
+// a wem.
+type propertyEdit struct {
+	// The index, where zero is the first wem, of the wem to change.
+	index     int
+	paramType byte
+	value     [4]byte
+	remove    bool
+}
+
+// Apply applies every change listed by m, and writes each changed container to
+// its output path. Every change is validated before anything is written, and
+// if any change is invalid or any container cannot be written, no output file
+// is changed and an error listing every problem is returned. If an output
+// cannot be moved into place, the outputs that already were are restored.
+// Otherwise, the result of each container is returned in the order of m.Files.
+// If opts.DryRun is true, nothing is written and each result instead describes
+// how the layout of its container would change. If opts.Journal is true, the
+// journal of every container is written before any output is changed.
+func Apply(m *Manifest, opts *Options) ([]*Result, error) {
+	if opts == nil {
+		opts = new(Options)
+	}
+	err := m.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	var closers []io.Closer
+	closeAll := func() {
+		for _, c := range closers {
+			c.Close()
+		}
+		closers = nil
+	}
+	defer closeAll()
+
+	targets, err := prepare(m, opts, &closers)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range targets {
+		before := t.ctn.Listing()
+		t.apply()
+		if opts.DryRun {
+			t.result.Layout = wwise.CompareLayouts(before, t.ctn.Listing())
+		}
+	}
+	if opts.DryRun {
+		var results []*Result
+		for _, t := range targets {
+			results = append(results, t.result)
+		}
+		return results, nil
+	}
+
+	// Write every container to a temporary file next to its output, so that no
+	// output is changed unless all of them could be written.
+	removeTemps := func() {
+		for _, t := range targets {
+			if t.temp != "" {
+				os.Remove(t.temp)
+			}
+		}
+	}
+	for _, t := range targets {
+		err = t.writeTemp()
+		if err != nil {
+			removeTemps()
+			return nil, fmt.Errorf("Could not write %s: %s", t.changes.Output, err)
+		}
+	}
+	if opts.Journal {
+		for _, t := range targets {
+			err = t.writeJournal()
+			if err != nil {
+				removeTemps()
+				return nil, fmt.Errorf("Could not write the journal of %s: %s",
+					t.changes.Output, err)
+			}
+		}
+	}
+
+	// Sources and replacements must be closed before they can be overwritten.
+	closeAll()
+	var committed []*target
+	for _, t := range targets {
+		err = t.commit()
+		if err != nil {
+			for i := len(committed) - 1; i >= 0; i-- {
+				committed[i].restore()
+			}
+			removeTemps()
+			return nil, fmt.Errorf("Could not move %s to %s: %s", t.temp,
+				t.changes.Output, err)
+		}
+		committed = append(committed, t)
+	}
+	var results []*Result
+	for _, t := range targets {
+		if t.backup != "" {
+			os.Remove(t.backup)
+		}
+		t.temp = ""
+		results = append(results, t.result)
+	}
+	return results, nil
+}
+
+// prepare opens every container and replacement wem listed by m, and checks
+// that every change can be made. Every opened file is added to closers.
+func prepare(m *Manifest, opts *Options,
+	closers *[]io.Closer) ([]*target, error) {
+	var targets []*target
+	var problems []string
+	for i, fc := range m.Files {
+		name := fmt.Sprintf("files[%d] (%s)", i, fc.Source)
+		ctn, err := container.Open(fc.Source)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %s", name, err))
+			continue
+		}
+		*closers = append(*closers, ctn)
+
+		t := &target{changes: fc, ctn: ctn,
+			result: &Result{Source: fc.Source, Output: fc.Output}}
+		for _, p := range t.prepare(opts, closers) {
+			problems = append(problems, fmt.Sprintf("%s: %s", name, p))
+		}
+		targets = append(targets, t)
+	}
+	err := problemsError(problems)
+	if err != nil {
+		return nil, err
+	}
+	return targets, nil
+}
+
+// prepare opens the replacement wems of this target and parses its loop and
+// property changes, returning every problem found.
+func (t *target) prepare(opts *Options, closers *[]io.Closer) []string {
+	var problems []string
+	fail := func(ref WemRef, format string, a ...interface{}) {
+		problems = append(problems, ref.String()+": "+fmt.Sprintf(format, a...))
+	}
+
+	// The reference of the replacement of each wem, by its index. A wem may be
+	// referred to by both its index and its ID, so only resolved indexes can be
+	// compared.
+	used := make(map[int]WemRef)
+	for _, r := range t.changes.Replacements {
+		index, err := indexOf(t.ctn, r.WemRef)
+		if err != nil {
+			fail(r.WemRef, "%s", err)
+			continue
+		}
+		if prev, ok := used[index]; ok {
+			fail(r.WemRef, "%s already replaces the same wem", prev)
+			continue
+		}
+		used[index] = r.WemRef
+		f, err := os.Open(r.Path)
+		if err != nil {
+			fail(r.WemRef, "%s", err)
+			continue
+		}
+		*closers = append(*closers, f)
+		stat, err := f.Stat()
+		if err != nil {
+			fail(r.WemRef, "%s", err)
+			continue
+		}
+
+		rep := &wwise.ReplacementWem{f, index, stat.Size()}
+		issues := wwise.ValidateReplacement(t.ctn.Wems()[index], rep)
+		for _, issue := range issues {
+			msg := fmt.Sprintf("%s: %s", filepath.Base(r.Path), issue)
+			if opts.Strict && issue.Severity == wwise.SeverityError {
+				fail(r.WemRef, "%s", msg)
+			} else {
+				t.result.Issues = append(t.result.Issues, msg)
+			}
+		}
+		t.replacements = append(t.replacements, rep)
+	}
+
+	soundBank, isSoundBank := t.ctn.(*bnk.File)
+	// Returns the index of the wem referred to by ref, if its sound object can
+	// be edited.
+	editable := func(ref WemRef) (int, bool) {
+		if !isSoundBank {
+			fail(ref, "sound objects can only be edited in SoundBanks")
+			return 0, false
+		}
+		index, err := indexOf(t.ctn, ref)
+		if err != nil {
+			fail(ref, "%s", err)
+			return 0, false
+		}
+		if !soundBank.CanLoop(index) {
+			fail(ref, "the wem is not played by a sound effect, so it cannot be "+
+				"edited")
+			return 0, false
+		}
+		return index, true
+	}
+
+	for _, l := range t.changes.Loops {
+		index, ok := editable(l.WemRef)
+		if !ok {
+			continue
+		}
+		loop, err := wwise.ParseLoopValue(l.Value)
+		if err != nil {
+			fail(l.WemRef, "%s", err)
+			continue
+		}
+		t.loops = append(t.loops, &loopEdit{index, loop})
+	}
+
+	for _, p := range t.changes.Properties {
+		index, ok := editable(p.WemRef)
+		if !ok {
+			continue
+		}
+		paramType, integer, err := bnk.ParseParameterType(p.Type)
+		if err != nil {
+			fail(p.WemRef, "%s", err)
+			continue
+		}
+		edit := &propertyEdit{index: index, paramType: paramType,
+			remove: p.Remove}
+		switch {
+		case p.Remove:
+		case integer || p.Integer:
+			if p.Value < 0 || p.Value > math.MaxUint32 ||
+				p.Value != math.Trunc(p.Value) {
+				fail(p.WemRef, "%g is not a valid value for the integer parameter %s",
+					p.Value, p.Type)
+				continue
+			}
+			binary.LittleEndian.PutUint32(edit.value[:], uint32(p.Value))
+		default:
+			binary.LittleEndian.PutUint32(edit.value[:],
+				math.Float32bits(float32(p.Value)))
+		}
+		t.properties = append(t.properties, edit)
+	}
+	return problems
+}
+
+// apply makes the changes of this target to its container in memory.
+func (t *target) apply() {
+	if len(t.replacements) > 0 {
+		t.ctn.ReplaceWems(t.replacements...)
+		t.result.Replaced = len(t.replacements)
+	}
+	soundBank, ok := t.ctn.(*bnk.File)
+	if !ok {
+		return
+	}
+	for _, l := range t.loops {
+		soundBank.ReplaceLoopOf(l.index, l.loop)
+	}
+	t.result.Loops = len(t.loops)
+	for _, p := range t.properties {
+		if p.remove {
+			soundBank.RemoveParameterOf(p.index, p.paramType)
+		} else {
+			soundBank.ReplaceParameterOf(p.index, p.paramType, p.value)
+		}
+	}
+	t.result.Properties = len(t.properties)
+}
+
+// writeTemp writes the changed container of this target to a new temporary
+// file in the directory of its output.
+func (t *target) writeTemp() error {
+	dir := filepath.Dir(t.changes.Output)
+	err := os.MkdirAll(dir, os.ModePerm)
+	if err != nil {
+		return err
+	}
+	// Keep the extension of the output, so that the file can be opened as a
+	// container.
+	f, err := ioutil.TempFile(dir, ".*."+filepath.Base(t.changes.Output))
+	if err != nil {
+		return err
+	}
+	t.temp = f.Name()
+	// Temporary files are only readable by their owner; keep the mode of any
+	// existing output instead.
+	mode := os.FileMode(0644)
+	if stat, err := os.Stat(t.changes.Output); err == nil {
+		mode = stat.Mode()
+	}
+	err = f.Chmod(mode)
+	if err != nil {
+		f.Close()
+		return err
+	}
+	t.result.Written, err = t.ctn.WriteTo(f)
+	if err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// commit moves the temporary file of this target to its output. Any existing
+// output is first moved to a backup next to it, from which restore can put it
+// back.
+func (t *target) commit() error {
+	if _, err := os.Lstat(t.changes.Output); err == nil {
+		f, err := ioutil.TempFile(filepath.Dir(t.changes.Output),
+			".*."+filepath.Base(t.changes.Output)+".bak")
+		if err != nil {
+			return err
+		}
+		f.Close()
+		err = os.Rename(t.changes.Output, f.Name())
+		if err != nil {
+			os.Remove(f.Name())
+			return err
+		}
+		t.backup = f.Name()
+	}
+	err := os.Rename(t.temp, t.changes.Output)
+	if err != nil {
+		t.restore()
+		return err
+	}
+	return nil
+}
+
+// restore undoes commit, putting back the output that the temporary file of
+// this target replaced, or removing the output if there was none.
+func (t *target) restore() {
+	if t.backup == "" {
+		os.Remove(t.changes.Output)
+		return
+	}
+	os.Rename(t.backup, t.changes.Output)
+	t.backup = ""
+}
+
+// writeJournal writes the journal that restores the source of this target
+// from its temporary file, next to its output.
+func (t *target) writeJournal() error {
+	j, err := patch.NewJournal(t.changes.Source, t.temp)
+	if err != nil {
+		return err
+	}
+	path := t.changes.Output + patch.JournalExtension
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	_, err = j.WriteTo(f)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	t.result.Journal = path
+	return nil
+}
+
+// indexOf returns the index, where zero is the first wem, of the wem in c
+// referred to by ref.
+func indexOf(c wwise.Container, ref WemRef) (int, error) {
+	wems := c.Wems()
+	if ref.Index != 0 {
+		if ref.Index < 1 || ref.Index > len(wems) {
+			return 0, fmt.Errorf("%d is not a valid index; the valid index range "+
+				"is %d to %d", ref.Index, 1, len(wems))
+		}
+		return ref.Index - 1, nil
+	}
+	for i, wem := range wems {
+		if wem.Descriptor.WemId == ref.Id {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("There is no wem with the ID %d", ref.Id)
+}
@@ -0,0 +1,269 @@
+// Package manifest implements modding manifests, which describe every change
+// to make to a set of SoundBanks and File Packages, so that they can be applied
+// in a single pass.
+package manifest
+
+// Large system tests for the manifest package.
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+import (
+	"github.com/hpxro7/wwiseutil/bnk"
+	"github.com/hpxro7/wwiseutil/util"
+)
+
+const (
+	testDir = "../bnk/testdata"
+
+	loopNoneSoundBank = "loop_none.bnk"
+	loop2SoundBank    = "loop_2.bnk"
+	simpleSoundBank   = "simple.bnk"
+	complexSoundBank  = "complex.bnk"
+)
+
+func tempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "manifest")
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	return dir
+}
+
+func TestApplyLoopChange(t *testing.T) {
+	util.SkipIfShort(t)
+
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	output := filepath.Join(dir, "out.bnk")
+	m := &Manifest{[]*FileChanges{{
+		Source: filepath.Join(testDir, loopNoneSoundBank),
+		Output: output,
+		Loops:  []*LoopChange{{WemRef{Index: 1}, "2"}},
+	}}}
+
+	results, err := Apply(m, nil)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	if len(results) != 1 || results[0].Loops != 1 {
+		t.Errorf("Expected a single loop change but got %+v", results)
+	}
+
+	expect, err := ioutil.ReadFile(filepath.Join(testDir, loop2SoundBank))
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	actual, err := ioutil.ReadFile(output)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	if !bytes.Equal(expect, actual) {
+		t.Error("The output does not match", loop2SoundBank)
+	}
+}
+
+func TestApplyPropertyChange(t *testing.T) {
+	util.SkipIfShort(t)
+
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	output := filepath.Join(dir, "out.bnk")
+	m := &Manifest{[]*FileChanges{{
+		Source: filepath.Join(testDir, simpleSoundBank),
+		Output: output,
+		Properties: []*PropertyChange{
+			{WemRef: WemRef{Index: 1}, Type: "volume", Value: -3.5}},
+	}}}
+
+	_, err := Apply(m, nil)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	b, err := bnk.Open(output)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	defer b.Close()
+	value, ok := b.ParameterOf(0, 0x00)
+	if !ok {
+		t.Error("Expected the sound object to have a volume parameter")
+	}
+	volume := math.Float32frombits(binary.LittleEndian.Uint32(value[:]))
+	if volume != -3.5 {
+		t.Errorf("Expected the volume to be -3.5 but got %g", volume)
+	}
+}
+
+func TestApplyIsAtomic(t *testing.T) {
+	util.SkipIfShort(t)
+
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	valid := filepath.Join(dir, "valid.bnk")
+	m := &Manifest{[]*FileChanges{{
+		Source: filepath.Join(testDir, loopNoneSoundBank),
+		Output: valid,
+		Loops:  []*LoopChange{{WemRef{Index: 1}, "infinite"}},
+	}, {
+		Source: filepath.Join(testDir, simpleSoundBank),
+		Output: filepath.Join(dir, "invalid.bnk"),
+		Loops:  []*LoopChange{{WemRef{Index: 99}, "infinite"}},
+	}}}
+
+	_, err := Apply(m, nil)
+	if err == nil {
+		t.Error("Expected an invalid wem index to fail the manifest")
+	}
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	if len(files) != 0 {
+		t.Errorf("Expected nothing to be written but found %d file(s)",
+			len(files))
+	}
+}
+
+func TestApplyRestoresOutputs(t *testing.T) {
+	util.SkipIfShort(t)
+
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	existing := []byte("an existing output")
+	first := filepath.Join(dir, "first.bnk")
+	err := ioutil.WriteFile(first, existing, 0644)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	// A directory cannot be replaced by the second output, which fails the
+	// manifest after the first output has been moved into place.
+	second := filepath.Join(dir, "second.bnk")
+	err = os.MkdirAll(filepath.Join(second, "child"), os.ModePerm)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	m := &Manifest{[]*FileChanges{{
+		Source: filepath.Join(testDir, loopNoneSoundBank),
+		Output: first,
+		Loops:  []*LoopChange{{WemRef{Index: 1}, "2"}},
+	}, {
+		Source: filepath.Join(testDir, loopNoneSoundBank),
+		Output: second,
+		Loops:  []*LoopChange{{WemRef{Index: 1}, "infinite"}},
+	}}}
+
+	_, err = Apply(m, nil)
+	if err == nil {
+		t.Error("Expected an output that cannot be replaced to fail the manifest")
+	}
+	actual, err := ioutil.ReadFile(first)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	if !bytes.Equal(actual, existing) {
+		t.Error("Expected the first output to be restored")
+	}
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	if len(files) != 2 {
+		t.Errorf("Expected only the original outputs to remain but found %d "+
+			"file(s)", len(files))
+	}
+}
+
+func TestApplyRejectsDuplicateReplacements(t *testing.T) {
+	util.SkipIfShort(t)
+
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	source := filepath.Join(testDir, complexSoundBank)
+	b, err := bnk.Open(source)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	defer b.Close()
+	wem := b.Wems()[2]
+	data, err := ioutil.ReadAll(io.NewSectionReader(wem, 0,
+		int64(wem.Descriptor.Length)))
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	replacement := filepath.Join(dir, "replacement.wem")
+	err = ioutil.WriteFile(replacement, data, 0644)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+
+	// Refer to the same wem by both its index and its ID.
+	output := filepath.Join(dir, "out.bnk")
+	m := &Manifest{[]*FileChanges{{
+		Source: source,
+		Output: output,
+		Replacements: []*Replacement{{WemRef{Index: 3}, replacement},
+			{WemRef{Id: wem.Descriptor.WemId}, replacement}},
+	}}}
+	_, err = Apply(m, nil)
+	if err == nil {
+		t.Error("Expected two replacements of the same wem to fail the manifest")
+	}
+	if _, err := os.Stat(output); !os.IsNotExist(err) {
+		t.Error("Expected an invalid manifest not to write", output)
+	}
+}
+
+func TestApplyDryRun(t *testing.T) {
+	util.SkipIfShort(t)
+
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	output := filepath.Join(dir, "out.bnk")
+	m := &Manifest{[]*FileChanges{{
+		Source: filepath.Join(testDir, loopNoneSoundBank),
+		Output: output,
+		Loops:  []*LoopChange{{WemRef{Index: 1}, "2"}},
+	}}}
+
+	results, err := Apply(m, &Options{DryRun: true})
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	if _, err := os.Stat(output); !os.IsNotExist(err) {
+		t.Error("Expected a dry run not to write", output)
+	}
+
+	expect, err := os.Stat(filepath.Join(testDir, loop2SoundBank))
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	layout := results[0].Layout
+	if layout == nil || layout.NewSize != expect.Size() {
+		t.Errorf("Expected the new size to be %d but got %+v", expect.Size(),
+			layout)
+	}
+}
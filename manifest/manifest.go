@@ -0,0 +1,167 @@
+// Package manifest implements modding manifests, which describe every change
+// to make to a set of SoundBanks and File Packages, so that they can be applied
+// in a single pass.
+package manifest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// A Manifest lists the changes to make to a set of Wwise containers.
+type Manifest struct {
+	Files []*FileChanges `json:"files"`
+}
+
+// FileChanges describes the changes to make to a single container, and where to
+// write the changed container to.
+type FileChanges struct {
+	// The path to the SoundBank or File Package to change.
+	Source string `json:"source"`
+	// The path to write the changed container to. This may be the same as
+	// Source.
+	Output       string            `json:"output"`
+	Replacements []*Replacement    `json:"replacements,omitempty"`
+	Loops        []*LoopChange     `json:"loops,omitempty"`
+	Properties   []*PropertyChange `json:"properties,omitempty"`
+}
+
+// A WemRef refers to a single wem of a container by either its index or its
+// ID. Exactly one of the two must be set.
+type WemRef struct {
+	// The index, where 1 is the first wem, of the wem in the container.
+	Index int    `json:"index,omitempty"`
+	Id    uint32 `json:"id,omitempty"`
+}
+
+// A Replacement replaces a wem with the contents of a .wem file.
+type Replacement struct {
+	WemRef
+	// The path to the replacement .wem file.
+	Path string `json:"path"`
+}
+
+// A LoopChange changes the loop value of a wem in a SoundBank.
+type LoopChange struct {
+	WemRef
+	// The loop value: "none", "infinite" or the number of times to play the wem.
+	Value string `json:"value"`
+}
+
+// A PropertyChange changes a parameter of the sound object playing a wem in a
+// SoundBank.
+type PropertyChange struct {
+	WemRef
+	// The type of the parameter, either a name such as "volume" or a number.
+	Type string `json:"type"`
+	// The new value of the parameter.
+	Value float64 `json:"value"`
+	// If true, Value is stored as a 32-bit unsigned integer rather than a
+	// floating point number. This is only needed for parameter types that are
+	// referred to by number.
+	Integer bool `json:"integer,omitempty"`
+	// If true, the parameter is removed from the sound object and Value is
+	// ignored.
+	Remove bool `json:"remove,omitempty"`
+}
+
+// Load reads the JSON manifest at path. Relative paths within the manifest are
+// resolved against the directory holding the manifest.
+func Load(path string) (*Manifest, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	m := new(Manifest)
+	dec := json.NewDecoder(f)
+	dec.DisallowUnknownFields()
+	err = dec.Decode(m)
+	if err != nil {
+		return nil, fmt.Errorf("Could not parse manifest %s: %s", path, err)
+	}
+
+	dir := filepath.Dir(path)
+	resolve := func(p string) string {
+		if p == "" || filepath.IsAbs(p) {
+			return p
+		}
+		return filepath.Join(dir, p)
+	}
+	for _, fc := range m.Files {
+		fc.Source, fc.Output = resolve(fc.Source), resolve(fc.Output)
+		for _, r := range fc.Replacements {
+			r.Path = resolve(r.Path)
+		}
+	}
+	return m, m.Validate()
+}
+
+// Validate checks that this manifest is well formed. It does not open any of
+// the files that the manifest refers to.
+func (m *Manifest) Validate() error {
+	if len(m.Files) == 0 {
+		return errors.New("The manifest does not list any files")
+	}
+	var problems []string
+	outputs := make(map[string]bool)
+	for i, fc := range m.Files {
+		name := fmt.Sprintf("files[%d]", i)
+		switch {
+		case fc.Source == "":
+			problems = append(problems, name+": source cannot be empty")
+		case fc.Output == "":
+			problems = append(problems, name+": output cannot be empty")
+		case outputs[filepath.Clean(fc.Output)]:
+			problems = append(problems,
+				fmt.Sprintf("%s: %s is written by more than one file", name,
+					fc.Output))
+		}
+		outputs[filepath.Clean(fc.Output)] = true
+
+		check := func(kind string, j int, ref WemRef) {
+			if (ref.Index == 0) == (ref.Id == 0) {
+				problems = append(problems, fmt.Sprintf("%s.%s[%d]: exactly one of "+
+					"index or id must be specified", name, kind, j))
+			}
+		}
+		for j, r := range fc.Replacements {
+			check("replacements", j, r.WemRef)
+			if r.Path == "" {
+				problems = append(problems,
+					fmt.Sprintf("%s.replacements[%d]: path cannot be empty", name, j))
+			}
+		}
+		for j, l := range fc.Loops {
+			check("loops", j, l.WemRef)
+		}
+		for j, p := range fc.Properties {
+			check("properties", j, p.WemRef)
+		}
+	}
+	return problemsError(problems)
+}
+
+// problemsError returns an error listing every problem, or nil if there are
+// none.
+func problemsError(problems []string) error {
+	if len(problems) == 0 {
+		return nil
+	}
+	msg := "The manifest is invalid:"
+	for _, p := range problems {
+		msg += "\n  " + p
+	}
+	return errors.New(msg)
+}
+
+func (ref WemRef) String() string {
+	if ref.Id != 0 {
+		return fmt.Sprintf("wem id %d", ref.Id)
+	}
+	return fmt.Sprintf("wem %d", ref.Index)
+}
@@ -0,0 +1,311 @@
+// Package patch implements mod patches, which record the changes made to a
+// SoundBank or File Package so that they can be redistributed and applied to
+// the original file without sharing the whole modified file.
+package patch
+
+// Large system tests for the patch package.
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+import (
+	"github.com/hpxro7/wwiseutil/bnk"
+	"github.com/hpxro7/wwiseutil/util"
+	"github.com/hpxro7/wwiseutil/wwise"
+)
+
+const (
+	testDir = "../bnk/testdata"
+
+	complexSoundBank  = "complex.bnk"
+	largerSoundBank   = "0_replaced_with_larger.bnk"
+	loopNoneSoundBank = "loop_none.bnk"
+	loop23SoundBank   = "loop_23.bnk"
+	loop2SoundBank    = "loop_2.bnk"
+)
+
+func TestPatchRoundTrip(t *testing.T) {
+	util.SkipIfShort(t)
+
+	dir, err := ioutil.TempDir("", "patch")
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	defer os.RemoveAll(dir)
+
+	var tests = []struct {
+		base, modified string
+	}{
+		{filepath.Join(testDir, complexSoundBank),
+			writeReplacedSoundBank(t, dir)},
+		{filepath.Join(testDir, loopNoneSoundBank),
+			filepath.Join(testDir, loop23SoundBank)},
+	}
+	for _, test := range tests {
+		base, modified := test.base, test.modified
+		name := filepath.Base(modified)
+		p, err := Make(base, modified)
+		if err != nil {
+			t.Errorf("%s: %s", name, err)
+			continue
+		}
+		buf := new(bytes.Buffer)
+		_, err = p.WriteTo(buf)
+		if err != nil {
+			t.Errorf("%s: %s", name, err)
+			continue
+		}
+		p, err = Read(buf)
+		if err != nil {
+			t.Errorf("%s: %s", name, err)
+			continue
+		}
+
+		output := filepath.Join(dir, "patched_"+name)
+		_, err = p.Apply(base, output)
+		if err != nil {
+			t.Errorf("%s: %s", name, err)
+			continue
+		}
+		got, err := ioutil.ReadFile(output)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		expect, err := ioutil.ReadFile(modified)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		if !bytes.Equal(got, expect) {
+			t.Errorf("Applying the patch did not reproduce %s", name)
+		}
+
+		// The patch must refuse to apply to any other file.
+		_, err = p.Apply(modified, filepath.Join(dir, "mismatched.bnk"))
+		if err == nil {
+			t.Errorf("%s: expected the patch to refuse a mismatched base file",
+				name)
+		}
+		if _, err := os.Stat(filepath.Join(dir, "mismatched.bnk")); err == nil {
+			t.Errorf("%s: expected nothing to be written for a mismatched base "+
+				"file", name)
+		}
+	}
+}
+
+func TestRebase(t *testing.T) {
+	util.SkipIfShort(t)
+
+	dir, err := ioutil.TempDir("", "patch")
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	defer os.RemoveAll(dir)
+	replaced := writeReplacedSoundBank(t, dir)
+
+	var tests = []struct {
+		old, modified, new string
+		force              bool
+		// The file the rebased container is expected to match.
+		expect    string
+		conflicts int
+	}{
+		{complexSoundBank, replaced, complexSoundBank, false, replaced, 0},
+		{loopNoneSoundBank, loop23SoundBank, loopNoneSoundBank, false,
+			loop23SoundBank, 0},
+		// The update also changed the loop, so the change is skipped unless it
+		// is forced.
+		{loopNoneSoundBank, loop23SoundBank, loop2SoundBank, false,
+			loop2SoundBank, 1},
+		{loopNoneSoundBank, loop23SoundBank, loop2SoundBank, true,
+			loop23SoundBank, 1},
+	}
+
+	for _, test := range tests {
+		name := filepath.Base(test.modified) + " onto " + test.new
+		oldBase, err := bnk.Open(filepath.Join(testDir, test.old))
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		modified, err := bnk.Open(testPath(test.modified))
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		newBase, err := bnk.Open(filepath.Join(testDir, test.new))
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+
+		result, err := Rebase(oldBase, modified, newBase, test.force)
+		if err != nil {
+			t.Errorf("%s: %s", name, err)
+			continue
+		}
+		if len(result.Conflicts) != test.conflicts {
+			t.Errorf("%s: expected %d conflict(s) but got %d: %v", name,
+				test.conflicts, len(result.Conflicts), result.Conflicts)
+		}
+		for _, c := range result.Conflicts {
+			if c.Forced != test.force {
+				t.Errorf("%s: expected conflict %s to be forced: %t", name, c,
+					test.force)
+			}
+		}
+
+		got := new(bytes.Buffer)
+		_, err = newBase.WriteTo(got)
+		if err != nil {
+			t.Errorf("%s: %s", name, err)
+			continue
+		}
+		expect, err := ioutil.ReadFile(testPath(test.expect))
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		if !bytes.Equal(got.Bytes(), expect) {
+			t.Errorf("%s: expected the rebased SoundBank to match %s", name,
+				filepath.Base(test.expect))
+		}
+		oldBase.Close()
+		modified.Close()
+		newBase.Close()
+	}
+}
+
+func TestJournal(t *testing.T) {
+	util.SkipIfShort(t)
+
+	dir, err := ioutil.TempDir("", "journal")
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	defer os.RemoveAll(dir)
+
+	var tests = []struct {
+		original, modified string
+	}{
+		{complexSoundBank, writeReplacedSoundBank(t, dir)},
+		{complexSoundBank, largerSoundBank},
+		{loopNoneSoundBank, loop23SoundBank},
+		{loop23SoundBank, loopNoneSoundBank},
+	}
+	for _, test := range tests {
+		original, modified := testPath(test.original), testPath(test.modified)
+		name := filepath.Base(modified) + " to " + test.original
+		j, err := NewJournal(original, modified)
+		if err != nil {
+			t.Errorf("%s: %s", name, err)
+			continue
+		}
+		buf := new(bytes.Buffer)
+		_, err = j.WriteTo(buf)
+		if err != nil {
+			t.Errorf("%s: %s", name, err)
+			continue
+		}
+		j, err = ReadJournal(buf)
+		if err != nil {
+			t.Errorf("%s: %s", name, err)
+			continue
+		}
+
+		// Undo in place, as a copy of the modified file would be overwritten.
+		output := filepath.Join(dir, "undone.bnk")
+		err = copyFile(modified, output)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		_, err = j.Undo(output, output)
+		if err != nil {
+			t.Errorf("%s: %s", name, err)
+			continue
+		}
+		got, err := ioutil.ReadFile(output)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		expect, err := ioutil.ReadFile(original)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		if !bytes.Equal(got, expect) {
+			t.Errorf("%s: undoing the journal did not restore the original", name)
+		}
+
+		// The journal must refuse to undo any other file.
+		_, err = j.Undo(original, filepath.Join(dir, "mismatched.bnk"))
+		if err == nil {
+			t.Errorf("%s: expected the journal to refuse a mismatched file", name)
+		}
+	}
+}
+
+// copyFile copies the file at src to dst.
+func copyFile(src, dst string) error {
+	data, err := ioutil.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(dst, data, 0644)
+}
+
+// testPath returns the path to the test file name, which is either the name of
+// a file in the testdata directory or an absolute path.
+func testPath(name string) string {
+	if filepath.IsAbs(name) {
+		return name
+	}
+	return filepath.Join(testDir, name)
+}
+
+// writeReplacedSoundBank writes the complex SoundBank, with its first wem
+// replaced by the larger wem of largerSoundBank, to dir and returns its path.
+// The SoundBanks of the testdata directory were replaced before the lengths of
+// wems were kept in sync with their sound objects, so they can not be
+// reproduced by replacing their wems.
+func writeReplacedSoundBank(t *testing.T, dir string) string {
+	larger, err := bnk.Open(filepath.Join(testDir, largerSoundBank))
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	defer larger.Close()
+	ctn, err := bnk.Open(filepath.Join(testDir, complexSoundBank))
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	defer ctn.Close()
+
+	wem := larger.Wems()[0]
+	ctn.ReplaceWems(&wwise.ReplacementWem{wem, 0,
+		int64(wem.Descriptor.Length)})
+	path := filepath.Join(dir, "replaced.bnk")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	defer f.Close()
+	_, err = ctn.WriteTo(f)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	return path
+}
@@ -0,0 +1,394 @@
+// Package patch implements mod patches, which record the changes made to a
+// SoundBank or File Package so that they can be redistributed and applied to
+// the original file without sharing the whole modified file.
+package patch
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+import (
+	"github.com/hpxro7/wwiseutil/bnk"
+	"github.com/hpxro7/wwiseutil/container"
+	"github.com/hpxro7/wwiseutil/wwise"
+)
+
+// The extension of the journal written next to a modified file.
+const JournalExtension = ".journal"
+
+// The identifier that every journal starts with.
+var journalMagic = [4]byte{'W', 'W', 'J', 'N'}
+
+// The version of the journal format written by this package.
+const journalFormatVersion = 1
+
+// The number of bytes of the fixed portion of an original wem, which is
+// followed by the contents of the wem.
+const ORIGINAL_WEM_BYTES = 16
+
+// The number of bytes of the fixed portion of an original object, which is
+// followed by the data of the object.
+const ORIGINAL_OBJECT_BYTES = 8
+
+// A Journal records what a modification overwrote in a container, so that the
+// exact original file can be restored from the modified file: the original
+// contents of every replaced wem, and the original data of every HIRC object
+// that was changed.
+type Journal struct {
+	// The SHA-256 hash of the original file, which undoing restores.
+	OriginalHash [HASH_BYTES]byte
+	// The SHA-256 hash of the modified file that this journal undoes.
+	ModifiedHash [HASH_BYTES]byte
+	Wems         []*OriginalWem
+	Objects      []*OriginalObject
+}
+
+// An OriginalWem holds the original contents of a replaced wem.
+type OriginalWem struct {
+	// The index, where zero is the first wem, of the replaced wem.
+	Index int
+	// The ID of the replaced wem, which must match the wem at Index.
+	Id uint32
+	// The number of bytes of padding that originally followed the wem.
+	Padding int64
+	Data    []byte
+}
+
+// An OriginalObject holds the original data of a changed HIRC object,
+// including its descriptor.
+type OriginalObject struct {
+	// The index, where zero is the first object, of the changed object.
+	Index int
+	Data  []byte
+}
+
+// NewJournal creates a journal that restores the container at originalPath
+// from the container at modifiedPath, which must hold the same wems and HIRC
+// objects, in the same order, as the original.
+func NewJournal(originalPath, modifiedPath string) (*Journal, error) {
+	j := new(Journal)
+	var err error
+	j.OriginalHash, err = HashFile(originalPath)
+	if err != nil {
+		return nil, err
+	}
+	j.ModifiedHash, err = HashFile(modifiedPath)
+	if err != nil {
+		return nil, err
+	}
+
+	original, err := container.Open(originalPath)
+	if err != nil {
+		return nil, err
+	}
+	defer original.Close()
+	modified, err := container.Open(modifiedPath)
+	if err != nil {
+		return nil, err
+	}
+	defer modified.Close()
+
+	orgWems, wems := original.Wems(), modified.Wems()
+	if len(orgWems) != len(wems) {
+		return nil, fmt.Errorf("The modified file has %d wems, but the original "+
+			"has %d", len(wems), len(orgWems))
+	}
+	for i, wem := range orgWems {
+		id := wem.Descriptor.WemId
+		if id != wems[i].Descriptor.WemId {
+			return nil, fmt.Errorf("Wem %d of the modified file has the ID %d, but "+
+				"the original has the ID %d", i+1, wems[i].Descriptor.WemId, id)
+		}
+		orgHash, err := wwise.HashWem(wem)
+		if err != nil {
+			return nil, err
+		}
+		hash, err := wwise.HashWem(wems[i])
+		if err != nil {
+			return nil, err
+		}
+		padding := wem.Padding.Size()
+		if orgHash == hash && padding == wems[i].Padding.Size() {
+			continue
+		}
+		data, err := readWem(wem)
+		if err != nil {
+			return nil, err
+		}
+		j.Wems = append(j.Wems, &OriginalWem{i, id, padding, data})
+	}
+
+	orgBank, isSoundBank := original.(*bnk.File)
+	if bank, ok := modified.(*bnk.File); ok && isSoundBank {
+		j.Objects, err = changedObjects(orgBank, bank)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Check that the journal restores the original file exactly before anything
+	// relies on it.
+	err = j.verify(modifiedPath)
+	if err != nil {
+		return nil, err
+	}
+	return j, nil
+}
+
+// changedObjects returns the original data of every HIRC object of org that
+// differs from the object at the same index of bank.
+func changedObjects(org, bank *bnk.File) ([]*OriginalObject, error) {
+	if org.ObjectSection == nil || bank.ObjectSection == nil {
+		if org.ObjectSection != bank.ObjectSection {
+			return nil, errors.New("Only one of the files has a HIRC section")
+		}
+		return nil, nil
+	}
+	orgObjects, objects := org.ObjectSection.Objects(),
+		bank.ObjectSection.Objects()
+	if len(orgObjects) != len(objects) {
+		return nil, fmt.Errorf("The modified file has %d HIRC objects, but the "+
+			"original has %d", len(objects), len(orgObjects))
+	}
+	var changed []*OriginalObject
+	for i, obj := range orgObjects {
+		orgData := new(bytes.Buffer)
+		_, err := obj.WriteTo(orgData)
+		if err != nil {
+			return nil, err
+		}
+		data := new(bytes.Buffer)
+		_, err = objects[i].WriteTo(data)
+		if err != nil {
+			return nil, err
+		}
+		if !bytes.Equal(orgData.Bytes(), data.Bytes()) {
+			changed = append(changed, &OriginalObject{i, orgData.Bytes()})
+		}
+	}
+	return changed, nil
+}
+
+// verify checks that undoing this journal on the container at modifiedPath
+// produces a file with the hash OriginalHash.
+func (j *Journal) verify(modifiedPath string) error {
+	ctn, err := container.Open(modifiedPath)
+	if err != nil {
+		return err
+	}
+	defer ctn.Close()
+	err = j.UndoTo(ctn)
+	if err != nil {
+		return err
+	}
+	h := sha256.New()
+	_, err = ctn.WriteTo(h)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(h.Sum(nil), j.OriginalHash[:]) {
+		return errors.New("The modified file has changes that a journal cannot " +
+			"record; only replaced wems and changed HIRC objects can be recorded")
+	}
+	return nil
+}
+
+// UndoTo restores the original wems and HIRC objects of this journal to ctn in
+// memory. It does not check that ctn is the container this journal was made
+// for.
+func (j *Journal) UndoTo(ctn wwise.Container) error {
+	wems := ctn.Wems()
+	var rs []*wwise.ReplacementWem
+	for _, w := range j.Wems {
+		if w.Index < 0 || w.Index >= len(wems) {
+			return fmt.Errorf("The journal refers to wem %d, but the file only "+
+				"has %d wems", w.Index+1, len(wems))
+		}
+		if wems[w.Index].Descriptor.WemId != w.Id {
+			return fmt.Errorf("The journal expects wem %d to have the ID %d, but "+
+				"it has the ID %d", w.Index+1, w.Id, wems[w.Index].Descriptor.WemId)
+		}
+		rs = append(rs, &wwise.ReplacementWem{bytes.NewReader(w.Data), w.Index,
+			int64(len(w.Data))})
+	}
+	soundBank, isSoundBank := ctn.(*bnk.File)
+	if len(j.Objects) > 0 && !isSoundBank {
+		return errors.New("The journal restores HIRC objects, but the file is " +
+			"not a SoundBank")
+	}
+
+	if len(rs) > 0 {
+		ctn.ReplaceWems(rs...)
+	}
+	// Replacing a wem pads it to the default alignment, which may not be how
+	// the original was padded.
+	for _, w := range j.Wems {
+		if wems[w.Index].Padding.Size() == w.Padding {
+			continue
+		}
+		if !isSoundBank {
+			return fmt.Errorf("The padding of wem %d cannot be restored",
+				w.Index+1)
+		}
+		soundBank.SetPaddingOf(w.Index, w.Padding)
+	}
+	if len(j.Objects) > 0 {
+		data := make(map[int][]byte)
+		for _, o := range j.Objects {
+			data[o.Index] = o.Data
+		}
+		return soundBank.ReplaceObjects(data)
+	}
+	return nil
+}
+
+// Undo restores the original file from the modified container at
+// modifiedPath, and writes it to output, which may be the same as
+// modifiedPath. An error is returned, and nothing is written, if the file at
+// modifiedPath is not the file this journal was made for. The number of bytes
+// written is returned.
+func (j *Journal) Undo(modifiedPath, output string) (int64, error) {
+	hash, err := HashFile(modifiedPath)
+	if err != nil {
+		return 0, err
+	}
+	if hash != j.ModifiedHash {
+		return 0, fmt.Errorf("%s is not the file this journal was made for: its "+
+			"SHA-256 hash is %x, but the journal expects %x", modifiedPath, hash,
+			j.ModifiedHash)
+	}
+
+	ctn, err := container.Open(modifiedPath)
+	if err != nil {
+		return 0, err
+	}
+	defer ctn.Close()
+	err = j.UndoTo(ctn)
+	if err != nil {
+		return 0, err
+	}
+	return writeChecked(ctn, output, j.OriginalHash,
+		"Undoing the journal did not restore the original file")
+}
+
+// ReadJournal reads a journal written by WriteTo from r.
+func ReadJournal(r io.Reader) (*Journal, error) {
+	var hdr struct {
+		Magic   [4]byte
+		Version uint32
+	}
+	err := binary.Read(r, binary.LittleEndian, &hdr)
+	if err != nil {
+		return nil, err
+	}
+	if hdr.Magic != journalMagic {
+		return nil, errors.New("This is not a wwiseutil journal")
+	}
+	if hdr.Version != journalFormatVersion {
+		return nil, fmt.Errorf("The journal format version %d is not supported",
+			hdr.Version)
+	}
+
+	j := new(Journal)
+	var count uint32
+	for _, v := range []interface{}{&j.OriginalHash, &j.ModifiedHash, &count} {
+		err = binary.Read(r, binary.LittleEndian, v)
+		if err != nil {
+			return nil, err
+		}
+	}
+	for i := uint32(0); i < count; i++ {
+		var fixed struct {
+			Index, Id, Padding, Length uint32
+		}
+		err = binary.Read(r, binary.LittleEndian, &fixed)
+		if err != nil {
+			return nil, err
+		}
+		data, err := readData(r, fixed.Length)
+		if err != nil {
+			return nil, err
+		}
+		j.Wems = append(j.Wems, &OriginalWem{int(fixed.Index), fixed.Id,
+			int64(fixed.Padding), data})
+	}
+
+	err = binary.Read(r, binary.LittleEndian, &count)
+	if err != nil {
+		return nil, err
+	}
+	for i := uint32(0); i < count; i++ {
+		var fixed struct {
+			Index, Length uint32
+		}
+		err = binary.Read(r, binary.LittleEndian, &fixed)
+		if err != nil {
+			return nil, err
+		}
+		data, err := readData(r, fixed.Length)
+		if err != nil {
+			return nil, err
+		}
+		j.Objects = append(j.Objects, &OriginalObject{int(fixed.Index), data})
+	}
+	return j, nil
+}
+
+// WriteTo writes this journal to w.
+func (j *Journal) WriteTo(w io.Writer) (written int64, err error) {
+	buf := new(bytes.Buffer)
+	binary.Write(buf, binary.LittleEndian, journalMagic)
+	binary.Write(buf, binary.LittleEndian, uint32(journalFormatVersion))
+	buf.Write(j.OriginalHash[:])
+	buf.Write(j.ModifiedHash[:])
+	binary.Write(buf, binary.LittleEndian, uint32(len(j.Wems)))
+	n, err := buf.WriteTo(w)
+	written += n
+	if err != nil {
+		return written, err
+	}
+
+	write := func(bs ...[]byte) error {
+		for _, b := range bs {
+			n, err := w.Write(b)
+			written += int64(n)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	for _, wem := range j.Wems {
+		fixed := make([]byte, ORIGINAL_WEM_BYTES)
+		binary.LittleEndian.PutUint32(fixed[0:], uint32(wem.Index))
+		binary.LittleEndian.PutUint32(fixed[4:], wem.Id)
+		binary.LittleEndian.PutUint32(fixed[8:], uint32(wem.Padding))
+		binary.LittleEndian.PutUint32(fixed[12:], uint32(len(wem.Data)))
+		err = write(fixed, wem.Data)
+		if err != nil {
+			return written, err
+		}
+	}
+
+	count := make([]byte, 4)
+	binary.LittleEndian.PutUint32(count, uint32(len(j.Objects)))
+	err = write(count)
+	if err != nil {
+		return written, err
+	}
+	for _, o := range j.Objects {
+		fixed := make([]byte, ORIGINAL_OBJECT_BYTES)
+		binary.LittleEndian.PutUint32(fixed[0:], uint32(o.Index))
+		binary.LittleEndian.PutUint32(fixed[4:], uint32(len(o.Data)))
+		err = write(fixed, o.Data)
+		if err != nil {
+			return written, err
+		}
+	}
+	return written, nil
+}
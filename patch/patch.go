@@ -0,0 +1,460 @@
+// Package patch implements mod patches, which record the changes made to a
+// SoundBank or File Package so that they can be redistributed and applied to
+// the original file without sharing the whole modified file.
+package patch
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+import (
+	"github.com/hpxro7/wwiseutil/bnk"
+	"github.com/hpxro7/wwiseutil/container"
+	"github.com/hpxro7/wwiseutil/wwise"
+)
+
+// The identifier that every patch starts with.
+var magic = [4]byte{'W', 'W', 'P', 'T'}
+
+// The version of the patch format written by this package.
+const formatVersion = 1
+
+// The number of bytes in a SHA-256 hash.
+const HASH_BYTES = sha256.Size
+
+// The number of bytes of the fixed portion of a replacement, which is followed
+// by the contents of the replacement wem.
+const REPLACEMENT_BYTES = 12
+
+// The number of bytes of a single parameter edit.
+const EDIT_BYTES = 14
+
+// The kinds of parameter edit.
+const (
+	editRemove byte = iota
+	editSet
+)
+
+// A Patch records the changes made to an original container: the wems that
+// were replaced and the parameters of sound objects that were changed.
+type Patch struct {
+	// The SHA-256 hash of the original file that this patch applies to.
+	BaseHash [HASH_BYTES]byte
+	// The SHA-256 hash of the file that applying this patch produces.
+	ResultHash   [HASH_BYTES]byte
+	Replacements []*Replacement
+	Edits        []*ParameterEdit
+}
+
+// A Replacement replaces the contents of a single wem.
+type Replacement struct {
+	// The index, where zero is the first wem, of the wem to replace.
+	Index int
+	// The ID of the wem to replace, which must match the wem at Index.
+	Id   uint32
+	Data []byte
+}
+
+// A ParameterEdit changes a parameter of the sound object playing a wem in a
+// SoundBank.
+type ParameterEdit struct {
+	// The index, where zero is the first wem, of the wem whose sound object is
+	// changed.
+	Index int
+	// The ID of the wem, which must match the wem at Index.
+	Id uint32
+	// If true, the parameter is removed and Value is ignored.
+	Remove bool
+	Type   byte
+	Value  [4]byte
+}
+
+// HashFile returns the SHA-256 hash of the file at path.
+func HashFile(path string) ([HASH_BYTES]byte, error) {
+	var sum [HASH_BYTES]byte
+	f, err := os.Open(path)
+	if err != nil {
+		return sum, err
+	}
+	defer f.Close()
+	h := sha256.New()
+	_, err = io.Copy(h, f)
+	if err != nil {
+		return sum, err
+	}
+	copy(sum[:], h.Sum(nil))
+	return sum, nil
+}
+
+// Make creates a patch that records every change made to the container at
+// basePath to produce the container at modifiedPath. The modified container
+// must hold the same wems, in the same order, as the original, and may only
+// differ by the contents of its wems and the parameters of its sound objects.
+func Make(basePath, modifiedPath string) (*Patch, error) {
+	p := new(Patch)
+	var err error
+	p.BaseHash, err = HashFile(basePath)
+	if err != nil {
+		return nil, err
+	}
+	p.ResultHash, err = HashFile(modifiedPath)
+	if err != nil {
+		return nil, err
+	}
+
+	base, err := container.Open(basePath)
+	if err != nil {
+		return nil, err
+	}
+	defer base.Close()
+	modified, err := container.Open(modifiedPath)
+	if err != nil {
+		return nil, err
+	}
+	defer modified.Close()
+
+	orgWems, wems := base.Wems(), modified.Wems()
+	if len(orgWems) != len(wems) {
+		return nil, fmt.Errorf("The modified file has %d wems, but the original "+
+			"has %d", len(wems), len(orgWems))
+	}
+	for i, wem := range wems {
+		id := wem.Descriptor.WemId
+		if id != orgWems[i].Descriptor.WemId {
+			return nil, fmt.Errorf("Wem %d of the modified file has the ID %d, but "+
+				"the original has the ID %d", i+1, id, orgWems[i].Descriptor.WemId)
+		}
+		orgHash, err := wwise.HashWem(orgWems[i])
+		if err != nil {
+			return nil, err
+		}
+		hash, err := wwise.HashWem(wem)
+		if err != nil {
+			return nil, err
+		}
+		if orgHash == hash {
+			continue
+		}
+		data, err := readWem(wem)
+		if err != nil {
+			return nil, err
+		}
+		p.Replacements = append(p.Replacements, &Replacement{i, id, data})
+	}
+
+	orgBank, isSoundBank := base.(*bnk.File)
+	if bank, ok := modified.(*bnk.File); ok && isSoundBank {
+		for i := range wems {
+			p.Edits = append(p.Edits, parameterEdits(orgBank, i, bank, i)...)
+		}
+	}
+
+	// The patch can only record some changes, so check that it reproduces the
+	// modified file exactly.
+	err = p.verify(basePath)
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// readWem reads the contents of wem into memory.
+func readWem(wem *wwise.Wem) ([]byte, error) {
+	return ioutil.ReadAll(io.NewSectionReader(wem, 0,
+		int64(wem.Descriptor.Length)))
+}
+
+// parameterEdits returns the edits that change the parameters of the sound
+// object playing the wem at index orgIndex of org to match those of the sound
+// object playing the wem at index i of bank. The edits refer to index i.
+func parameterEdits(org *bnk.File, orgIndex int, bank *bnk.File,
+	i int) []*ParameterEdit {
+	id := bank.Wems()[i].Descriptor.WemId
+	var edits []*ParameterEdit
+	for _, t := range org.ParameterTypesOf(orgIndex) {
+		if _, ok := bank.ParameterOf(i, t); !ok {
+			edits = append(edits, &ParameterEdit{Index: i, Id: id, Remove: true,
+				Type: t})
+		}
+	}
+	for _, t := range bank.ParameterTypesOf(i) {
+		value, _ := bank.ParameterOf(i, t)
+		if orgValue, ok := org.ParameterOf(orgIndex, t); !ok || orgValue != value {
+			edits = append(edits, &ParameterEdit{Index: i, Id: id, Type: t,
+				Value: value})
+		}
+	}
+	return edits
+}
+
+// verify checks that applying this patch to the container at basePath
+// produces a file with the hash ResultHash.
+func (p *Patch) verify(basePath string) error {
+	ctn, err := container.Open(basePath)
+	if err != nil {
+		return err
+	}
+	defer ctn.Close()
+	err = p.ApplyTo(ctn)
+	if err != nil {
+		return err
+	}
+	h := sha256.New()
+	_, err = ctn.WriteTo(h)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(h.Sum(nil), p.ResultHash[:]) {
+		return errors.New("The modified file has changes that a patch cannot " +
+			"record; only replaced wems and changed sound object parameters can " +
+			"be recorded")
+	}
+	return nil
+}
+
+// ApplyTo makes the changes of this patch to ctn in memory. It does not check
+// that ctn is the container this patch was made for.
+func (p *Patch) ApplyTo(ctn wwise.Container) error {
+	wems := ctn.Wems()
+	check := func(index int, id uint32) error {
+		if index < 0 || index >= len(wems) {
+			return fmt.Errorf("The patch refers to wem %d, but the file only has "+
+				"%d wems", index+1, len(wems))
+		}
+		if wems[index].Descriptor.WemId != id {
+			return fmt.Errorf("The patch expects wem %d to have the ID %d, but it "+
+				"has the ID %d", index+1, id, wems[index].Descriptor.WemId)
+		}
+		return nil
+	}
+
+	var rs []*wwise.ReplacementWem
+	for _, r := range p.Replacements {
+		if err := check(r.Index, r.Id); err != nil {
+			return err
+		}
+		rs = append(rs, &wwise.ReplacementWem{bytes.NewReader(r.Data), r.Index,
+			int64(len(r.Data))})
+	}
+
+	soundBank, isSoundBank := ctn.(*bnk.File)
+	for _, e := range p.Edits {
+		if err := check(e.Index, e.Id); err != nil {
+			return err
+		}
+		if !isSoundBank || !soundBank.CanLoop(e.Index) {
+			return fmt.Errorf("The patch edits the sound object of wem %d, but it "+
+				"is not played by a sound effect", e.Index+1)
+		}
+	}
+
+	if len(rs) > 0 {
+		ctn.ReplaceWems(rs...)
+	}
+	for _, e := range p.Edits {
+		if e.Remove {
+			soundBank.RemoveParameterOf(e.Index, e.Type)
+		} else {
+			soundBank.ReplaceParameterOf(e.Index, e.Type, e.Value)
+		}
+	}
+	return nil
+}
+
+// Apply applies this patch to the container at basePath, and writes the result
+// to output, which may be the same as basePath. An error is returned, and
+// nothing is written, if the file at basePath is not the file this patch was
+// made for. The number of bytes written is returned.
+func (p *Patch) Apply(basePath, output string) (int64, error) {
+	hash, err := HashFile(basePath)
+	if err != nil {
+		return 0, err
+	}
+	if hash != p.BaseHash {
+		return 0, fmt.Errorf("%s is not the file this patch was made for: its "+
+			"SHA-256 hash is %x, but the patch expects %x", basePath, hash,
+			p.BaseHash)
+	}
+
+	ctn, err := container.Open(basePath)
+	if err != nil {
+		return 0, err
+	}
+	defer ctn.Close()
+	err = p.ApplyTo(ctn)
+	if err != nil {
+		return 0, err
+	}
+	return writeChecked(ctn, output, p.ResultHash,
+		"Applying the patch did not produce the expected file")
+}
+
+// writeChecked writes ctn to output, which may be the file ctn was opened
+// from. The output is left unchanged, and an error with the message mismatch
+// is returned, if what is written does not have the SHA-256 hash expected. The
+// number of bytes written is returned.
+func writeChecked(ctn wwise.Container, output string, expected [HASH_BYTES]byte,
+	mismatch string) (int64, error) {
+	// Write to a temporary file first, so that output is left unchanged if the
+	// result is not as expected.
+	dir := filepath.Dir(output)
+	f, err := ioutil.TempFile(dir, "."+filepath.Base(output)+".")
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(f.Name())
+	h := sha256.New()
+	written, err := ctn.WriteTo(io.MultiWriter(f, h))
+	if err == nil {
+		err = f.Chmod(0644)
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return 0, err
+	}
+	if !bytes.Equal(h.Sum(nil), expected[:]) {
+		return 0, errors.New(mismatch)
+	}
+	// The original must be closed before it can be overwritten.
+	ctn.Close()
+	err = os.Rename(f.Name(), output)
+	if err != nil {
+		return 0, err
+	}
+	return written, nil
+}
+
+// Read reads a patch written by WriteTo from r.
+func Read(r io.Reader) (*Patch, error) {
+	var hdr struct {
+		Magic   [4]byte
+		Version uint32
+	}
+	err := binary.Read(r, binary.LittleEndian, &hdr)
+	if err != nil {
+		return nil, err
+	}
+	if hdr.Magic != magic {
+		return nil, errors.New("This is not a wwiseutil patch")
+	}
+	if hdr.Version != formatVersion {
+		return nil, fmt.Errorf("The patch format version %d is not supported",
+			hdr.Version)
+	}
+
+	p := new(Patch)
+	var count uint32
+	for _, v := range []interface{}{&p.BaseHash, &p.ResultHash, &count} {
+		err = binary.Read(r, binary.LittleEndian, v)
+		if err != nil {
+			return nil, err
+		}
+	}
+	for i := uint32(0); i < count; i++ {
+		var fixed struct {
+			Index, Id, Length uint32
+		}
+		err = binary.Read(r, binary.LittleEndian, &fixed)
+		if err != nil {
+			return nil, err
+		}
+		data, err := readData(r, fixed.Length)
+		if err != nil {
+			return nil, err
+		}
+		p.Replacements = append(p.Replacements,
+			&Replacement{int(fixed.Index), fixed.Id, data})
+	}
+
+	err = binary.Read(r, binary.LittleEndian, &count)
+	if err != nil {
+		return nil, err
+	}
+	for i := uint32(0); i < count; i++ {
+		var fixed struct {
+			Index, Id uint32
+			Kind      byte
+			Type      byte
+			Value     [4]byte
+		}
+		err = binary.Read(r, binary.LittleEndian, &fixed)
+		if err != nil {
+			return nil, err
+		}
+		p.Edits = append(p.Edits, &ParameterEdit{int(fixed.Index), fixed.Id,
+			fixed.Kind == editRemove, fixed.Type, fixed.Value})
+	}
+	return p, nil
+}
+
+// readData reads length bytes from r.
+func readData(r io.Reader, length uint32) ([]byte, error) {
+	// Read through a LimitReader, so that a corrupt length cannot allocate more
+	// memory than r holds.
+	buf := new(bytes.Buffer)
+	n, err := io.Copy(buf, io.LimitReader(r, int64(length)))
+	if err != nil {
+		return nil, err
+	}
+	if n != int64(length) {
+		return nil, io.ErrUnexpectedEOF
+	}
+	return buf.Bytes(), nil
+}
+
+// WriteTo writes this patch to w.
+func (p *Patch) WriteTo(w io.Writer) (written int64, err error) {
+	buf := new(bytes.Buffer)
+	binary.Write(buf, binary.LittleEndian, magic)
+	binary.Write(buf, binary.LittleEndian, uint32(formatVersion))
+	buf.Write(p.BaseHash[:])
+	buf.Write(p.ResultHash[:])
+	binary.Write(buf, binary.LittleEndian, uint32(len(p.Replacements)))
+	n, err := buf.WriteTo(w)
+	written += n
+	if err != nil {
+		return written, err
+	}
+
+	for _, r := range p.Replacements {
+		fixed := make([]byte, REPLACEMENT_BYTES)
+		binary.LittleEndian.PutUint32(fixed[0:], uint32(r.Index))
+		binary.LittleEndian.PutUint32(fixed[4:], r.Id)
+		binary.LittleEndian.PutUint32(fixed[8:], uint32(len(r.Data)))
+		for _, b := range [][]byte{fixed, r.Data} {
+			n, err := w.Write(b)
+			written += int64(n)
+			if err != nil {
+				return written, err
+			}
+		}
+	}
+
+	buf.Reset()
+	binary.Write(buf, binary.LittleEndian, uint32(len(p.Edits)))
+	for _, e := range p.Edits {
+		edit := make([]byte, EDIT_BYTES)
+		binary.LittleEndian.PutUint32(edit[0:], uint32(e.Index))
+		binary.LittleEndian.PutUint32(edit[4:], e.Id)
+		edit[8] = editSet
+		if e.Remove {
+			edit[8] = editRemove
+		}
+		edit[9] = e.Type
+		copy(edit[10:], e.Value[:])
+		buf.Write(edit)
+	}
+	n, err = buf.WriteTo(w)
+	written += n
+	return written, err
+}
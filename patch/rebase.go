@@ -0,0 +1,187 @@
+// Package patch implements mod patches, which record the changes made to a
+// SoundBank or File Package so that they can be redistributed and applied to
+// the original file without sharing the whole modified file.
+package patch
+
+import (
+	"fmt"
+)
+
+import (
+	"github.com/hpxro7/wwiseutil/bnk"
+	"github.com/hpxro7/wwiseutil/wwise"
+)
+
+// A Conflict is a change that could not be replayed cleanly onto a new version
+// of a container, because the new version also changed the same wem or sound
+// object.
+type Conflict struct {
+	// The ID of the wem that was changed.
+	Id uint32 `json:"id"`
+	// A description of the change, such as "replaced wem".
+	Change string `json:"change"`
+	Reason string `json:"reason"`
+	// True if the change was replayed anyway.
+	Forced bool `json:"forced"`
+}
+
+func (c *Conflict) String() string {
+	s := fmt.Sprintf("wem id %d: %s: %s", c.Id, c.Change, c.Reason)
+	if c.Forced {
+		s += " (replayed anyway)"
+	}
+	return s
+}
+
+// A RebaseResult describes the changes replayed by Rebase.
+type RebaseResult struct {
+	// The number of replaced wems that were replayed.
+	Replaced int `json:"replaced"`
+	// The number of sound object parameter changes, including loop changes,
+	// that were replayed.
+	Edited    int         `json:"edited"`
+	Conflicts []*Conflict `json:"conflicts,omitempty"`
+}
+
+// Rebase works out the changes made to the container oldBase to produce
+// modified, and replays them onto newBase, an updated version of oldBase.
+// Changes are matched to wems by their ID: wems whose contents were replaced,
+// and the parameters, such as loop values, of the sound objects playing them.
+// Any other change to modified is not replayed. A change conflicts if newBase
+// also differs from oldBase in the same wem or parameter; conflicting changes
+// are only replayed if force is true. Changes to wems that newBase no longer
+// has are never replayed. newBase is changed in memory.
+func Rebase(oldBase, modified, newBase wwise.Container,
+	force bool) (*RebaseResult, error) {
+	result := new(RebaseResult)
+	p := new(Patch)
+	var conflict conflictFunc = func(id uint32, change, reason string,
+		forceable bool) bool {
+		forced := force && forceable
+		result.Conflicts = append(result.Conflicts,
+			&Conflict{id, change, reason, forced})
+		return forced
+	}
+
+	oldIndexes, newIndexes := wemIndexes(oldBase), wemIndexes(newBase)
+	indexes := wemIndexes(modified)
+	for i, wem := range modified.Wems() {
+		id := wem.Descriptor.WemId
+		oi, ok := oldIndexes[id]
+		if !ok {
+			conflict(id, "added wem", "only wems of the original version can be "+
+				"replayed", false)
+			continue
+		}
+		if indexes[id] != i {
+			continue
+		}
+		org := oldBase.Wems()[oi]
+		orgHash, hash, err := hashPair(org, wem)
+		if err != nil {
+			return nil, err
+		}
+		if orgHash == hash {
+			continue
+		}
+		ni, ok := newIndexes[id]
+		if !ok {
+			conflict(id, "replaced wem", "the new version no longer has this wem",
+				false)
+			continue
+		}
+		newHash, err := wwise.HashWem(newBase.Wems()[ni])
+		if err != nil {
+			return nil, err
+		}
+		if newHash != orgHash && !conflict(id, "replaced wem",
+			"the new version also changed this wem", true) {
+			continue
+		}
+		data, err := readWem(wem)
+		if err != nil {
+			return nil, fmt.Errorf("Could not read wem %d: %s", i+1, err)
+		}
+		p.Replacements = append(p.Replacements, &Replacement{ni, id, data})
+	}
+
+	oldBank, ok1 := oldBase.(*bnk.File)
+	bank, ok2 := modified.(*bnk.File)
+	newBank, ok3 := newBase.(*bnk.File)
+	if ok1 && ok2 && ok3 {
+		p.Edits = rebaseEdits(oldBank, bank, newBank, conflict)
+	}
+
+	err := p.ApplyTo(newBase)
+	if err != nil {
+		return nil, err
+	}
+	result.Replaced, result.Edited = len(p.Replacements), len(p.Edits)
+	return result, nil
+}
+
+// A conflictFunc records a conflicting change to the wem with the ID id, and
+// returns true if the change should be replayed anyway.
+type conflictFunc func(id uint32, change, reason string, forceable bool) bool
+
+// rebaseEdits returns the edits that replay the changes to the sound object
+// parameters made in bank, relative to oldBank, onto newBank. conflict records
+// each conflicting edit.
+func rebaseEdits(oldBank, bank, newBank *bnk.File,
+	conflict conflictFunc) []*ParameterEdit {
+	oldIndexes, newIndexes := wemIndexes(oldBank), wemIndexes(newBank)
+	indexes := wemIndexes(bank)
+	var edits []*ParameterEdit
+	for i, wem := range bank.Wems() {
+		id := wem.Descriptor.WemId
+		oi, ok := oldIndexes[id]
+		if !ok || indexes[id] != i || !bank.CanLoop(i) ||
+			!oldBank.CanLoop(oi) {
+			continue
+		}
+		for _, e := range parameterEdits(oldBank, oi, bank, i) {
+			change := "changed " + bnk.ParameterTypeName(e.Type)
+			if e.Remove {
+				change = "removed " + bnk.ParameterTypeName(e.Type)
+			}
+			ni, ok := newIndexes[id]
+			if !ok || !newBank.CanLoop(ni) {
+				conflict(id, change, "the new version no longer has a sound effect "+
+					"playing this wem", false)
+				continue
+			}
+			orgValue, orgOk := oldBank.ParameterOf(oi, e.Type)
+			newValue, newOk := newBank.ParameterOf(ni, e.Type)
+			if (orgOk != newOk || orgValue != newValue) && !conflict(id, change,
+				"the new version also changed this parameter", true) {
+				continue
+			}
+			e.Index = ni
+			edits = append(edits, e)
+		}
+	}
+	return edits
+}
+
+// wemIndexes returns the index, where zero is the first wem, of the first wem
+// of ctn with each ID.
+func wemIndexes(ctn wwise.Container) map[uint32]int {
+	indexes := make(map[uint32]int)
+	for i, wem := range ctn.Wems() {
+		if _, ok := indexes[wem.Descriptor.WemId]; !ok {
+			indexes[wem.Descriptor.WemId] = i
+		}
+	}
+	return indexes
+}
+
+// hashPair returns the hashes of the contents of the wems a and b, as given by
+// wwise.HashWem.
+func hashPair(a, b *wwise.Wem) (string, string, error) {
+	hashA, err := wwise.HashWem(a)
+	if err != nil {
+		return "", "", err
+	}
+	hashB, err := wwise.HashWem(b)
+	return hashA, hashB, err
+}
@@ -8,7 +8,9 @@ import (
 	"io"
 	"math"
 	"os"
+	"sort"
 	"strings"
+	"unicode/utf16"
 )
 
 import (
@@ -20,7 +22,19 @@ import (
 const HEADER_BYTES = 4 + 4 + 44 + 4
 
 // The number of bytes used to describe a single data index entry.
-const DATA_INDEX_BYTES = 4 + 4 + 4 + 4
+const DATA_INDEX_BYTES = 4 + 4 + 4 + 4 + 4
+
+// The number of bytes into the header's Unknown field where the length of the
+// language map is stored.
+const languageMapLengthOffset = 4
+
+// The number of bytes into the header's Unknown field where the language map
+// begins.
+const languageMapOffset = 20
+
+// The number of bytes used to describe a single language map entry, excluding
+// its name.
+const LANGUAGE_ENTRY_BYTES = 4 + 4
 
 // A File represents an open Wwise File Package.
 type File struct {
@@ -46,7 +60,9 @@ type DataIndex struct {
 	Type uint32
 	// A descriptor of the wem contained at this location, if it is a wem.
 	Descriptor *wwise.WemDescriptor
-	Unknown    uint32
+	// The ID of the language of this file, as stored in the header's language
+	// map.
+	Unknown uint32
 }
 
 // NewFile creates a new File for access Wwise File Package files. The file is
@@ -79,13 +95,14 @@ func NewFile(r io.ReaderAt) (*File, error) {
 
 	// Read in the data contained within this File Package
 	for i, idx := range pck.Indexes {
-		var nextOffset uint32
+		var nextOffset int64
 		if i+1 < len(pck.Indexes) {
 			// There is a subsequent wem, use it to find the next offset.
-			nextOffset = pck.Indexes[i+1].Descriptor.Offset
+			nextOffset = int64(pck.Indexes[i+1].Descriptor.Offset)
 		} else {
 			// This is the last wem, the next offset will be the end of the wem.
-			nextOffset = idx.Descriptor.Length + idx.Descriptor.Offset
+			nextOffset = int64(idx.Descriptor.Length) +
+				int64(idx.Descriptor.Offset)
 		}
 
 		wem, err := newWem(sr, idx, nextOffset)
@@ -195,6 +212,121 @@ func (pck *File) String() string {
 	return b.String()
 }
 
+// Listing returns a structured description of the wems and languages of this
+// File Package.
+func (pck *File) Listing() *wwise.Listing {
+	l := wwise.NewListing(pck, wwise.FilePackageType)
+	// The header is the only section of a File Package, and its length covers
+	// the indexes of every file.
+	l.Sections = append(l.Sections, &wwise.SectionListing{
+		string(pck.Header.Identifier[:]), 0, pck.Header.Length})
+	l.Size = HEADER_BYTES
+	languages := pck.Header.Languages()
+	for i, wem := range l.Wems {
+		wem.Language = languages[pck.Indexes[i].Unknown]
+		if end := int64(wem.Offset) + int64(wem.Length) + wem.Padding; end >
+			l.Size {
+			l.Size = end
+		}
+	}
+
+	var ids []uint32
+	for id := range languages {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	for _, id := range ids {
+		l.Languages = append(l.Languages, &wwise.LanguageListing{id, languages[id]})
+	}
+	return l
+}
+
+// Verify checks that this File Package is internally consistent: that the
+// header covers every data index, that wems are stored in order of their
+// offsets without overlapping, and that wem IDs are unique within each
+// language.
+func (pck *File) Verify() []*wwise.Problem {
+	var problems []*wwise.Problem
+	report := func(offset int64, format string, a ...interface{}) {
+		problems = append(problems, wwise.NewProblem(offset, format, a...))
+	}
+
+	// The length of the header excludes its identifier and the length itself,
+	// and covers the data indexes and the padding that follows them.
+	dataStart := int64(HEADER_BYTES + len(pck.Indexes)*DATA_INDEX_BYTES + 4)
+	if int64(pck.Header.Length)+8 != dataStart {
+		report(4, "The header declares %d bytes, but its %d data indexes end at "+
+			"offset %d", pck.Header.Length, len(pck.Indexes), dataStart)
+	}
+
+	type wemKey struct {
+		id       uint32
+		language uint32
+	}
+	seen := make(map[wemKey]bool)
+	end := dataStart
+	for i, idx := range pck.Indexes {
+		entryOffset := int64(HEADER_BYTES + i*DATA_INDEX_BYTES)
+		desc := idx.Descriptor
+		key := wemKey{desc.WemId, idx.Unknown}
+		if seen[key] {
+			report(entryOffset, "Wem %d has the same id, %d, and language as an "+
+				"earlier wem", i+1, desc.WemId)
+		}
+		seen[key] = true
+		if int64(desc.Offset) < end {
+			report(entryOffset, "Wem %d (id %d) starts at offset %d, before the "+
+				"end of the data that precedes it at offset %d", i+1, desc.WemId,
+				desc.Offset, end)
+		}
+		end = int64(desc.Offset) + int64(desc.Length)
+	}
+
+	problems = append(problems, wwise.VerifyReadable(pck, func(i int) int64 {
+		return int64(pck.Indexes[i].Descriptor.Offset)
+	})...)
+	wwise.SortProblems(problems)
+	return problems
+}
+
+// Languages returns the names of the languages of this File Package, by their
+// ID. Entries of the language map that do not fit within the header are
+// ignored.
+func (hdr *Header) Languages() map[uint32]string {
+	languages := make(map[uint32]string)
+	length := binary.LittleEndian.Uint32(hdr.Unknown[languageMapLengthOffset:])
+	end := languageMapOffset + int64(length)
+	if end > int64(len(hdr.Unknown)) {
+		end = int64(len(hdr.Unknown))
+	}
+	langMap := hdr.Unknown[languageMapOffset:end]
+	if len(langMap) < 4 {
+		return languages
+	}
+
+	count := binary.LittleEndian.Uint32(langMap)
+	for i := uint32(0); i < count; i++ {
+		entry := 4 + int64(i)*LANGUAGE_ENTRY_BYTES
+		if entry+LANGUAGE_ENTRY_BYTES > int64(len(langMap)) {
+			break
+		}
+		// Names are NUL terminated UTF-16 strings, at an offset relative to the
+		// start of the language map.
+		offset := int64(binary.LittleEndian.Uint32(langMap[entry:]))
+		id := binary.LittleEndian.Uint32(langMap[entry+4:])
+		var name []uint16
+		for c := offset; c >= 0 && c+2 <= int64(len(langMap)); c += 2 {
+			char := binary.LittleEndian.Uint16(langMap[c:])
+			if char == 0 {
+				break
+			}
+			name = append(name, char)
+		}
+		languages[id] = string(utf16.Decode(name))
+	}
+	return languages
+}
+
 func NewHeader(sr util.ReadSeekerAt) (*Header, error) {
 	hdr := new(Header)
 	err := binary.Read(sr, binary.LittleEndian, hdr)
@@ -285,7 +417,7 @@ func (idx *DataIndex) WriteTo(w io.Writer) (written int64, err error) {
 }
 
 func newWem(sr util.ReadSeekerAt, idx *DataIndex,
-	nextOffset uint32) (*wwise.Wem, error) {
+	nextOffset int64) (*wwise.Wem, error) {
 	startOffset, _ := sr.Seek(0, io.SeekCurrent)
 	desc := idx.Descriptor
 	if uint32(startOffset) != desc.Offset {
@@ -296,7 +428,17 @@ func newWem(sr util.ReadSeekerAt, idx *DataIndex,
 
 	wemReader := util.NewResettingReader(sr, startOffset, int64(desc.Length))
 	wemEndOffset := startOffset + int64(desc.Length)
-	remaining := int64(nextOffset) - wemEndOffset
+	remaining := nextOffset - wemEndOffset
+	if remaining < 0 {
+		return nil, fmt.Errorf("Wem %d ends at offset %d, past the start of the "+
+			"next wem at offset %d", desc.WemId, wemEndOffset, nextOffset)
+	}
+	// The padding is written as zeros rather than read, so check that the file
+	// holds it; a corrupt offset would otherwise pad the wem by gigabytes.
+	if remaining > 0 && !util.HasBytes(sr, wemEndOffset, remaining) {
+		return nil, fmt.Errorf("The padding after wem %d runs past the end of "+
+			"the file to offset %d", desc.WemId, nextOffset)
+	}
 
 	padding := util.NewResettingReader(&util.InfiniteReaderAt{0}, 0, remaining)
 	sr.Seek(int64(desc.Length)+remaining, io.SeekCurrent)
@@ -4,6 +4,7 @@ package pck
 // Large system tests for the bnk package.
 import (
 	"bytes"
+	"fmt"
 	"os"
 	"path/filepath"
 	"testing"
@@ -116,3 +117,127 @@ func rereadFile(t *testing.T, org *File) *File {
 
 	return ctn
 }
+
+func TestListing(t *testing.T) {
+	pck, err := Open(filepath.Join(testDir, simpleFilePackage))
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	l := pck.Listing()
+	if len(l.Wems) != len(pck.Wems()) {
+		t.Errorf("Expected %d wems to be listed but got %d", len(pck.Wems()),
+			len(l.Wems))
+	}
+	if len(l.Languages) != 1 || l.Languages[0].Name != "sfx" {
+		t.Errorf("Expected the only language to be sfx but got %v", l.Languages)
+	}
+	for _, wem := range l.Wems {
+		if wem.Language != "sfx" {
+			t.Errorf("Expected wem %d to have the language sfx but got %q",
+				wem.Index, wem.Language)
+		}
+	}
+}
+
+func TestNameTemplateRoundTrip(t *testing.T) {
+	pck, err := Open(filepath.Join(testDir, simpleFilePackage))
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	// Store every other wem in a second language, under the ID of the wem
+	// before it, as File Packages do for localized voices.
+	listing := pck.Listing()
+	for i, wem := range listing.Wems {
+		if i%2 == 1 {
+			wem.Id, wem.Language = listing.Wems[i-1].Id, "english(us)"
+		}
+	}
+	names := map[uint32]string{
+		listing.Wems[0].Id: "music/theme",
+		listing.Wems[2].Id: "voice_line",
+	}
+	for _, template := range []string{wwise.DefaultNameTemplate,
+		"{id}_{lang}{ext}", "{lang}-{name}{ext}", "{name}_{lang}",
+		"{lang}_{name}_{lang}{ext}"} {
+		tmpl, err := wwise.ParseNameTemplate(template)
+		if err != nil {
+			t.Errorf("%s: %s", template, err)
+			continue
+		}
+		for i, wem := range listing.Wems {
+			filename := tmpl.Format(&wwise.WemName{Index: i,
+				Count: len(listing.Wems), Id: wem.Id, Name: names[wem.Id],
+				Language: wem.Language})
+			index, _, err := tmpl.IndexOf(filename, listing, names)
+			if err != nil || index != i {
+				t.Errorf("%s: expected %s to be parsed as index %d but got %d, %v",
+					template, filename, i, index, err)
+			}
+		}
+	}
+
+	tmpl, _ := wwise.ParseNameTemplate("{name}_{lang}{ext}")
+	index, ext, err := tmpl.IndexOf("voice_line_english(us).wem", listing, names)
+	if err != nil || index != 3 || ext != wwise.WemExtension {
+		t.Errorf("Expected the name voice_line to be split from its language, "+
+			"but got %d, %q, %v", index, ext, err)
+	}
+	// Either a wem named voice with the language line_sfx, or the wem named
+	// voice_line with the language sfx.
+	names[listing.Wems[4].Id] = "voice"
+	listing.Wems[4].Language = "line_sfx"
+	_, _, err = tmpl.IndexOf("voice_line_sfx.wem", listing, names)
+	if err == nil {
+		t.Error("Expected a name that can be split in two ways to be rejected")
+	}
+	tmpl, _ = wwise.ParseNameTemplate("{id}{ext}")
+	filename := fmt.Sprintf("%d.wem", listing.Wems[0].Id)
+	if _, _, err = tmpl.IndexOf(filename, listing, names); err == nil {
+		t.Error("Expected an ID stored in two languages to be rejected")
+	}
+
+	for _, template := range []string{"{lang}{ext}", "{index}{nope}",
+		"{lang}/{index}"} {
+		if _, err := wwise.ParseNameTemplate(template); err == nil {
+			t.Errorf("Expected %s to be an invalid naming template", template)
+		}
+	}
+}
+
+func TestVerify(t *testing.T) {
+	util.SkipIfShort(t)
+
+	for _, name := range []string{simpleFilePackage, complexFilePackage} {
+		pck, err := Open(filepath.Join(testDir, name))
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		if problems := pck.Verify(); problems != nil {
+			t.Errorf("%s: expected no problems but got %v", name, problems)
+		}
+
+		// Swap the offsets of the first two wems, so that they are out of order.
+		first, second := pck.Indexes[0].Descriptor, pck.Indexes[1].Descriptor
+		first.Offset, second.Offset = second.Offset, first.Offset
+		problems := pck.Verify()
+		if len(problems) == 0 ||
+			problems[0].Offset != HEADER_BYTES+DATA_INDEX_BYTES {
+			t.Errorf("%s: expected the out of order wem to be reported, but got "+
+				"%v", name, problems)
+		}
+
+		// Lengthen the first wem so that it overlaps the second.
+		first.Offset, second.Offset = second.Offset, first.Offset
+		first.Length = second.Offset - first.Offset + 1
+		problems = pck.Verify()
+		if len(problems) == 0 ||
+			problems[0].Offset != HEADER_BYTES+DATA_INDEX_BYTES {
+			t.Errorf("%s: expected the overlapping wem to be reported, but got "+
+				"%v", name, problems)
+		}
+		pck.Close()
+	}
+}
@@ -0,0 +1,100 @@
+// Package pck implements access to the Wwise File Package file format.
+package pck
+
+// Fuzz tests for the parser of the pck package, seeded from the File Packages
+// of the testdata directory.
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"path/filepath"
+	"testing"
+)
+
+import (
+	"github.com/hpxro7/wwiseutil/util"
+)
+
+// The number of generated File Packages added to the seed corpus.
+const generatedSeeds = 8
+
+func FuzzNewFile(f *testing.F) {
+	for _, data := range seedFilePackages(f) {
+		f.Add(data)
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		pck, err := NewFile(bytes.NewReader(data))
+		if err != nil {
+			return
+		}
+		pck.Verify()
+		pck.Listing()
+		assertWriteTo(t, pck)
+	})
+}
+
+func TestPaddingPastEnd(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	org := generateFilePackage(r)
+	for binary.LittleEndian.Uint32(org[HEADER_BYTES-4:]) < 2 {
+		org = generateFilePackage(r)
+	}
+	// Move the second wem far past the end of the file, which would otherwise
+	// pad the first wem by gigabytes of zeros.
+	offset := HEADER_BYTES + DATA_INDEX_BYTES + 12
+	binary.LittleEndian.PutUint32(org[offset:], 0xF0000000)
+	_, err := NewFile(bytes.NewReader(org))
+	if err == nil {
+		t.Error("Expected padding past the end of the file to be rejected")
+	}
+}
+
+func TestOverlappingWems(t *testing.T) {
+	org, err := ioutil.ReadFile(filepath.Join(testDir, simpleFilePackage))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Lengthen the first wem so that it runs into the second, which would
+	// otherwise leave it with a negative, unbounded amount of padding.
+	offset := HEADER_BYTES + 8
+	next := binary.LittleEndian.Uint32(org[HEADER_BYTES+DATA_INDEX_BYTES+12:])
+	binary.LittleEndian.PutUint32(org[offset:], next)
+	_, err = NewFile(bytes.NewReader(org))
+	if err == nil {
+		t.Error("Expected a wem overlapping the next wem to be rejected")
+	}
+}
+
+// seedFilePackages returns the contents of every File Package of the testdata
+// directory, and of a few generated File Packages.
+func seedFilePackages(f *testing.F) [][]byte {
+	paths, err := filepath.Glob(filepath.Join(testDir, "*.pck"))
+	if err != nil {
+		f.Fatal(err)
+	}
+	var pcks [][]byte
+	for _, path := range paths {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			f.Fatal(err)
+		}
+		pcks = append(pcks, data)
+	}
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < generatedSeeds; i++ {
+		pcks = append(pcks, generateFilePackage(r))
+	}
+	return pcks
+}
+
+// assertWriteTo checks that w reports the number of bytes it writes.
+func assertWriteTo(t *testing.T, w io.WriterTo) {
+	counter := new(util.CountingWriter)
+	n, err := w.WriteTo(counter)
+	if err == nil && n != counter.Count {
+		t.Errorf("%d bytes were written, but %d bytes were reported to be "+
+			"written", counter.Count, n)
+	}
+}
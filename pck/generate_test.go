@@ -0,0 +1,65 @@
+// Package pck implements access to the Wwise File Package file format.
+package pck
+
+// Generators of random, valid File Packages for the property-based and fuzz
+// tests.
+import (
+	"bytes"
+	"encoding/binary"
+	"math/rand"
+)
+
+import (
+	"github.com/hpxro7/wwiseutil/wwise"
+)
+
+// The largest number of wems in a generated File Package.
+const maxGeneratedWems = 24
+
+// The largest number of bytes of a generated wem.
+const maxGeneratedWemBytes = 2048
+
+// The largest number of bytes of padding after a generated wem.
+const maxGeneratedPaddingBytes = 64
+
+// generateFilePackage returns a random, valid File Package built from r. It
+// holds a random number of wems of random lengths and languages, each but the
+// last followed by a random amount of padding.
+func generateFilePackage(r *rand.Rand) []byte {
+	count := 1 + r.Intn(maxGeneratedWems)
+	hdr := Header{Identifier: [4]byte{'A', 'K', 'P', 'K'},
+		WemCount: uint32(count)}
+	dataStart := HEADER_BYTES + count*DATA_INDEX_BYTES + 4
+	hdr.Length = uint32(dataStart - 8)
+	r.Read(hdr.Unknown[:])
+	// Leave the language map empty.
+	binary.LittleEndian.PutUint32(hdr.Unknown[languageMapLengthOffset:], 0)
+
+	seen := make(map[uint32]bool)
+	indexes := new(bytes.Buffer)
+	data := new(bytes.Buffer)
+	for i := 0; i < count; i++ {
+		id := r.Uint32()
+		for seen[id] {
+			id = r.Uint32()
+		}
+		seen[id] = true
+		wem := make([]byte, 1+r.Intn(maxGeneratedWemBytes))
+		r.Read(wem)
+		idx := &DataIndex{uint32(r.Intn(2)), &wwise.WemDescriptor{id,
+			uint32(dataStart + data.Len()), uint32(len(wem))},
+			uint32(r.Intn(3))}
+		idx.WriteTo(indexes)
+		data.Write(wem)
+		if i < count-1 {
+			data.Write(make([]byte, r.Intn(maxGeneratedPaddingBytes)))
+		}
+	}
+
+	pck := new(bytes.Buffer)
+	hdr.WriteTo(pck)
+	pck.Write(indexes.Bytes())
+	binary.Write(pck, binary.LittleEndian, r.Uint32())
+	pck.Write(data.Bytes())
+	return pck.Bytes()
+}
@@ -0,0 +1,122 @@
+// Package pck implements access to the Wwise File Package file format.
+package pck
+
+// Property-based tests for the pck package, run against generated File
+// Packages.
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"testing"
+	"testing/quick"
+)
+
+import (
+	"github.com/hpxro7/wwiseutil/wwise"
+)
+
+// The number of File Packages that each property is checked against.
+const propertyChecks = 200
+
+func TestGeneratedRoundTrip(t *testing.T) {
+	checkProperty(t, func(r *rand.Rand) error {
+		org := generateFilePackage(r)
+		pck, err := NewFile(bytes.NewReader(org))
+		if err != nil {
+			return err
+		}
+		if problems := pck.Verify(); len(problems) > 0 {
+			return fmt.Errorf("the generated File Package is inconsistent: %s",
+				problems[0])
+		}
+		for i := 0; i < 2; i++ {
+			err = assertWrites(pck, org)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func TestGeneratedReplaceRestore(t *testing.T) {
+	checkProperty(t, func(r *rand.Rand) error {
+		org := generateFilePackage(r)
+		pck, err := NewFile(bytes.NewReader(org))
+		if err != nil {
+			return err
+		}
+
+		// Replace a random selection of wems, recording their original contents.
+		var originals, rs []*wwise.ReplacementWem
+		for _, i := range r.Perm(len(pck.Wems()))[:1+r.Intn(len(pck.Wems()))] {
+			wem := pck.Wems()[i]
+			data, err := ioutil.ReadAll(io.NewSectionReader(wem, 0,
+				int64(wem.Descriptor.Length)))
+			if err != nil {
+				return err
+			}
+			originals = append(originals, &wwise.ReplacementWem{
+				bytes.NewReader(data), i, int64(len(data))})
+
+			replacement := make([]byte, 1+r.Intn(maxGeneratedWemBytes))
+			r.Read(replacement)
+			rs = append(rs, &wwise.ReplacementWem{bytes.NewReader(replacement), i,
+				int64(len(replacement))})
+		}
+		pck.ReplaceWems(rs...)
+		replaced := new(bytes.Buffer)
+		_, err = pck.WriteTo(replaced)
+		if err != nil {
+			return err
+		}
+		pck, err = NewFile(bytes.NewReader(replaced.Bytes()))
+		if err != nil {
+			return err
+		}
+		if problems := pck.Verify(); len(problems) > 0 {
+			return fmt.Errorf("the replaced File Package is inconsistent: %s",
+				problems[0])
+		}
+
+		pck.ReplaceWems(originals...)
+		return assertWrites(pck, org)
+	})
+}
+
+// checkProperty checks that property returns no error for a fixed sequence of
+// random sources, reporting the seed of the source of any failure.
+func checkProperty(t *testing.T, property func(r *rand.Rand) error) {
+	check := func(seed int64) bool {
+		err := property(rand.New(rand.NewSource(seed)))
+		if err != nil {
+			t.Errorf("seed %d: %s", seed, err)
+		}
+		return err == nil
+	}
+	err := quick.Check(check, &quick.Config{MaxCount: propertyChecks,
+		Rand: rand.New(rand.NewSource(1))})
+	if _, ok := err.(*quick.CheckError); err != nil && !ok {
+		t.Error(err)
+	}
+}
+
+// assertWrites returns an error if pck does not write exactly expect.
+func assertWrites(pck *File, expect []byte) error {
+	written := new(bytes.Buffer)
+	total, err := pck.WriteTo(written)
+	if err != nil {
+		return err
+	}
+	if total != int64(written.Len()) {
+		return fmt.Errorf("%d bytes were written, but %d bytes were reported to "+
+			"be written", written.Len(), total)
+	}
+	if !bytes.Equal(written.Bytes(), expect) {
+		return errors.New("the written File Package differs from the original")
+	}
+	return nil
+}
@@ -27,6 +27,20 @@ func (r *ResettingReader) Read(p []byte) (n int, err error) {
 	return
 }
 
+// HasBytes returns true if r holds at least n bytes starting at offset off.
+// Lengths read from a file can be checked this way before they are trusted.
+func HasBytes(r io.ReaderAt, off, n int64) bool {
+	if off < 0 || n < 0 {
+		return false
+	}
+	if n == 0 {
+		return true
+	}
+	var last [1]byte
+	read, _ := r.ReadAt(last[:], off+n-1)
+	return read == len(last)
+}
+
 // A utility ReaderAt that emits an infinite stream of a specific value.
 type InfiniteReaderAt struct {
 	// The value that this padding writer will write.
@@ -46,3 +60,15 @@ func (r *InfiniteReaderAt) ReadAt(p []byte, off int64) (int, error) {
 func NewConstantReader(size int64) io.ReaderAt {
 	return io.NewSectionReader(&InfiniteReaderAt{'A'}, 0, size)
 }
+
+// A CountingWriter discards everything written to it, counting the number of
+// bytes written.
+type CountingWriter struct {
+	Count int64
+}
+
+// Write counts the len(p) bytes of p as written.
+func (w *CountingWriter) Write(p []byte) (int, error) {
+	w.Count += int64(len(p))
+	return len(p), nil
+}
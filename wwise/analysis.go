@@ -0,0 +1,229 @@
+// Package wwise implements access and modification iterfaces and functions to
+// common WWise container formats.
+package wwise
+
+import (
+	"io"
+	"math"
+)
+
+// The level, in dBFS or LUFS, reported for silent audio.
+const SilenceLevel = -144.0
+
+// The duration in seconds of a single gating block, as defined by ITU-R BS.1770.
+const gatingBlockSeconds = 0.4
+
+// The number of gating blocks that overlap a single point in time.
+const gatingBlockOverlap = 4
+
+// The absolute gating threshold, in LUFS.
+const absoluteGate = -70.0
+
+// The relative gating threshold, in LU below the absolute-gated loudness.
+const relativeGate = -10.0
+
+// Loudness describes the level of a single wem.
+type Loudness struct {
+	// The sample peak of all channels, in dBFS.
+	Peak float64 `json:"peak"`
+	// The root mean square level of all channels, in dBFS.
+	RMS float64 `json:"rms"`
+	// The gated integrated loudness as defined by ITU-R BS.1770, in LUFS.
+	Integrated float64 `json:"integrated"`
+	// The duration of the wem, in seconds.
+	Duration float64 `json:"duration"`
+}
+
+// A LoudnessComparison describes how the level of a replacement wem differs
+// from the wem it replaces. Positive differences mean the replacement is louder.
+type LoudnessComparison struct {
+	Original    *Loudness `json:"original"`
+	Replacement *Loudness `json:"replacement"`
+	// The difference in sample peak, in dB.
+	PeakDifference float64 `json:"peak_difference"`
+	// The difference in RMS level, in dB.
+	RMSDifference float64 `json:"rms_difference"`
+	// The difference in integrated loudness, in LU.
+	IntegratedDifference float64 `json:"integrated_difference"`
+}
+
+// A biquad is a second order IIR filter in direct form I.
+type biquad struct {
+	b0, b1, b2, a1, a2 float64
+	x1, x2, y1, y2     float64
+}
+
+// AnalyzeLoudness decodes the wem stored in the first size bytes of r and
+// measures its loudness. ErrNotDecodable is returned if the wem's codec is not
+// supported.
+func AnalyzeLoudness(r io.ReaderAt, size int64) (*Loudness, error) {
+	dec, err := Decode(r, size)
+	if err != nil {
+		return nil, err
+	}
+	return dec.Loudness(), nil
+}
+
+// CompareLoudness compares the loudness of a replacement wem with the loudness
+// of the original wem it replaces.
+func CompareLoudness(org, rep *Loudness) *LoudnessComparison {
+	return &LoudnessComparison{org, rep, rep.Peak - org.Peak, rep.RMS - org.RMS,
+		rep.Integrated - org.Integrated}
+}
+
+// Loudness measures the loudness of the decoded samples.
+func (dec *DecodedWem) Loudness() *Loudness {
+	l := &Loudness{SilenceLevel, SilenceLevel, SilenceLevel, 0}
+	if len(dec.Channels) == 0 || len(dec.Channels[0]) == 0 ||
+		dec.SampleRate <= 0 {
+		return l
+	}
+	frames := len(dec.Channels[0])
+	l.Duration = float64(frames) / float64(dec.SampleRate)
+
+	peak, sumSquares := 0.0, 0.0
+	for _, samples := range dec.Channels {
+		for _, s := range samples {
+			peak = math.Max(peak, math.Abs(s))
+			sumSquares += s * s
+		}
+	}
+	l.Peak = toDecibels(peak)
+	l.RMS = toDecibels(math.Sqrt(sumSquares / float64(frames*len(dec.Channels))))
+	l.Integrated = dec.integratedLoudness()
+	return l
+}
+
+// integratedLoudness computes the gated loudness of the decoded samples, as
+// defined by ITU-R BS.1770-4.
+func (dec *DecodedWem) integratedLoudness() float64 {
+	rate := float64(dec.SampleRate)
+	blockLength := int(rate * gatingBlockSeconds)
+	step := blockLength / gatingBlockOverlap
+	frames := len(dec.Channels[0])
+	if step == 0 || frames < blockLength {
+		return SilenceLevel
+	}
+
+	// The mean square of each block, summed across weighted channels.
+	blockCount := (frames-blockLength)/step + 1
+	powers := make([]float64, blockCount)
+	for c, samples := range dec.Channels {
+		weight := channelWeight(c, len(dec.Channels))
+		if weight == 0 {
+			continue
+		}
+		filtered := kWeight(samples, rate)
+		for b := range powers {
+			sum := 0.0
+			for _, s := range filtered[b*step : b*step+blockLength] {
+				sum += s * s
+			}
+			powers[b] += weight * sum / float64(blockLength)
+		}
+	}
+
+	gated := func(threshold float64) (mean float64, count int) {
+		for _, p := range powers {
+			if blockLoudness(p) > threshold {
+				mean += p
+				count++
+			}
+		}
+		if count == 0 {
+			return 0, 0
+		}
+		return mean / float64(count), count
+	}
+
+	mean, count := gated(absoluteGate)
+	if count == 0 {
+		return SilenceLevel
+	}
+	mean, count = gated(blockLoudness(mean) + relativeGate)
+	if count == 0 {
+		return SilenceLevel
+	}
+	return blockLoudness(mean)
+}
+
+// channelWeight returns the weight of the channel at index c of a wem with
+// count channels. Surround channels of a 5.1 wem are weighted higher and the
+// LFE channel is excluded.
+func channelWeight(c, count int) float64 {
+	if count == 6 {
+		switch c {
+		case 3:
+			return 0
+		case 4, 5:
+			return 1.41
+		}
+	}
+	return 1
+}
+
+// kWeight applies the two stage K-weighting pre-filter of ITU-R BS.1770 to
+// samples recorded at the sample rate rate.
+func kWeight(samples []float64, rate float64) []float64 {
+	// Stage 1: a high shelf modelling the acoustic effect of the head.
+	const (
+		shelfFrequency = 1681.974450955533
+		shelfGain      = 3.999843853973347
+		shelfQ         = 0.7071752369554196
+	)
+	k := math.Tan(math.Pi * shelfFrequency / rate)
+	vh := math.Pow(10, shelfGain/20)
+	vb := math.Pow(vh, 0.4996667741545416)
+	a0 := 1 + k/shelfQ + k*k
+	shelf := &biquad{
+		b0: (vh + vb*k/shelfQ + k*k) / a0,
+		b1: 2 * (k*k - vh) / a0,
+		b2: (vh - vb*k/shelfQ + k*k) / a0,
+		a1: 2 * (k*k - 1) / a0,
+		a2: (1 - k/shelfQ + k*k) / a0,
+	}
+
+	// Stage 2: the RLB high pass filter.
+	const (
+		passFrequency = 38.13547087602444
+		passQ         = 0.5003270373238773
+	)
+	k = math.Tan(math.Pi * passFrequency / rate)
+	a0 = 1 + k/passQ + k*k
+	pass := &biquad{
+		b0: 1,
+		b1: -2,
+		b2: 1,
+		a1: 2 * (k*k - 1) / a0,
+		a2: (1 - k/passQ + k*k) / a0,
+	}
+
+	filtered := make([]float64, len(samples))
+	for i, s := range samples {
+		filtered[i] = pass.process(shelf.process(s))
+	}
+	return filtered
+}
+
+func (f *biquad) process(x float64) float64 {
+	y := f.b0*x + f.b1*f.x1 + f.b2*f.x2 - f.a1*f.y1 - f.a2*f.y2
+	f.x2, f.x1 = f.x1, x
+	f.y2, f.y1 = f.y1, y
+	return y
+}
+
+// blockLoudness returns the loudness, in LUFS, of a weighted mean square power.
+func blockLoudness(power float64) float64 {
+	if power <= 0 {
+		return SilenceLevel
+	}
+	return -0.691 + 10*math.Log10(power)
+}
+
+// toDecibels converts a linear amplitude into dBFS.
+func toDecibels(amplitude float64) float64 {
+	if amplitude <= 0 {
+		return SilenceLevel
+	}
+	return math.Max(20*math.Log10(amplitude), SilenceLevel)
+}
@@ -0,0 +1,147 @@
+// Package wwise implements access and modification iterfaces and functions to
+// common WWise container formats.
+package wwise
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// The extension used for wems that are written without conversion.
+const WemExtension = ".wem"
+
+// Codec identifies the encoding of the audio data stored within a wem.
+type Codec int
+
+const (
+	UnknownCodec Codec = iota
+	PCMCodec
+	ADPCMCodec
+	VorbisCodec
+	OpusCodec
+	OpusNXCodec
+	OpusMultistreamCodec
+	XMACodec
+	XMA2Codec
+	AACCodec
+	ATRAC9Codec
+	DSPCodec
+)
+
+// The mapping from a wem's fmt format tag to the codec it identifies.
+var codecOfFormatTag = map[uint16]Codec{
+	0x0001: PCMCodec,
+	0xFFFE: PCMCodec,
+	0x0002: ADPCMCodec,
+	0xFFFF: VorbisCodec,
+	0x3040: OpusCodec,
+	0x3039: OpusNXCodec,
+	0x3041: OpusMultistreamCodec,
+	0x0165: XMACodec,
+	0x0166: XMA2Codec,
+	0xAAC0: AACCodec,
+	0xFFFC: ATRAC9Codec,
+	0xFFF0: DSPCodec,
+}
+
+var codecNames = map[Codec]string{
+	UnknownCodec:         "unknown",
+	PCMCodec:             "pcm",
+	ADPCMCodec:           "adpcm",
+	VorbisCodec:          "vorbis",
+	OpusCodec:            "opus",
+	OpusNXCodec:          "opusnx",
+	OpusMultistreamCodec: "opusww",
+	XMACodec:             "xma",
+	XMA2Codec:            "xma2",
+	AACCodec:             "aac",
+	ATRAC9Codec:          "atrac9",
+	DSPCodec:             "dsp",
+}
+
+// ErrExtractionUnsupported is returned when a wem's codec has no standard
+// container that it can be extracted into.
+var ErrExtractionUnsupported = errors.New(
+	"Extraction into a standard container is not supported for this codec")
+
+// DetectCodec reads the fmt chunk of the wem stored in the first size bytes of
+// r and returns the codec it was encoded with. UnknownCodec is returned, without
+// an error, for valid wems that use a codec this package does not recognize.
+func DetectCodec(r io.ReaderAt, size int64) (Codec, error) {
+	hdr, err := ReadWemHeader(r, size)
+	if err != nil {
+		return UnknownCodec, err
+	}
+	return hdr.Codec(), nil
+}
+
+// Codec returns the codec this wem was encoded with, based on its fmt chunk.
+func (hdr *WemHeader) Codec() Codec {
+	if hdr.Format == nil {
+		return UnknownCodec
+	}
+	return codecOfFormatTag[hdr.Format.FormatTag]
+}
+
+// ParseCodec returns the Codec identified by name, as returned by Codec.String.
+func ParseCodec(name string) (Codec, error) {
+	for c, n := range codecNames {
+		if strings.EqualFold(n, name) {
+			return c, nil
+		}
+	}
+	return UnknownCodec, fmt.Errorf("%s is not a known codec", name)
+}
+
+// Extractable returns true if wems of this codec can be extracted into a
+// standard container using Extract.
+func (c Codec) Extractable() bool {
+	return c == OpusCodec || c == XMA2Codec
+}
+
+// Extension returns the file extension, including the leading dot, of the
+// container that wems of this codec are extracted into. WemExtension is
+// returned for codecs that are not Extractable.
+func (c Codec) Extension() string {
+	switch c {
+	case OpusCodec:
+		return ".opus"
+	case XMA2Codec:
+		return ".xma"
+	}
+	return WemExtension
+}
+
+func (c Codec) String() string {
+	if name, ok := codecNames[c]; ok {
+		return name
+	}
+	return fmt.Sprintf("Codec(%d)", int(c))
+}
+
+// Extract converts the wem stored in the first size bytes of r into the
+// standard container for its codec, and writes it to w. Wwise Opus wems are
+// written as Ogg Opus streams and XMA2 wems are written as little-endian XMA2
+// RIFF files. ErrExtractionUnsupported is returned for any other codec.
+func Extract(w io.Writer, r io.ReaderAt, size int64) (written int64, err error) {
+	hdr, err := ReadWemHeader(r, size)
+	if err != nil {
+		return 0, err
+	}
+	for _, c := range hdr.Chunks {
+		if c.End() > size {
+			return 0, fmt.Errorf("The %q chunk at offset 0x%X runs past the end of "+
+				"the wem", c.Id, c.Offset)
+		}
+	}
+
+	switch hdr.Codec() {
+	case OpusCodec:
+		return extractOpus(w, r, hdr)
+	case XMA2Codec:
+		return extractXMA2(w, r, hdr)
+	}
+	return 0, ErrExtractionUnsupported
+}
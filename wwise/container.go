@@ -3,9 +3,12 @@
 package wwise
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"sort"
+	"strconv"
+	"strings"
 )
 
 import (
@@ -30,6 +33,10 @@ type Container interface {
 	// begins. DataStart() + WemDescriptor.Length gives you the true offset of a
 	// wem in a file.
 	DataStart() uint32
+
+	// Listing returns a structured description of the contents of this
+	// container.
+	Listing() *Listing
 }
 
 // A Wem represents a single sound entity contained within a SoundBank file.
@@ -42,6 +49,17 @@ type Wem struct {
 	Padding util.ReadSeekerAt
 }
 
+// ReadAt reads len(p) bytes of this wem's contents starting at byte offset off.
+// It is an error to call this method on a wem whose Reader does not support
+// random access.
+func (wem *Wem) ReadAt(p []byte, off int64) (int, error) {
+	ra, ok := wem.Reader.(io.ReaderAt)
+	if !ok {
+		return 0, errors.New("The wem does not support random access")
+	}
+	return ra.ReadAt(p, off)
+}
+
 // A WemDescriptor represents the location of a single wem entity within the
 // SoundBank DATA section.
 type WemDescriptor struct {
@@ -53,6 +71,45 @@ type WemDescriptor struct {
 	Length uint32
 }
 
+// A LoopValue identifier for looping infinite times.
+const InfiniteLoops = 0
+
+// LoopValue describes the loop parameters of a given audio object.
+type LoopValue struct {
+	// True if this audio object loops; and false if otherwise.
+	Loops bool `json:"loops"`
+	// The number of times this audio track will play. 0 means that this audio will
+	// play infinite times. This value is not vaild if loops is false.
+	Value uint32 `json:"value"`
+}
+
+// ParseLoopValue parses a loop value written as "none", "infinite" or the number
+// of times to play, which must be at least 2.
+func ParseLoopValue(s string) (LoopValue, error) {
+	switch strings.ToLower(s) {
+	case "none":
+		return LoopValue{false, 0}, nil
+	case "infinite", "infinity", "inf":
+		return LoopValue{true, InfiniteLoops}, nil
+	}
+	times, err := strconv.ParseUint(s, 10, 32)
+	if err != nil || times < 2 {
+		return LoopValue{}, fmt.Errorf("%s is not a valid loop value; it must be "+
+			"none, infinite or a number of times greater than 1", s)
+	}
+	return LoopValue{true, uint32(times)}, nil
+}
+
+func (loop LoopValue) String() string {
+	switch {
+	case !loop.Loops:
+		return "None"
+	case loop.Value == InfiniteLoops:
+		return "Infinity"
+	}
+	return fmt.Sprintf("%d times", loop.Value)
+}
+
 // A ReplacementWem defines a wem to be replaced into an original SoundBank File.
 type ReplacementWem struct {
 	// The reader pointing to the contents of the new wem.
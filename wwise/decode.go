@@ -0,0 +1,183 @@
+// Package wwise implements access and modification iterfaces and functions to
+// common WWise container formats.
+package wwise
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// The number of bytes used to describe the header of a single channel within
+// a Wwise IMA ADPCM block.
+const IMA_CHANNEL_HEADER_BYTES = 4
+
+// ErrNotDecodable is returned when a wem's codec cannot be decoded into PCM
+// samples by this package.
+var ErrNotDecodable = errors.New(
+	"Decoding is only supported for PCM and ADPCM wems")
+
+// The IMA ADPCM step sizes, indexed by the current step index.
+var imaStepTable = [89]int32{
+	7, 8, 9, 10, 11, 12, 13, 14, 16, 17, 19, 21, 23, 25, 28, 31, 34, 37, 41,
+	45, 50, 55, 60, 66, 73, 80, 88, 97, 107, 118, 130, 143, 157, 173, 190, 209,
+	230, 253, 279, 307, 337, 371, 408, 449, 494, 544, 598, 658, 724, 796, 876,
+	963, 1060, 1166, 1282, 1411, 1552, 1707, 1878, 2066, 2272, 2499, 2749, 3024,
+	3327, 3660, 4026, 4428, 4871, 5358, 5894, 6484, 7132, 7845, 8630, 9493,
+	10442, 11487, 12635, 13899, 15289, 16818, 18500, 20350, 22385, 24623,
+	27086, 29794, 32767,
+}
+
+// The adjustment made to the IMA ADPCM step index after decoding a nibble.
+var imaIndexTable = [8]int32{-1, -1, -1, -1, 2, 4, 6, 8}
+
+// A DecodedWem holds the samples of a decoded wem.
+type DecodedWem struct {
+	SampleRate int
+	// The samples of each channel, normalized to the range [-1, 1].
+	Channels [][]float64
+}
+
+// Decode decodes the wem stored in the first size bytes of r into PCM samples.
+// ErrNotDecodable is returned if the wem's codec is not supported.
+func Decode(r io.ReaderAt, size int64) (*DecodedWem, error) {
+	hdr, err := ReadWemHeader(r, size)
+	if err != nil {
+		return nil, err
+	}
+	data := hdr.Chunk(dataChunkId)
+	if data == nil {
+		return nil, errors.New("The wem has no data chunk")
+	}
+	if data.End() > size {
+		return nil, fmt.Errorf("The data chunk at offset 0x%X runs past the end "+
+			"of the wem", data.Offset)
+	}
+
+	codec := hdr.Codec()
+	if codec != PCMCodec && codec != ADPCMCodec {
+		return nil, ErrNotDecodable
+	}
+	if hdr.Format.Channels == 0 {
+		return nil, errors.New("The wem has no channels")
+	}
+
+	bs := make([]byte, data.Length)
+	_, err = r.ReadAt(bs, data.DataOffset())
+	if err != nil {
+		return nil, err
+	}
+
+	dec := &DecodedWem{int(hdr.Format.SampleRate),
+		make([][]float64, hdr.Format.Channels)}
+	if codec == PCMCodec {
+		err = dec.decodePCM(bs, hdr)
+	} else {
+		err = dec.decodeIMA(bs, hdr)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return dec, nil
+}
+
+// decodePCM decodes the interleaved integer PCM samples in bs.
+func (dec *DecodedWem) decodePCM(bs []byte, hdr *WemHeader) error {
+	bits := int(hdr.Format.BitsPerSample)
+	if bits != 8 && bits != 16 && bits != 24 && bits != 32 {
+		return fmt.Errorf("%d-bit PCM wems are not supported", bits)
+	}
+	width := bits / 8
+	channels := len(dec.Channels)
+	frames := len(bs) / (width * channels)
+	scale := float64(int64(1) << uint(bits-1))
+	bigEndian := hdr.ByteOrder == binary.BigEndian
+
+	for i := 0; i < frames; i++ {
+		for c := 0; c < channels; c++ {
+			sample := bs[(i*channels+c)*width:][:width]
+			var v int64
+			switch {
+			case width == 1:
+				// 8-bit PCM is unsigned.
+				v = int64(sample[0]) - 128
+			case bigEndian:
+				v = int64(int8(sample[0]))
+				for _, b := range sample[1:] {
+					v = v<<8 | int64(b)
+				}
+			default:
+				v = int64(int8(sample[width-1]))
+				for j := width - 2; j >= 0; j-- {
+					v = v<<8 | int64(sample[j])
+				}
+			}
+			dec.Channels[c] = append(dec.Channels[c], float64(v)/scale)
+		}
+	}
+	return nil
+}
+
+// decodeIMA decodes the Wwise IMA ADPCM blocks in bs. Unlike Microsoft IMA
+// ADPCM, each block stores the header of every channel first, followed by all
+// of the nibbles of each channel in turn.
+func (dec *DecodedWem) decodeIMA(bs []byte, hdr *WemHeader) error {
+	channels := len(dec.Channels)
+	blockAlign := int(hdr.Format.BlockAlign)
+	headers := IMA_CHANNEL_HEADER_BYTES * channels
+	if blockAlign <= headers {
+		return fmt.Errorf("An ADPCM block of %d bytes is too small for %d "+
+			"channel(s)", blockAlign, channels)
+	}
+	channelBytes := (blockAlign - headers) / channels
+	blockSamples := channelBytes*2 + 1
+
+	// A trailing partial block is ignored.
+	for block := 0; block+blockAlign <= len(bs); block += blockAlign {
+		for c := 0; c < channels; c++ {
+			h := bs[block+c*IMA_CHANNEL_HEADER_BYTES:]
+			hist := int32(int16(hdr.ByteOrder.Uint16(h[0:2])))
+			index := int32(h[2])
+			if index > 88 {
+				index = 88
+			}
+			out := append(dec.Channels[c], float64(hist)/32768)
+
+			nibbles := bs[block+headers+c*channelBytes:][:channelBytes]
+			for i := 1; i < blockSamples; i++ {
+				// The low nibble of each byte is decoded first.
+				nibble := int32(nibbles[(i-1)/2]>>(uint((i-1)%2)*4)) & 0xF
+				step := imaStepTable[index]
+				delta := step >> 3
+				if nibble&1 != 0 {
+					delta += step >> 2
+				}
+				if nibble&2 != 0 {
+					delta += step >> 1
+				}
+				if nibble&4 != 0 {
+					delta += step
+				}
+				if nibble&8 != 0 {
+					delta = -delta
+				}
+				hist += delta
+				if hist > 32767 {
+					hist = 32767
+				} else if hist < -32768 {
+					hist = -32768
+				}
+				index += imaIndexTable[nibble&7]
+				if index < 0 {
+					index = 0
+				} else if index > 88 {
+					index = 88
+				}
+				out = append(out, float64(hist)/32768)
+			}
+			dec.Channels[c] = out
+		}
+	}
+	return nil
+}
@@ -0,0 +1,338 @@
+// Package wwise implements access and modification iterfaces and functions to
+// common WWise container formats.
+package wwise
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// The kinds of change reported by a ContainerDiff.
+const (
+	Added   = "added"
+	Removed = "removed"
+	Changed = "changed"
+)
+
+// A ContainerDiff describes the differences between two versions of a
+// container, such as a SoundBank before and after a game patch.
+type ContainerDiff struct {
+	// The number of bytes the old and new containers take up.
+	OldSize int64 `json:"old_size"`
+	NewSize int64 `json:"new_size"`
+	// Every section that was added, removed or resized.
+	Sections []*SectionDiff `json:"sections"`
+	// Every wem that was added, removed or changed, ordered by ID.
+	Wems []*WemDiff `json:"wems"`
+	// Every HIRC object that was added, removed or changed, ordered by ID.
+	Objects []*ObjectDiff `json:"objects"`
+	// The number of HIRC objects of each type that were added, removed or
+	// changed, ordered by type.
+	ObjectTypes []*ObjectTypeDiff `json:"object_types"`
+}
+
+// A SectionDiff describes how a single section differs between containers.
+type SectionDiff struct {
+	Id string `json:"id"`
+	// Either Added, Removed or Changed.
+	Change    string `json:"change"`
+	OldLength uint32 `json:"old_length"`
+	NewLength uint32 `json:"new_length"`
+}
+
+// A WemDiff describes how a single wem differs between containers. Wems are
+// matched by their ID and language.
+type WemDiff struct {
+	Id       uint32 `json:"id"`
+	Language string `json:"language,omitempty"`
+	// Either Added, Removed or Changed.
+	Change string `json:"change"`
+	// The wem in the old and new containers. Old is nil if the wem was added,
+	// and New is nil if it was removed.
+	Old *WemVersion `json:"old,omitempty"`
+	New *WemVersion `json:"new,omitempty"`
+}
+
+// A WemVersion describes a wem as it is stored in a single container.
+type WemVersion struct {
+	// The index, where 1 is the first wem, of this wem in the container.
+	Index  int    `json:"index"`
+	Length uint32 `json:"length"`
+	// The SHA-256 hash, in hexadecimal, of the contents of this wem.
+	Hash string     `json:"hash"`
+	Loop *LoopValue `json:"loop,omitempty"`
+}
+
+// An ObjectDiff describes how a single HIRC object differs between
+// containers. Objects are matched by their ID and type.
+type ObjectDiff struct {
+	Id       uint32 `json:"id"`
+	TypeName string `json:"type_name"`
+	// Either Added, Removed or Changed.
+	Change    string `json:"change"`
+	OldLength uint32 `json:"old_length,omitempty"`
+	NewLength uint32 `json:"new_length,omitempty"`
+}
+
+// An ObjectTypeDiff counts the HIRC objects of a single type that differ
+// between containers.
+type ObjectTypeDiff struct {
+	Type     byte   `json:"type"`
+	TypeName string `json:"type_name"`
+	Added    int    `json:"added"`
+	Removed  int    `json:"removed"`
+	Changed  int    `json:"changed"`
+}
+
+// A wemKey identifies a wem across containers.
+type wemKey struct {
+	id       uint32
+	language string
+}
+
+// An objectKey identifies a HIRC object across containers.
+type objectKey struct {
+	id  uint32
+	typ byte
+}
+
+// Diff compares the container a with the container b, which is treated as the
+// newer of the two. Wems are compared by the hash of their contents.
+func Diff(a, b Container) (*ContainerDiff, error) {
+	from, to := a.Listing(), b.Listing()
+	d := &ContainerDiff{OldSize: from.Size, NewSize: to.Size}
+	d.diffSections(from, to)
+
+	oldWems, err := wemVersions(a, from)
+	if err != nil {
+		return nil, err
+	}
+	newWems, err := wemVersions(b, to)
+	if err != nil {
+		return nil, err
+	}
+	for key, org := range oldWems {
+		wem, ok := newWems[key]
+		switch {
+		case !ok:
+			d.Wems = append(d.Wems,
+				&WemDiff{key.id, key.language, Removed, org, nil})
+		case org.Hash != wem.Hash || !equalLoops(org.Loop, wem.Loop):
+			d.Wems = append(d.Wems,
+				&WemDiff{key.id, key.language, Changed, org, wem})
+		}
+	}
+	for key, wem := range newWems {
+		if _, ok := oldWems[key]; !ok {
+			d.Wems = append(d.Wems,
+				&WemDiff{key.id, key.language, Added, nil, wem})
+		}
+	}
+	sort.Slice(d.Wems, func(i, j int) bool {
+		if d.Wems[i].Id != d.Wems[j].Id {
+			return d.Wems[i].Id < d.Wems[j].Id
+		}
+		return d.Wems[i].Language < d.Wems[j].Language
+	})
+
+	d.diffObjects(from, to)
+	return d, nil
+}
+
+// diffSections adds every section that differs between the listings from and
+// to. Sections are matched by their ID.
+func (d *ContainerDiff) diffSections(from, to *Listing) {
+	newLengths := make(map[string]uint32)
+	for _, sec := range to.Sections {
+		newLengths[sec.Id] = sec.Length
+	}
+	oldLengths := make(map[string]uint32)
+	for _, sec := range from.Sections {
+		oldLengths[sec.Id] = sec.Length
+		length, ok := newLengths[sec.Id]
+		switch {
+		case !ok:
+			d.Sections = append(d.Sections,
+				&SectionDiff{sec.Id, Removed, sec.Length, 0})
+		case length != sec.Length:
+			d.Sections = append(d.Sections,
+				&SectionDiff{sec.Id, Changed, sec.Length, length})
+		}
+	}
+	for _, sec := range to.Sections {
+		if _, ok := oldLengths[sec.Id]; !ok {
+			d.Sections = append(d.Sections,
+				&SectionDiff{sec.Id, Added, 0, sec.Length})
+		}
+	}
+}
+
+// diffObjects adds every HIRC object that differs between the listings from
+// and to, along with the counts of each type.
+func (d *ContainerDiff) diffObjects(from, to *Listing) {
+	oldObjects := make(map[objectKey]*ObjectListing)
+	for _, obj := range from.Objects {
+		oldObjects[objectKey{obj.Id, obj.Type}] = obj
+	}
+	newObjects := make(map[objectKey]*ObjectListing)
+	for _, obj := range to.Objects {
+		newObjects[objectKey{obj.Id, obj.Type}] = obj
+	}
+
+	types := make(map[byte]*ObjectTypeDiff)
+	count := func(obj *ObjectListing) *ObjectTypeDiff {
+		t, ok := types[obj.Type]
+		if !ok {
+			t = &ObjectTypeDiff{Type: obj.Type, TypeName: obj.TypeName}
+			types[obj.Type] = t
+		}
+		return t
+	}
+	for key, org := range oldObjects {
+		obj, ok := newObjects[key]
+		switch {
+		case !ok:
+			d.Objects = append(d.Objects,
+				&ObjectDiff{org.Id, org.TypeName, Removed, org.Length, 0})
+			count(org).Removed++
+		case org.Hash != obj.Hash:
+			d.Objects = append(d.Objects,
+				&ObjectDiff{org.Id, org.TypeName, Changed, org.Length, obj.Length})
+			count(org).Changed++
+		}
+	}
+	for key, obj := range newObjects {
+		if _, ok := oldObjects[key]; !ok {
+			d.Objects = append(d.Objects,
+				&ObjectDiff{obj.Id, obj.TypeName, Added, 0, obj.Length})
+			count(obj).Added++
+		}
+	}
+	sort.Slice(d.Objects, func(i, j int) bool {
+		if d.Objects[i].Id != d.Objects[j].Id {
+			return d.Objects[i].Id < d.Objects[j].Id
+		}
+		return d.Objects[i].TypeName < d.Objects[j].TypeName
+	})
+	for _, t := range types {
+		d.ObjectTypes = append(d.ObjectTypes, t)
+	}
+	sort.Slice(d.ObjectTypes, func(i, j int) bool {
+		return d.ObjectTypes[i].Type < d.ObjectTypes[j].Type
+	})
+}
+
+// wemVersions returns a description of every wem of ctn, whose listing is l,
+// by its ID and language. If more than one wem shares an ID and language, the
+// first is used.
+func wemVersions(ctn Container, l *Listing) (map[wemKey]*WemVersion, error) {
+	versions := make(map[wemKey]*WemVersion)
+	for i, wem := range ctn.Wems() {
+		listed := l.Wems[i]
+		key := wemKey{listed.Id, listed.Language}
+		if _, ok := versions[key]; ok {
+			continue
+		}
+		hash, err := HashWem(wem)
+		if err != nil {
+			return nil, fmt.Errorf("Could not read wem %d: %s", i+1, err)
+		}
+		versions[key] = &WemVersion{listed.Index, listed.Length, hash,
+			listed.Loop}
+	}
+	return versions, nil
+}
+
+// HashWem returns the SHA-256 hash, in hexadecimal, of the contents of wem.
+func HashWem(wem *Wem) (string, error) {
+	h := sha256.New()
+	_, err := io.Copy(h, io.NewSectionReader(wem, 0,
+		int64(wem.Descriptor.Length)))
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func equalLoops(a, b *LoopValue) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// Empty returns true if there are no differences between the containers.
+func (d *ContainerDiff) Empty() bool {
+	return d.OldSize == d.NewSize && len(d.Sections) == 0 &&
+		len(d.Wems) == 0 && len(d.Objects) == 0
+}
+
+func (d *ContainerDiff) String() string {
+	b := new(strings.Builder)
+
+	fmt.Fprintf(b, "Size: %d -> %d bytes (%+d)\n", d.OldSize, d.NewSize,
+		d.NewSize-d.OldSize)
+	for _, sec := range d.Sections {
+		fmt.Fprintf(b, "%s: %s, len(%d) -> len(%d) (%+d)\n", sec.Id, sec.Change,
+			sec.OldLength, sec.NewLength,
+			int64(sec.NewLength)-int64(sec.OldLength))
+	}
+
+	if len(d.Wems) == 0 {
+		fmt.Fprintln(b, "No wems were added, removed or changed")
+	} else {
+		tableParams := []string{"%-15", "%-9", "%-10", "%-15", "%-23", "%-25",
+			"%s\n"}
+		titleFmt := strings.Join(tableParams, "s|")
+		title := fmt.Sprintf(titleFmt, "Id", "Language", "Change", "Index",
+			"Length", "Loop", "Hash")
+		fmt.Fprint(b, title)
+		fmt.Fprintln(b, strings.Repeat("-", len(title)-1))
+		for _, wem := range d.Wems {
+			fmt.Fprintf(b, titleFmt, fmt.Sprint(wem.Id), wem.Language, wem.Change,
+				wem.field(func(v *WemVersion) string { return fmt.Sprint(v.Index) }),
+				wem.field(func(v *WemVersion) string { return fmt.Sprint(v.Length) }),
+				wem.field(func(v *WemVersion) string {
+					if v.Loop == nil {
+						return ""
+					}
+					return v.Loop.String()
+				}),
+				wem.field(func(v *WemVersion) string { return v.Hash[:12] }))
+		}
+	}
+
+	if len(d.ObjectTypes) == 0 {
+		fmt.Fprintln(b, "No HIRC objects were added, removed or changed")
+		return b.String()
+	}
+	for _, t := range d.ObjectTypes {
+		fmt.Fprintf(b, "%s objects: %d added, %d removed, %d changed\n",
+			t.TypeName, t.Added, t.Removed, t.Changed)
+	}
+	for _, obj := range d.Objects {
+		fmt.Fprintf(b, "  %s %d: %s, len(%d) -> len(%d)\n", obj.TypeName, obj.Id,
+			obj.Change, obj.OldLength, obj.NewLength)
+	}
+	return b.String()
+}
+
+// field formats a field of the old and new versions of this wem with format,
+// showing both if the wem was changed.
+func (wem *WemDiff) field(format func(v *WemVersion) string) string {
+	switch {
+	case wem.Old == nil:
+		return format(wem.New)
+	case wem.New == nil:
+		return format(wem.Old)
+	}
+	from, to := format(wem.Old), format(wem.New)
+	if from == to {
+		return from
+	}
+	return from + " -> " + to
+}
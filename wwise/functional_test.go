@@ -0,0 +1,594 @@
+// Package wwise implements access and modification iterfaces and functions to
+// common WWise container formats.
+package wwise
+
+// Large system tests for the wwise package, run against synthetic wems.
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"testing"
+)
+
+// The sample rate of the synthetic wems.
+const testSampleRate = 48000
+
+// The largest difference, in dB or LU, allowed between a measured and an
+// expected level.
+const levelTolerance = 0.1
+
+// The largest difference allowed between the integrated loudness of a tone and
+// of the same tone followed by silence.
+const gatedTolerance = 0.3
+
+// The largest difference allowed between the levels of a wem encoded as IMA
+// ADPCM and of the same samples stored as PCM.
+const adpcmTolerance = 0.5
+
+// The number of bytes of a single block of a synthetic mono ADPCM wem.
+const testADPCMBlockAlign = 0x24
+
+// A testChunk is a single chunk of a synthetic wem.
+type testChunk struct {
+	id   string
+	data []byte
+}
+
+func TestLoudnessOfSine(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		channels int
+		// The expected integrated loudness. A 1 kHz sine with a peak of 0 dBFS
+		// in a single channel measures -3.01 LUFS.
+		integrated float64
+	}{
+		{"Mono", 1, -3.01 - 6.02},
+		{"Stereo", 2, -6.02},
+	} {
+		channels := make([][]float64, test.channels)
+		for c := range channels {
+			channels[c] = sine(1000, 0.5, 3)
+		}
+		l := analyze(t, pcmWem(binary.LittleEndian, 16, channels))
+		assertLevel(t, test.name+" peak", l.Peak, -6.02, levelTolerance)
+		assertLevel(t, test.name+" RMS", l.RMS, -9.03, levelTolerance)
+		assertLevel(t, test.name+" integrated loudness", l.Integrated,
+			test.integrated, levelTolerance)
+		assertLevel(t, test.name+" duration", l.Duration, 3, 1e-9)
+	}
+}
+
+func TestLoudnessGatesSilence(t *testing.T) {
+	tone := sine(1000, 0.5, 3)
+	l := analyze(t, pcmWem(binary.LittleEndian, 16, [][]float64{tone}))
+
+	// Silence quarters the mean square power, but is dropped by the absolute
+	// gate of the integrated loudness. Only the few blocks overlapping the end
+	// of the tone, which are partly silent, lower it.
+	withSilence := append(append([]float64(nil), tone...),
+		make([]float64, 3*len(tone))...)
+	gated := analyze(t, pcmWem(binary.LittleEndian, 16,
+		[][]float64{withSilence}))
+	assertLevel(t, "RMS", gated.RMS, l.RMS-6.02, levelTolerance)
+	assertLevel(t, "integrated loudness", gated.Integrated, l.Integrated,
+		gatedTolerance)
+
+	silent := analyze(t, pcmWem(binary.LittleEndian, 16,
+		[][]float64{make([]float64, len(tone))}))
+	if silent.Peak != SilenceLevel || silent.Integrated != SilenceLevel {
+		t.Errorf("Expected silence to measure %g but got a peak of %g and an "+
+			"integrated loudness of %g", SilenceLevel, silent.Peak,
+			silent.Integrated)
+	}
+}
+
+func TestLoudnessOfADPCM(t *testing.T) {
+	tone := sine(1000, 0.5, 3)
+	pcm := analyze(t, pcmWem(binary.LittleEndian, 16, [][]float64{tone}))
+	adpcm := analyze(t, adpcmWem(tone))
+	assertLevel(t, "peak", adpcm.Peak, pcm.Peak, adpcmTolerance)
+	assertLevel(t, "RMS", adpcm.RMS, pcm.RMS, adpcmTolerance)
+	assertLevel(t, "integrated loudness", adpcm.Integrated, pcm.Integrated,
+		adpcmTolerance)
+}
+
+func TestDecodePCMSampleFormats(t *testing.T) {
+	tone := sine(440, 0.25, 0.1)
+	for _, test := range []struct {
+		name  string
+		order binary.ByteOrder
+		bits  int
+	}{
+		{"8-bit", binary.LittleEndian, 8},
+		{"16-bit", binary.LittleEndian, 16},
+		{"24-bit", binary.LittleEndian, 24},
+		{"32-bit", binary.LittleEndian, 32},
+		{"16-bit RIFX", binary.BigEndian, 16},
+	} {
+		wem := pcmWem(test.order, test.bits, [][]float64{tone})
+		dec, err := Decode(bytes.NewReader(wem), int64(len(wem)))
+		if err != nil {
+			t.Errorf("%s: %s", test.name, err)
+			continue
+		}
+		if len(dec.Channels) != 1 || len(dec.Channels[0]) != len(tone) {
+			t.Errorf("%s: expected a single channel of %d samples", test.name,
+				len(tone))
+			continue
+		}
+		// Quantization loses up to half of a step of the sample width.
+		tolerance := 1 / float64(int64(1)<<uint(test.bits-1))
+		for i, s := range dec.Channels[0] {
+			if math.Abs(s-tone[i]) > tolerance {
+				t.Errorf("%s: expected sample %d to be %g but got %g", test.name,
+					i, tone[i], s)
+				break
+			}
+		}
+	}
+}
+
+func TestCompareLoudness(t *testing.T) {
+	loud := analyze(t, pcmWem(binary.LittleEndian, 16,
+		[][]float64{sine(1000, 0.5, 3)}))
+	quiet := analyze(t, pcmWem(binary.LittleEndian, 16,
+		[][]float64{sine(1000, 0.25, 3)}))
+	cmp := CompareLoudness(loud, quiet)
+	if cmp.Original != loud || cmp.Replacement != quiet {
+		t.Error("Expected the comparison to hold both measurements")
+	}
+	assertLevel(t, "peak difference", cmp.PeakDifference, -6.02,
+		levelTolerance)
+	assertLevel(t, "RMS difference", cmp.RMSDifference, -6.02, levelTolerance)
+	assertLevel(t, "integrated difference", cmp.IntegratedDifference, -6.02,
+		levelTolerance)
+}
+
+func TestDecodeRejectsUnsupportedCodecs(t *testing.T) {
+	wem := buildWem(binary.LittleEndian,
+		fmtChunk(binary.LittleEndian, WemFormat{0xFFFF, 1, testSampleRate, 0, 0,
+			0}),
+		testChunk{"data", make([]byte, 16)})
+	_, err := AnalyzeLoudness(bytes.NewReader(wem), int64(len(wem)))
+	if err != ErrNotDecodable {
+		t.Errorf("Expected a Vorbis wem to be reported as not decodable but "+
+			"got %v", err)
+	}
+}
+
+func TestExtractOpus(t *testing.T) {
+	// A 20ms CELT frame, two 20ms frames and three 20ms frames, the second
+	// spanning two Ogg segments.
+	packets := [][]byte{
+		append([]byte{0xF8}, bytes.Repeat([]byte{1}, 40)...),
+		append([]byte{0xF9}, bytes.Repeat([]byte{2}, 300)...),
+		append([]byte{0xFB, 0x03}, bytes.Repeat([]byte{3}, 90)...),
+	}
+	const skip, trimmed = 312, 100
+	sampleCount := 960 + 2*960 + 3*960 - trimmed
+
+	order := binary.BigEndian
+	format := fmtChunk(order, WemFormat{0x3040, 2, testSampleRate, 0, 0, 0})
+	extra := make([]byte, opusSkipOffset+2-FMT_CHUNK_MIN_BYTES)
+	order.PutUint32(extra[opusSampleCountOffset-FMT_CHUNK_MIN_BYTES:],
+		uint32(sampleCount))
+	order.PutUint32(extra[opusTableCountOffset-FMT_CHUNK_MIN_BYTES:],
+		uint32(len(packets)))
+	order.PutUint16(extra[opusSkipOffset-FMT_CHUNK_MIN_BYTES:], skip)
+	format.data = append(format.data, extra...)
+	seek, data := new(bytes.Buffer), new(bytes.Buffer)
+	for _, p := range packets {
+		binary.Write(seek, order, uint16(len(p)))
+		data.Write(p)
+	}
+	wem := buildWem(order, format, testChunk{"seek", seek.Bytes()},
+		testChunk{"data", data.Bytes()})
+
+	out := new(bytes.Buffer)
+	written, err := Extract(out, bytes.NewReader(wem), int64(len(wem)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if written != int64(out.Len()) {
+		t.Errorf("%d bytes were written, but %d bytes were reported to be "+
+			"written", out.Len(), written)
+	}
+
+	pages := readOggPages(t, out.Bytes())
+	if len(pages) != 2+len(packets) {
+		t.Fatalf("Expected %d Ogg pages but got %d", 2+len(packets), len(pages))
+	}
+	head := pages[0].packet
+	if !bytes.HasPrefix(head, []byte("OpusHead")) || head[9] != 2 ||
+		binary.LittleEndian.Uint16(head[10:]) != skip {
+		t.Errorf("Expected an OpusHead packet for 2 channels skipping %d "+
+			"samples but got % X", skip, head)
+	}
+	if !bytes.HasPrefix(pages[1].packet, []byte("OpusTags")) {
+		t.Errorf("Expected an OpusTags packet but got % X", pages[1].packet)
+	}
+	granules := []int64{0, 0, 960, 960 + 2*960, skip + int64(sampleCount)}
+	flags := []byte{oggFirstPage, 0, 0, 0, oggLastPage}
+	for i, page := range pages {
+		if page.seq != uint32(i) {
+			t.Errorf("Page %d: expected the sequence number %d but got %d", i, i,
+				page.seq)
+		}
+		if page.granule != granules[i] || page.flags != flags[i] {
+			t.Errorf("Page %d: expected the granule %d and flags %d but got %d "+
+				"and %d", i, granules[i], flags[i], page.granule, page.flags)
+		}
+		if i >= 2 && !bytes.Equal(page.packet, packets[i-2]) {
+			t.Errorf("Page %d: expected packet %d to be copied as is", i, i-2)
+		}
+	}
+}
+
+func TestExtractXMA2(t *testing.T) {
+	f := xma2Format{WemFormat: WemFormat{0x0166, 2, testSampleRate, 12000,
+		2048, 16}, ExtraLength: 34, NumStreams: 1, ChannelMask: 3,
+		SamplesEncoded: 96000, BytesPerBlock: 0x10000, PlayLength: 96000,
+		LoopBegin: 1024, LoopLength: 4096, LoopCount: 255, EncoderVersion: 4,
+		BlockCount: 1}
+	format := new(bytes.Buffer)
+	binary.Write(format, binary.BigEndian, f)
+	// The seek table has an odd length, which must be padded when copied.
+	seek := []byte{1, 2, 3, 4, 5}
+	data := bytes.Repeat([]byte{0xA5, 0x5A}, 64)
+	wem := buildWem(binary.BigEndian, testChunk{"fmt ", format.Bytes()},
+		testChunk{"seek", seek}, testChunk{"data", data})
+
+	out := new(bytes.Buffer)
+	written, err := Extract(out, bytes.NewReader(wem), int64(len(wem)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if written != int64(out.Len()) {
+		t.Errorf("%d bytes were written, but %d bytes were reported to be "+
+			"written", out.Len(), written)
+	}
+
+	xma := out.Bytes()
+	hdr, err := ReadWemHeader(bytes.NewReader(xma), int64(len(xma)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hdr.ByteOrder != binary.LittleEndian {
+		t.Error("Expected a little-endian RIFF file")
+	}
+	if int(hdr.Length) != len(xma)-8 {
+		t.Errorf("Expected the RIFF length to be %d but got %d", len(xma)-8,
+			hdr.Length)
+	}
+	fc := hdr.Chunk(fmtChunkId)
+	if fc == nil || fc.Length != XMA2_FORMAT_BYTES {
+		t.Fatalf("Expected a fmt chunk of %d bytes but got %v",
+			XMA2_FORMAT_BYTES, fc)
+	}
+	actual := xma2Format{}
+	binary.Read(bytes.NewReader(xma[fc.DataOffset():fc.End()]),
+		binary.LittleEndian, &actual)
+	if actual != f {
+		t.Errorf("Expected the XMA2 format %+v but got %+v", f, actual)
+	}
+	for _, expect := range []testChunk{{"seek", seek}, {"data", data}} {
+		var id [4]byte
+		copy(id[:], expect.id)
+		c := hdr.Chunk(id)
+		if c == nil || !bytes.Equal(xma[c.DataOffset():c.End()], expect.data) {
+			t.Errorf("Expected the %s chunk to be copied as is", expect.id)
+		}
+	}
+}
+
+// An oggPage is a single page of an Ogg bitstream holding a single packet.
+type oggPage struct {
+	flags   byte
+	granule int64
+	seq     uint32
+	packet  []byte
+}
+
+// readOggPages returns the pages of the Ogg bitstream bs, checking the capture
+// pattern and checksum of each.
+func readOggPages(t *testing.T, bs []byte) []*oggPage {
+	var pages []*oggPage
+	for len(bs) > 0 {
+		if len(bs) < 27 || string(bs[:4]) != "OggS" {
+			t.Fatalf("Page %d: expected the OggS capture pattern", len(pages))
+		}
+		segments := int(bs[26])
+		length := 0
+		for _, l := range bs[27 : 27+segments] {
+			length += int(l)
+		}
+		end := 27 + segments + length
+		page := &oggPage{bs[5], int64(binary.LittleEndian.Uint64(bs[6:])),
+			binary.LittleEndian.Uint32(bs[18:]), bs[27+segments : end]}
+
+		crc := binary.LittleEndian.Uint32(bs[22:])
+		unsummed := append([]byte(nil), bs[:end]...)
+		binary.LittleEndian.PutUint32(unsummed[22:], 0)
+		if expect := oggChecksum(unsummed); crc != expect {
+			t.Errorf("Page %d: expected the checksum 0x%08X but got 0x%08X",
+				len(pages), expect, crc)
+		}
+		pages = append(pages, page)
+		bs = bs[end:]
+	}
+	return pages
+}
+
+// oggChecksum computes the CRC-32 of an Ogg page bit by bit, without the
+// lookup table that the package uses.
+func oggChecksum(bs []byte) uint32 {
+	crc := uint32(0)
+	for _, b := range bs {
+		crc ^= uint32(b) << 24
+		for i := 0; i < 8; i++ {
+			if crc&0x80000000 != 0 {
+				crc = crc<<1 ^ 0x04C11DB7
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
+
+func TestInspectChunks(t *testing.T) {
+	le := binary.LittleEndian
+	u32s := func(vs ...uint32) []byte {
+		b := new(bytes.Buffer)
+		binary.Write(b, le, vs)
+		return b.Bytes()
+	}
+	// The smpl and cue chunks declare one more entry than they hold, the LIST
+	// chunk ends with a note that runs past its end, and the akd chunk ends
+	// with a partial value.
+	smpl := append(u32s(0, 0, 22675, 60, 0, 0, 0, 2, 0),
+		u32s(1, 0, 100, 4000, 0, 0)...)
+	cue := append(u32s(2), u32s(1, 0)...)
+	cue = append(cue, "data"...)
+	cue = append(cue, u32s(0, 0, 100)...)
+	list := append([]byte("adtl"), "labl"...)
+	list = append(list, u32s(10, 1)...)
+	list = append(list, "intro\x00"...)
+	list = append(list, "note"...)
+	list = append(list, u32s(64)...)
+	akd := append(u32s(math.Float32bits(0.5), math.Float32bits(-3)), 0, 0)
+
+	wem := buildWem(le,
+		fmtChunk(le, WemFormat{0x0001, 1, testSampleRate, 0, 2, 16}),
+		testChunk{"smpl", smpl}, testChunk{"cue ", cue},
+		testChunk{"LIST", list}, testChunk{"akd ", akd},
+		testChunk{"data", make([]byte, 4)})
+	hdr, err := InspectWem(bytes.NewReader(wem), int64(len(wem)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	values := make(map[string]string)
+	for _, c := range hdr.Chunks {
+		for _, f := range c.Fields {
+			values[string(c.Id[:])+"."+f.Name] = f.Value
+		}
+	}
+	for name, expect := range map[string]string{
+		"fmt .FormatTag":    "0x0001 (pcm)",
+		"fmt .SampleRate":   "48000",
+		"smpl.SamplePeriod": "22675",
+		"smpl.LoopCount":    "2",
+		"smpl.Loop[0]": "cue(1) type(0) start(100) end(4000) fraction(0) " +
+			"play_count(0)",
+		"cue .CuePointCount": "2",
+		"cue .CuePoint[0]": "id(1) position(0) chunk(\"data\") chunk_start(0) " +
+			"block_start(0) sample_offset(100)",
+		"LIST.ListType": "\"adtl\"",
+		"LIST.\"labl\"": "cue(1) text(\"intro\")",
+		"LIST.\"note\"": "len(64) runs past the end of the chunk",
+		"akd .Value[0]": "0.5",
+		"akd .Value[1]": "-3",
+	} {
+		if actual, ok := values[name]; !ok || actual != expect {
+			t.Errorf("Expected %s to be %s but got %q", name, expect, actual)
+		}
+	}
+	for _, name := range []string{"smpl.Loop[1]", "cue .CuePoint[1]",
+		"akd .Value[2]"} {
+		if _, ok := values[name]; ok {
+			t.Errorf("Expected %s, which is past the end of its chunk, not to be "+
+				"decoded", name)
+		}
+	}
+}
+
+// analyze measures the loudness of wem, failing t if it cannot be decoded.
+func TestTxtpGroups(t *testing.T) {
+	loop := LoopValue{Loops: false}
+	txtp := &Txtp{
+		Comment: "event 1",
+		Lines: []TxtpLine{
+			&TxtpEntry{Path: `sounds\bank.bnk`, Subsong: 1, Loop: &loop},
+			&TxtpEntry{Path: "sounds/2.wem"},
+			&TxtpGroup{Type: TxtpRandom, Count: 2},
+			&TxtpEntry{Path: "sounds/3.wem"},
+			&TxtpEntry{Path: "sounds/4.wem"},
+			&TxtpGroup{Type: TxtpRandom, Count: 2, Selected: 1},
+			&TxtpGroup{Type: TxtpLayer, Count: 2},
+		},
+	}
+	expected := "# event 1\n" +
+		"sounds/bank.bnk#1 #i\n" +
+		"sounds/2.wem\n" +
+		"group = -R2\n" +
+		"sounds/3.wem\n" +
+		"sounds/4.wem\n" +
+		"group = -R2>1\n" +
+		"group = -L2\n"
+	var b bytes.Buffer
+	n, err := txtp.WriteTo(&b)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	if b.String() != expected || n != int64(b.Len()) {
+		t.Errorf("Expected %d bytes:\n%s\nbut got %d:\n%s", len(expected),
+			expected, n, b.String())
+	}
+}
+
+func analyze(t *testing.T, wem []byte) *Loudness {
+	l, err := AnalyzeLoudness(bytes.NewReader(wem), int64(len(wem)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return l
+}
+
+// assertLevel checks that actual is within tolerance of expect.
+func assertLevel(t *testing.T, name string, actual, expect,
+	tolerance float64) {
+	if math.Abs(actual-expect) > tolerance {
+		t.Errorf("Expected the %s to be %.2f but got %.2f", name, expect, actual)
+	}
+}
+
+// sine returns seconds of a sine wave at the frequency freq with the peak
+// amplitude amplitude, sampled at testSampleRate.
+func sine(freq, amplitude, seconds float64) []float64 {
+	samples := make([]float64, int(seconds*testSampleRate))
+	for i := range samples {
+		samples[i] = amplitude * math.Sin(2*math.Pi*freq*float64(i)/
+			testSampleRate)
+	}
+	return samples
+}
+
+// pcmWem returns a synthetic integer PCM wem of the given sample width holding
+// the samples of each channel.
+func pcmWem(order binary.ByteOrder, bits int, channels [][]float64) []byte {
+	width := bits / 8
+	scale := float64(int64(1)<<uint(bits-1)) - 1
+	data := new(bytes.Buffer)
+	for i := range channels[0] {
+		for _, samples := range channels {
+			v := int64(math.Round(samples[i] * scale))
+			if width == 1 {
+				// 8-bit PCM is unsigned.
+				data.WriteByte(byte(v + 128))
+				continue
+			}
+			sample := make([]byte, width)
+			for j := 0; j < width; j++ {
+				shift := uint(8 * j)
+				if order == binary.BigEndian {
+					shift = uint(8 * (width - 1 - j))
+				}
+				sample[j] = byte(v >> shift)
+			}
+			data.Write(sample)
+		}
+	}
+	align := len(channels) * width
+	format := WemFormat{0x0001, uint16(len(channels)), testSampleRate,
+		uint32(testSampleRate * align), uint16(align), uint16(bits)}
+	return buildWem(order, fmtChunk(order, format),
+		testChunk{"data", data.Bytes()})
+}
+
+// adpcmWem returns a synthetic mono Wwise IMA ADPCM wem encoding samples.
+// Samples that do not fill a whole block are dropped.
+func adpcmWem(samples []float64) []byte {
+	headers := IMA_CHANNEL_HEADER_BYTES
+	blockSamples := (testADPCMBlockAlign-headers)*2 + 1
+	data := new(bytes.Buffer)
+	index := int32(0)
+	for start := 0; start+blockSamples <= len(samples); start += blockSamples {
+		block := make([]byte, testADPCMBlockAlign)
+		hist := int32(math.Round(samples[start] * 32767))
+		binary.LittleEndian.PutUint16(block, uint16(int16(hist)))
+		block[2] = byte(index)
+		for i := 1; i < blockSamples; i++ {
+			var nibble int32
+			hist, index, nibble = encodeIMA(
+				int32(math.Round(samples[start+i]*32767)), hist, index)
+			block[headers+(i-1)/2] |= byte(nibble << (uint((i-1)%2) * 4))
+		}
+		data.Write(block)
+	}
+	format := WemFormat{0x0002, 1, testSampleRate,
+		testSampleRate * testADPCMBlockAlign / uint32(blockSamples),
+		testADPCMBlockAlign, 4}
+	return buildWem(binary.LittleEndian, fmtChunk(binary.LittleEndian, format),
+		testChunk{"data", data.Bytes()})
+}
+
+// encodeIMA returns the IMA ADPCM nibble that best approaches the sample
+// target from the previous sample hist with the step index index, and the
+// sample and step index that decoding the nibble results in.
+func encodeIMA(target, hist, index int32) (int32, int32, int32) {
+	step := imaStepTable[index]
+	diff := target - hist
+	var nibble int32
+	if diff < 0 {
+		nibble, diff = 8, -diff
+	}
+	for bit, s := int32(4), step; bit > 0; bit, s = bit>>1, s>>1 {
+		if diff >= s {
+			nibble |= bit
+			diff -= s
+		}
+	}
+
+	// Decode the nibble the way that decodeIMA does, so that the encoder does
+	// not drift from the decoder.
+	delta := step >> 3
+	if nibble&1 != 0 {
+		delta += step >> 2
+	}
+	if nibble&2 != 0 {
+		delta += step >> 1
+	}
+	if nibble&4 != 0 {
+		delta += step
+	}
+	if nibble&8 != 0 {
+		delta = -delta
+	}
+	hist = int32(math.Max(-32768, math.Min(32767, float64(hist+delta))))
+	index += imaIndexTable[nibble&7]
+	index = int32(math.Max(0, math.Min(88, float64(index))))
+	return hist, index, nibble
+}
+
+// fmtChunk returns the fmt chunk describing format.
+func fmtChunk(order binary.ByteOrder, format WemFormat) testChunk {
+	b := new(bytes.Buffer)
+	binary.Write(b, order, format)
+	return testChunk{"fmt ", b.Bytes()}
+}
+
+// buildWem returns a synthetic wem holding chunks. A RIFX wem is returned if
+// order is binary.BigEndian.
+func buildWem(order binary.ByteOrder, chunks ...testChunk) []byte {
+	body := new(bytes.Buffer)
+	body.WriteString("WAVE")
+	for _, c := range chunks {
+		body.WriteString(c.id)
+		binary.Write(body, order, uint32(len(c.data)))
+		body.Write(c.data)
+		if len(c.data)%2 != 0 {
+			body.WriteByte(0)
+		}
+	}
+	wem := new(bytes.Buffer)
+	if order == binary.BigEndian {
+		wem.WriteString("RIFX")
+	} else {
+		wem.WriteString("RIFF")
+	}
+	binary.Write(wem, order, uint32(body.Len()))
+	wem.Write(body.Bytes())
+	return wem.Bytes()
+}
@@ -0,0 +1,305 @@
+// Package wwise implements access and modification iterfaces and functions to
+// common WWise container formats.
+package wwise
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+)
+
+// The offset into the fmt chunk data where Vorbis setup information is
+// embedded, for wems with no separate vorb chunk.
+const vorbisFmtVorbOffset = 0x18
+
+// The length of the vorb data used by Wwise 2012 and later.
+const vorbModernBytes = 0x2A
+
+// The number of bytes used to describe the fixed portion of a smpl chunk.
+const SMPL_HEADER_BYTES = 36
+
+// The number of bytes used to describe a single loop within a smpl chunk.
+const SMPL_LOOP_BYTES = 24
+
+// The number of bytes used to describe a single cue point within a cue chunk.
+const CUE_POINT_BYTES = 24
+
+// The identifier for the chunk describing loop points.
+var smplChunkId = [4]byte{'s', 'm', 'p', 'l'}
+
+// The identifier for the chunk describing cue points.
+var cueChunkId = [4]byte{'c', 'u', 'e', ' '}
+
+// The identifier for the chunk holding Wwise analysis data.
+var akdChunkId = [4]byte{'a', 'k', 'd', ' '}
+
+// The identifier for a chunk holding a list of sub-chunks, such as labels.
+var listChunkId = [4]byte{'L', 'I', 'S', 'T'}
+
+// The identifier for a chunk holding padding.
+var junkChunkId = [4]byte{'J', 'U', 'N', 'K'}
+
+// A ChunkField is a single decoded value within a RIFF chunk.
+type ChunkField struct {
+	Name string
+	// The number of bytes from the start of the wem that this value begins.
+	Offset int64
+	Value  string
+}
+
+// A chunkDecoder decodes the fields of a chunk from its data, which begins at
+// the offset base into the wem.
+type chunkDecoder struct {
+	data   []byte
+	base   int64
+	order  binary.ByteOrder
+	fields []*ChunkField
+}
+
+// InspectWem reads the RIFF structure of the wem stored in the first size bytes
+// of r, and decodes the fields of every known chunk. Chunks that run past the
+// end of the wem are listed, but are not decoded.
+func InspectWem(r io.ReaderAt, size int64) (*WemHeader, error) {
+	hdr, err := ReadWemHeader(r, size)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, c := range hdr.Chunks {
+		if c.End() > size || c.Id == dataChunkId {
+			continue
+		}
+		data := make([]byte, c.Length)
+		_, err := r.ReadAt(data, c.DataOffset())
+		if err != nil {
+			return nil, err
+		}
+		d := &chunkDecoder{data: data, base: c.DataOffset(), order: hdr.ByteOrder}
+		switch c.Id {
+		case fmtChunkId:
+			d.decodeFmt(hdr.Codec())
+		case vorbChunkId:
+			d.decodeVorb(0, len(data))
+		case smplChunkId:
+			d.decodeSmpl()
+		case cueChunkId:
+			d.decodeCue()
+		case akdChunkId:
+			d.decodeAkd()
+		case listChunkId:
+			d.decodeList()
+		case junkChunkId:
+			d.decodeJunk()
+		}
+		c.Fields = d.fields
+	}
+
+	return hdr, nil
+}
+
+// has returns true if n bytes of data are available at off.
+func (d *chunkDecoder) has(off, n int) bool {
+	return off >= 0 && off+n <= len(d.data)
+}
+
+func (d *chunkDecoder) add(name string, off int, format string,
+	a ...interface{}) {
+	d.fields = append(d.fields,
+		&ChunkField{name, d.base + int64(off), fmt.Sprintf(format, a...)})
+}
+
+func (d *chunkDecoder) u8(name string, off int) {
+	if d.has(off, 1) {
+		d.add(name, off, "%d", d.data[off])
+	}
+}
+
+func (d *chunkDecoder) u16(name string, off int) {
+	if d.has(off, 2) {
+		d.add(name, off, "%d", d.order.Uint16(d.data[off:]))
+	}
+}
+
+func (d *chunkDecoder) u32(name string, off int) {
+	if d.has(off, 4) {
+		d.add(name, off, "%d", d.order.Uint32(d.data[off:]))
+	}
+}
+
+func (d *chunkDecoder) hex32(name string, off int) {
+	if d.has(off, 4) {
+		d.add(name, off, "0x%08X", d.order.Uint32(d.data[off:]))
+	}
+}
+
+func (d *chunkDecoder) fourcc(name string, off int) {
+	if d.has(off, 4) {
+		d.add(name, off, "%q", d.data[off:off+4])
+	}
+}
+
+func (d *chunkDecoder) decodeFmt(codec Codec) {
+	if !d.has(0, 2) {
+		return
+	}
+	d.add("FormatTag", 0, "0x%04X (%s)", d.order.Uint16(d.data), codec)
+	d.u16("Channels", 0x02)
+	d.u32("SampleRate", 0x04)
+	d.u32("AvgBytesPerSecond", 0x08)
+	d.u16("BlockAlign", 0x0C)
+	d.u16("BitsPerSample", 0x0E)
+	d.u16("ExtraLength", 0x10)
+
+	switch codec {
+	case XMA2Codec:
+		d.u16("NumStreams", 0x12)
+		d.hex32("ChannelMask", 0x14)
+		d.u32("SamplesEncoded", 0x18)
+		d.u32("BytesPerBlock", 0x1C)
+		d.u32("PlayBegin", 0x20)
+		d.u32("PlayLength", 0x24)
+		d.u32("LoopBegin", 0x28)
+		d.u32("LoopLength", 0x2C)
+		d.u8("LoopCount", 0x30)
+		d.u8("EncoderVersion", 0x31)
+		d.u16("BlockCount", 0x32)
+		return
+	}
+
+	d.u16("SamplesPerBlock", 0x12)
+	if d.has(0x14, 4) {
+		// Wwise stores its own channel configuration in place of the WAVE
+		// extensible channel mask.
+		config := d.order.Uint32(d.data[0x14:])
+		d.add("ChannelConfig", 0x14,
+			"0x%08X (channels: %d, type: %d, mask: 0x%X)", config, config&0xFF,
+			(config>>8)&0xF, config>>12)
+	}
+
+	switch codec {
+	case VorbisCodec:
+		if len(d.data) == vorbisFmtChunkBytes {
+			d.decodeVorb(vorbisFmtVorbOffset, vorbModernBytes)
+		}
+	case OpusCodec:
+		d.u32("SampleCount", opusSampleCountOffset)
+		d.u32("PacketCount", opusTableCountOffset)
+		d.u16("PreSkip", opusSkipOffset)
+	}
+}
+
+// decodeVorb decodes Vorbis setup information of length bytes starting at off.
+func (d *chunkDecoder) decodeVorb(off, length int) {
+	d.u32("SampleCount", off)
+	if length != vorbModernBytes {
+		// Older layouts vary between Wwise versions; only the sample count is
+		// common to all of them.
+		return
+	}
+	d.hex32("ModSignal", off+0x04)
+	d.u32("SetupPacketOffset", off+0x10)
+	d.u32("FirstAudioPacketOffset", off+0x14)
+	d.hex32("Uid", off+0x24)
+	if d.has(off+0x28, 2) {
+		d.add("BlockSizes", off+0x28, "%d, %d", 1<<d.data[off+0x28],
+			1<<d.data[off+0x29])
+	}
+}
+
+func (d *chunkDecoder) decodeSmpl() {
+	d.hex32("Manufacturer", 0x00)
+	d.hex32("Product", 0x04)
+	d.u32("SamplePeriod", 0x08)
+	d.u32("MIDIUnityNote", 0x0C)
+	d.u32("MIDIPitchFraction", 0x10)
+	d.hex32("SMPTEFormat", 0x14)
+	d.hex32("SMPTEOffset", 0x18)
+	d.u32("LoopCount", 0x1C)
+	d.u32("SamplerDataLength", 0x20)
+	if !d.has(0x1C, 4) {
+		return
+	}
+	count := int(d.order.Uint32(d.data[0x1C:]))
+	for i := 0; i < count; i++ {
+		off := SMPL_HEADER_BYTES + i*SMPL_LOOP_BYTES
+		if !d.has(off, SMPL_LOOP_BYTES) {
+			return
+		}
+		loop := d.data[off:]
+		d.add(fmt.Sprintf("Loop[%d]", i), off,
+			"cue(%d) type(%d) start(%d) end(%d) fraction(%d) play_count(%d)",
+			d.order.Uint32(loop[0:]), d.order.Uint32(loop[4:]),
+			d.order.Uint32(loop[8:]), d.order.Uint32(loop[12:]),
+			d.order.Uint32(loop[16:]), d.order.Uint32(loop[20:]))
+	}
+}
+
+func (d *chunkDecoder) decodeCue() {
+	d.u32("CuePointCount", 0)
+	if !d.has(0, 4) {
+		return
+	}
+	count := int(d.order.Uint32(d.data))
+	for i := 0; i < count; i++ {
+		off := 4 + i*CUE_POINT_BYTES
+		if !d.has(off, CUE_POINT_BYTES) {
+			return
+		}
+		cue := d.data[off:]
+		d.add(fmt.Sprintf("CuePoint[%d]", i), off,
+			"id(%d) position(%d) chunk(%q) chunk_start(%d) block_start(%d) "+
+				"sample_offset(%d)", d.order.Uint32(cue[0:]), d.order.Uint32(cue[4:]),
+			cue[8:12], d.order.Uint32(cue[12:]), d.order.Uint32(cue[16:]),
+			d.order.Uint32(cue[20:]))
+	}
+}
+
+// decodeAkd decodes the Wwise analysis data chunk. Its layout is undocumented,
+// but it is made up of 32-bit floating point values, such as the peak and
+// loudness of the wem.
+func (d *chunkDecoder) decodeAkd() {
+	for off := 0; d.has(off, 4); off += 4 {
+		v := math.Float32frombits(d.order.Uint32(d.data[off:]))
+		d.add(fmt.Sprintf("Value[%d]", off/4), off, "%g", v)
+	}
+}
+
+func (d *chunkDecoder) decodeList() {
+	d.fourcc("ListType", 0)
+	for off := 4; d.has(off, CHUNK_HEADER_BYTES); {
+		id := d.data[off : off+4]
+		length := int(d.order.Uint32(d.data[off+4:]))
+		data := off + CHUNK_HEADER_BYTES
+		if !d.has(data, length) {
+			d.add(fmt.Sprintf("%q", id), off, "len(%d) runs past the end of the "+
+				"chunk", length)
+			return
+		}
+		switch string(id) {
+		case "labl", "note":
+			if length >= 4 {
+				text := strings.TrimRight(string(d.data[data+4:data+length]), "\x00")
+				d.add(fmt.Sprintf("%q", id), off, "cue(%d) text(%q)",
+					d.order.Uint32(d.data[data:]), text)
+				break
+			}
+			fallthrough
+		default:
+			d.add(fmt.Sprintf("%q", id), off, "len(%d)", length)
+		}
+		off = data + length + length%2
+	}
+}
+
+func (d *chunkDecoder) decodeJunk() {
+	zeroes := true
+	for _, b := range d.data {
+		if b != 0 {
+			zeroes = false
+			break
+		}
+	}
+	d.add("Padding", 0, "%d bytes (all zero: %t)", len(d.data), zeroes)
+}
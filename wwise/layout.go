@@ -0,0 +1,97 @@
+// Package wwise implements access and modification iterfaces and functions to
+// common WWise container formats.
+package wwise
+
+import (
+	"fmt"
+	"strings"
+)
+
+// A LayoutChange describes how the layout of a container changes when it is
+// modified, such as by ReplaceWems.
+type LayoutChange struct {
+	// The number of bytes the container takes up before and after the change.
+	OldSize int64 `json:"old_size"`
+	NewSize int64 `json:"new_size"`
+	// The changes to the length of every section, including those that did not
+	// change.
+	Sections []*SectionLayoutChange `json:"sections"`
+	// The changes to every wem whose offset, length or padding changed.
+	Wems []*WemLayoutChange `json:"wems"`
+}
+
+// A SectionLayoutChange describes how the length of a single section changes.
+type SectionLayoutChange struct {
+	Id        string `json:"id"`
+	OldLength uint32 `json:"old_length"`
+	NewLength uint32 `json:"new_length"`
+}
+
+// A WemLayoutChange describes how the location of a single wem changes.
+type WemLayoutChange struct {
+	// The index, where 1 is the first wem, of this wem in the container.
+	Index      int    `json:"index"`
+	Id         uint32 `json:"id"`
+	OldOffset  uint32 `json:"old_offset"`
+	NewOffset  uint32 `json:"new_offset"`
+	OldLength  uint32 `json:"old_length"`
+	NewLength  uint32 `json:"new_length"`
+	OldPadding int64  `json:"old_padding"`
+	NewPadding int64  `json:"new_padding"`
+}
+
+// CompareLayouts compares the listings of a single container taken before and
+// after it was modified. Wems and sections are matched by their position, as
+// modifying a container does not reorder them.
+func CompareLayouts(before, after *Listing) *LayoutChange {
+	c := &LayoutChange{OldSize: before.Size, NewSize: after.Size}
+	for i, sec := range before.Sections {
+		if i >= len(after.Sections) {
+			break
+		}
+		c.Sections = append(c.Sections,
+			&SectionLayoutChange{sec.Id, sec.Length, after.Sections[i].Length})
+	}
+	for i, org := range before.Wems {
+		if i >= len(after.Wems) {
+			break
+		}
+		wem := after.Wems[i]
+		if org.Offset == wem.Offset && org.Length == wem.Length &&
+			org.Padding == wem.Padding {
+			continue
+		}
+		c.Wems = append(c.Wems, &WemLayoutChange{org.Index, org.Id,
+			org.Offset, wem.Offset, org.Length, wem.Length, org.Padding,
+			wem.Padding})
+	}
+	return c
+}
+
+func (c *LayoutChange) String() string {
+	b := new(strings.Builder)
+
+	fmt.Fprintf(b, "Size: %d -> %d bytes (%+d)\n", c.OldSize, c.NewSize,
+		c.NewSize-c.OldSize)
+	for _, sec := range c.Sections {
+		fmt.Fprintf(b, "%s: len(%d) -> len(%d) (%+d)\n", sec.Id, sec.OldLength,
+			sec.NewLength, int64(sec.NewLength)-int64(sec.OldLength))
+	}
+	if len(c.Wems) == 0 {
+		fmt.Fprintln(b, "No wems are moved or resized")
+		return b.String()
+	}
+
+	tableParams := []string{"%-7", "%-15", "%-23", "%-19", "%-15", "\n"}
+	titleFmt := strings.Join(tableParams, "s|")
+	title := fmt.Sprintf(titleFmt, "Index", "Id", "Offset", "Length", "Padding")
+	fmt.Fprint(b, title)
+	fmt.Fprintln(b, strings.Repeat("-", len(title)-1))
+	for _, wem := range c.Wems {
+		fmt.Fprintf(b, titleFmt, fmt.Sprint(wem.Index), fmt.Sprint(wem.Id),
+			fmt.Sprintf("%d -> %d", wem.OldOffset, wem.NewOffset),
+			fmt.Sprintf("%d -> %d", wem.OldLength, wem.NewLength),
+			fmt.Sprintf("%d -> %d", wem.OldPadding, wem.NewPadding))
+	}
+	return b.String()
+}
@@ -0,0 +1,166 @@
+// Package wwise implements access and modification iterfaces and functions to
+// common WWise container formats.
+package wwise
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// The names of the tables of a Listing that can be written as CSV.
+const (
+	WemsTable      = "wems"
+	SectionsTable  = "sections"
+	ObjectsTable   = "objects"
+	LanguagesTable = "languages"
+)
+
+// The names of the container types described by a Listing.
+const (
+	SoundBankType   = "SoundBank"
+	FilePackageType = "File Package"
+)
+
+// A Listing is a structured description of the contents of a Container, which
+// can be serialized to JSON or CSV for use by other tools. Fields that do not
+// apply to a container type are left empty.
+type Listing struct {
+	// The type of the container, either SoundBankType or FilePackageType.
+	Type string `json:"type"`
+	// The number of bytes from the start of the file that wem offsets are
+	// relative to.
+	DataStart uint32 `json:"data_start"`
+	// The number of bytes this container takes up when written.
+	Size     int64             `json:"size"`
+	Sections []*SectionListing `json:"sections,omitempty"`
+	Wems     []*WemListing     `json:"wems"`
+	// A summary of every object in the SoundBank HIRC section.
+	Objects   []*ObjectListing   `json:"objects,omitempty"`
+	Languages []*LanguageListing `json:"languages,omitempty"`
+}
+
+// A SectionListing describes a single section of a SoundBank, or the header of
+// a File Package.
+type SectionListing struct {
+	Id string `json:"id"`
+	// The number of bytes from the start of the file that this section's header
+	// begins.
+	Offset int64 `json:"offset"`
+	// The length in bytes of this section, excluding its header.
+	Length uint32 `json:"length"`
+}
+
+// A WemListing describes a single wem stored within a container.
+type WemListing struct {
+	// The index, where 1 is the first wem, of this wem in the container.
+	Index int    `json:"index"`
+	Id    uint32 `json:"id"`
+	// The number of bytes from the start of the file that this wem begins.
+	Offset uint32 `json:"offset"`
+	Length uint32 `json:"length"`
+	// The number of bytes of padding that follow this wem.
+	Padding int64 `json:"padding"`
+	// The loop value of this wem, if the container stores one.
+	Loop *LoopValue `json:"loop,omitempty"`
+	// The name of the language of this wem, if the container stores one.
+	Language string `json:"language,omitempty"`
+}
+
+// An ObjectListing summarizes a single object of a SoundBank HIRC section.
+type ObjectListing struct {
+	Id       uint32 `json:"id"`
+	Type     byte   `json:"type"`
+	TypeName string `json:"type_name"`
+	// The length in bytes of the id and data portion of this object.
+	Length uint32 `json:"length"`
+	// The ID of the parent of this object, if it is known.
+	ParentId uint32 `json:"parent_id,omitempty"`
+	// The ID of the wem played by this object, if it is a sound.
+	WemId uint32 `json:"wem_id,omitempty"`
+	// The SHA-256 hash, in hexadecimal, of the contents of this object.
+	Hash string `json:"hash"`
+}
+
+// A LanguageListing describes a single language of a File Package.
+type LanguageListing struct {
+	Id   uint32 `json:"id"`
+	Name string `json:"name"`
+}
+
+// NewListing creates a Listing of the wems of ctn, which are described by the
+// index, offset, length and padding common to all containers. Container types
+// should fill in any remaining fields.
+func NewListing(ctn Container, containerType string) *Listing {
+	l := &Listing{Type: containerType, DataStart: ctn.DataStart()}
+	for i, wem := range ctn.Wems() {
+		desc := wem.Descriptor
+		l.Wems = append(l.Wems, &WemListing{
+			Index:   i + 1,
+			Id:      desc.WemId,
+			Offset:  ctn.DataStart() + desc.Offset,
+			Length:  desc.Length,
+			Padding: wem.Padding.Size(),
+		})
+	}
+	return l
+}
+
+// WriteCSV writes the table of this Listing named table, such as WemsTable, to
+// w as CSV. The first record names each column.
+func (l *Listing) WriteCSV(w io.Writer, table string) error {
+	records, err := l.CSVRecords(table)
+	if err != nil {
+		return err
+	}
+	cw := csv.NewWriter(w)
+	cw.WriteAll(records)
+	return cw.Error()
+}
+
+// CSVRecords returns the records of the table of this Listing named table, such
+// as WemsTable. The first record names each column.
+func (l *Listing) CSVRecords(table string) ([][]string, error) {
+	u32 := func(v uint32) string { return strconv.FormatUint(uint64(v), 10) }
+
+	var records [][]string
+	switch table {
+	case WemsTable:
+		records = append(records, []string{"index", "id", "offset", "length",
+			"padding", "loops", "loop_value", "language"})
+		for _, wem := range l.Wems {
+			loops, value := "", ""
+			if wem.Loop != nil {
+				loops = strconv.FormatBool(wem.Loop.Loops)
+				value = u32(wem.Loop.Value)
+			}
+			records = append(records, []string{strconv.Itoa(wem.Index),
+				u32(wem.Id), u32(wem.Offset), u32(wem.Length),
+				strconv.FormatInt(wem.Padding, 10), loops, value, wem.Language})
+		}
+	case SectionsTable:
+		records = append(records, []string{"id", "offset", "length"})
+		for _, sec := range l.Sections {
+			records = append(records, []string{sec.Id,
+				strconv.FormatInt(sec.Offset, 10), u32(sec.Length)})
+		}
+	case ObjectsTable:
+		records = append(records, []string{"id", "type", "type_name", "length",
+			"parent_id", "wem_id", "hash"})
+		for _, obj := range l.Objects {
+			records = append(records, []string{u32(obj.Id),
+				strconv.Itoa(int(obj.Type)), obj.TypeName, u32(obj.Length),
+				u32(obj.ParentId), u32(obj.WemId), obj.Hash})
+		}
+	case LanguagesTable:
+		records = append(records, []string{"id", "name"})
+		for _, lang := range l.Languages {
+			records = append(records, []string{u32(lang.Id), lang.Name})
+		}
+	default:
+		return nil, fmt.Errorf("%s is not a table; it must be one of %s, %s, %s "+
+			"or %s", table, WemsTable, SectionsTable, ObjectsTable, LanguagesTable)
+	}
+	return records, nil
+}
@@ -0,0 +1,295 @@
+// Package wwise implements access and modification iterfaces and functions to
+// common WWise container formats.
+package wwise
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// The naming template that gives the same names as util.CanonicalWemName.
+const DefaultNameTemplate = "{index}{ext}"
+
+// The placeholders that can be used within a naming template.
+const (
+	// The index, where 1 is the first wem, of the wem within its container. This
+	// is padded with leading zeros to the number of digits of the wem count.
+	indexPlaceholder = "index"
+	// The ID of the wem.
+	idPlaceholder = "id"
+	// The name of the wem, as given by a names file, or its ID if it has none.
+	namePlaceholder = "name"
+	// The name of the language of the wem, if its container stores one.
+	langPlaceholder = "lang"
+	// The file extension of the wem, including the leading dot.
+	extPlaceholder = "ext"
+)
+
+// The patterns matching the value of each placeholder within a file name.
+var placeholderPatterns = map[string]string{
+	indexPlaceholder: "([0-9]+)",
+	idPlaceholder:    "([0-9]+)",
+	namePlaceholder:  "(.+?)",
+	langPlaceholder:  "(.*?)",
+	extPlaceholder:   `(\.[^.]+)`,
+}
+
+var placeholderRegexp = regexp.MustCompile(`\{([^{}]*)\}`)
+
+// A NameTemplate names the files that wems are written to, such as
+// "{id}_{lang}{ext}". Names written with a template can be parsed back to the
+// wems that they were written for.
+type NameTemplate struct {
+	template string
+	// The pattern that matches file names written with this template.
+	pattern *regexp.Regexp
+	// The placeholder of each group of pattern.
+	groups []string
+	// The text and placeholders of the template, in order.
+	segments []nameSegment
+}
+
+// A nameSegment is either the text or a placeholder of a naming template.
+type nameSegment struct {
+	text string
+	// The name of the placeholder, or an empty string if this is text.
+	placeholder string
+}
+
+// The values that a NameTemplate fills its placeholders with, for a single wem.
+type WemName struct {
+	// The index, where zero is the first wem, of the wem within its container.
+	Index int
+	// The number of wems within the container.
+	Count int
+	Id    uint32
+	// The name of the wem. If this is empty, the ID of the wem is used instead.
+	Name     string
+	Language string
+	// The file extension of the wem, including the leading dot. If this is
+	// empty, WemExtension is used instead.
+	Ext string
+}
+
+// ParseNameTemplate parses a naming template made up of text and the
+// placeholders {index}, {id}, {name}, {lang} and {ext}. At least one of
+// {index}, {id} or {name} must be used so that every wem can be told apart.
+func ParseNameTemplate(template string) (*NameTemplate, error) {
+	if strings.ContainsAny(template, `/\`) {
+		return nil, fmt.Errorf("The naming template %s must not contain path "+
+			"separators", template)
+	}
+	t := &NameTemplate{template: template}
+	pattern := "^"
+	identified := false
+	last := 0
+	for _, m := range placeholderRegexp.FindAllStringSubmatchIndex(template, -1) {
+		name := template[m[2]:m[3]]
+		group, ok := placeholderPatterns[name]
+		if !ok {
+			return nil, fmt.Errorf("{%s} is not a placeholder; the naming template "+
+				"may only use {index}, {id}, {name}, {lang} and {ext}", name)
+		}
+		identified = identified || name == indexPlaceholder ||
+			name == idPlaceholder || name == namePlaceholder
+		pattern += regexp.QuoteMeta(template[last:m[0]]) + group
+		t.groups = append(t.groups, name)
+		t.segments = append(t.segments, nameSegment{text: template[last:m[0]]},
+			nameSegment{placeholder: name})
+		last = m[1]
+	}
+	pattern += regexp.QuoteMeta(template[last:]) + "$"
+	t.segments = append(t.segments, nameSegment{text: template[last:]})
+	if !identified {
+		return nil, fmt.Errorf("The naming template %s must use at least one of "+
+			"{index}, {id} or {name}", template)
+	}
+	t.pattern = regexp.MustCompile(pattern)
+	return t, nil
+}
+
+func (t *NameTemplate) String() string {
+	return t.template
+}
+
+// Format returns the file name of the wem described by n.
+func (t *NameTemplate) Format(n *WemName) string {
+	return placeholderRegexp.ReplaceAllStringFunc(t.template,
+		func(placeholder string) string {
+			switch strings.Trim(placeholder, "{}") {
+			case indexPlaceholder:
+				digits := len(strconv.Itoa(n.Count))
+				return fmt.Sprintf("%0*d", digits, n.Index+1)
+			case idPlaceholder:
+				return strconv.FormatUint(uint64(n.Id), 10)
+			case namePlaceholder:
+				return n.displayName()
+			case langPlaceholder:
+				return sanitizeName(n.Language)
+			case extPlaceholder:
+				if n.Ext == "" {
+					return WemExtension
+				}
+				return n.Ext
+			}
+			return placeholder
+		})
+}
+
+// displayName returns the name of the wem as it is written in file names.
+func (n *WemName) displayName() string {
+	if n.Name == "" {
+		return strconv.FormatUint(uint64(n.Id), 10)
+	}
+	return sanitizeName(n.Name)
+}
+
+// sanitizeName replaces the path separators of name, so that it can be used as
+// part of a file name.
+func sanitizeName(name string) string {
+	return strings.NewReplacer("/", "_", `\`, "_").Replace(name)
+}
+
+// IndexOf returns the index, where zero is the first wem, of the wem of the
+// container described by listing that filename was written for with this
+// template, along with the extension that filename was written with, or an
+// empty string if the template has no {ext}. names gives the names of wems by
+// their ID, as used for {name}, and may be nil. Every wem is tried in turn, so
+// that names whose placeholders could be split in more than one way are parsed
+// correctly, and it is an error if filename could have been written for more
+// than one wem. If filename matches the template but not a wem, the extension
+// it appears to have is still returned.
+func (t *NameTemplate) IndexOf(filename string, listing *Listing,
+	names map[uint32]string) (index int, ext string, err error) {
+	m := t.pattern.FindStringSubmatch(filename)
+	if m == nil {
+		return 0, "", fmt.Errorf("It does not match the naming template %s", t)
+	}
+	for i, group := range t.groups {
+		if group == extPlaceholder {
+			ext = m[i+1]
+		}
+	}
+
+	var found []int
+	var foundExt string
+	for i, wem := range listing.Wems {
+		n := &WemName{Id: wem.Id, Name: names[wem.Id]}
+		values := map[string]string{
+			indexPlaceholder: strconv.Itoa(i + 1),
+			idPlaceholder:    strconv.FormatUint(uint64(wem.Id), 10),
+			namePlaceholder:  n.displayName(),
+			langPlaceholder:  sanitizeName(wem.Language),
+		}
+		if e, ok := matchSegments(filename, t.segments, values, ""); ok {
+			found, foundExt = append(found, i), e
+		}
+	}
+	switch len(found) {
+	case 0:
+		return 0, ext, errors.New("There is no wem with this index, ID, name " +
+			"and language")
+	case 1:
+		return found[0], foundExt, nil
+	}
+	return 0, ext, fmt.Errorf("It could have been written for wems %d and %d",
+		found[0]+1, found[1]+1)
+}
+
+// matchSegments returns true if s is made up of segments, where values gives
+// the value of each placeholder other than {ext}. Indexes may have leading
+// zeros. ext is the value of {ext} if it has been matched already, or an empty
+// string otherwise; the value of {ext} that s was matched with is returned.
+func matchSegments(s string, segments []nameSegment, values map[string]string,
+	ext string) (string, bool) {
+	if len(segments) == 0 {
+		return ext, s == ""
+	}
+	seg, rest := segments[0], segments[1:]
+	switch seg.placeholder {
+	case "":
+		if !strings.HasPrefix(s, seg.text) {
+			return "", false
+		}
+		return matchSegments(s[len(seg.text):], rest, values, ext)
+	case indexPlaceholder:
+		for {
+			v := values[indexPlaceholder]
+			if strings.HasPrefix(s, v) {
+				if e, ok := matchSegments(s[len(v):], rest, values, ext); ok {
+					return e, true
+				}
+			}
+			if !strings.HasPrefix(s, "0") {
+				return "", false
+			}
+			s = s[1:]
+		}
+	case extPlaceholder:
+		if ext != "" {
+			if !strings.HasPrefix(s, ext) {
+				return "", false
+			}
+			return matchSegments(s[len(ext):], rest, values, ext)
+		}
+		// An extension is a dot followed by at least one other character, none
+		// of which are dots.
+		if !strings.HasPrefix(s, ".") {
+			return "", false
+		}
+		for end := 2; end <= len(s) && s[end-1] != '.'; end++ {
+			if e, ok := matchSegments(s[end:], rest, values, s[:end]); ok {
+				return e, true
+			}
+		}
+		return "", false
+	}
+	v := values[seg.placeholder]
+	if !strings.HasPrefix(s, v) {
+		return "", false
+	}
+	return matchSegments(s[len(v):], rest, values, ext)
+}
+
+// HashName returns the ID that Wwise derives from name: the 32-bit FNV-1 hash
+// of the lower case name.
+func HashName(name string) uint32 {
+	h := fnv.New32()
+	h.Write([]byte(strings.ToLower(name)))
+	return h.Sum32()
+}
+
+// LoadNames reads the names of wems from the file at path. Each line holds
+// either a wem ID followed by its name, or a name alone, whose ID is given by
+// HashName. Empty lines and lines starting with # are ignored.
+func LoadNames(path string) (map[uint32]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	names := make(map[uint32]string)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if i := strings.IndexAny(line, " \t"); i >= 0 {
+			id, err := strconv.ParseUint(line[:i], 10, 32)
+			if err == nil {
+				names[uint32(id)] = strings.TrimSpace(line[i+1:])
+				continue
+			}
+		}
+		names[HashName(line)] = line
+	}
+	return names, scanner.Err()
+}
@@ -0,0 +1,235 @@
+// Package wwise implements access and modification iterfaces and functions to
+// common WWise container formats.
+package wwise
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// The offset into the fmt chunk data of a Wwise Opus wem where the total
+// number of samples is stored.
+const opusSampleCountOffset = 0x18
+
+// The offset into the fmt chunk data of a Wwise Opus wem where the number of
+// entries in the seek table is stored.
+const opusTableCountOffset = 0x1C
+
+// The offset into the fmt chunk data of a Wwise Opus wem where the number of
+// pre-skip samples is stored.
+const opusSkipOffset = 0x20
+
+// The sample rate that every Opus stream is decoded at.
+const opusSampleRate = 48000
+
+// The maximum number of bytes in a single Ogg page segment.
+const oggMaxSegmentBytes = 255
+
+// Ogg page header flags.
+const (
+	oggFirstPage = 0x02
+	oggLastPage  = 0x04
+)
+
+// The identifier for the chunk holding the packet size table of an Opus wem.
+var seekChunkId = [4]byte{'s', 'e', 'e', 'k'}
+
+// The CRC-32 lookup table used by Ogg pages, which use the polynomial
+// 0x04C11DB7 without bit reflection.
+var oggCrcTable = func() [256]uint32 {
+	var table [256]uint32
+	for i := range table {
+		r := uint32(i) << 24
+		for j := 0; j < 8; j++ {
+			if r&0x80000000 != 0 {
+				r = r<<1 ^ 0x04C11DB7
+			} else {
+				r <<= 1
+			}
+		}
+		table[i] = r
+	}
+	return table
+}()
+
+// An oggWriter writes a single logical Ogg bitstream, one packet per page.
+type oggWriter struct {
+	w       io.Writer
+	serial  uint32
+	seq     uint32
+	written int64
+}
+
+// writePacket writes packet as a single Ogg page, with the absolute granule
+// position granule.
+func (ogg *oggWriter) writePacket(packet []byte, granule int64,
+	flags byte) error {
+	segments := len(packet)/oggMaxSegmentBytes + 1
+	if segments > oggMaxSegmentBytes {
+		return fmt.Errorf("An Opus packet of %d bytes is too large to fit in a "+
+			"single Ogg page", len(packet))
+	}
+
+	page := new(bytes.Buffer)
+	page.WriteString("OggS")
+	page.WriteByte(0)
+	page.WriteByte(flags)
+	binary.Write(page, binary.LittleEndian, granule)
+	binary.Write(page, binary.LittleEndian, ogg.serial)
+	binary.Write(page, binary.LittleEndian, ogg.seq)
+	// The checksum is computed with this field zeroed, and is filled in below.
+	binary.Write(page, binary.LittleEndian, uint32(0))
+	page.WriteByte(byte(segments))
+	for remaining := len(packet); ; remaining -= oggMaxSegmentBytes {
+		if remaining < oggMaxSegmentBytes {
+			page.WriteByte(byte(remaining))
+			break
+		}
+		page.WriteByte(oggMaxSegmentBytes)
+	}
+	page.Write(packet)
+
+	bs := page.Bytes()
+	crc := uint32(0)
+	for _, b := range bs {
+		crc = crc<<8 ^ oggCrcTable[byte(crc>>24)^b]
+	}
+	binary.LittleEndian.PutUint32(bs[22:26], crc)
+
+	n, err := ogg.w.Write(bs)
+	ogg.written += int64(n)
+	ogg.seq++
+	return err
+}
+
+// extractOpus writes the Wwise Opus wem described by hdr as an Ogg Opus
+// stream. Wwise Opus stores raw Opus packets back to back in the data chunk,
+// with the size of every packet stored in the seek chunk.
+func extractOpus(w io.Writer, r io.ReaderAt,
+	hdr *WemHeader) (written int64, err error) {
+	fc, seek, data := hdr.Chunk(fmtChunkId), hdr.Chunk(seekChunkId),
+		hdr.Chunk(dataChunkId)
+	if seek == nil || data == nil {
+		return 0, errors.New("An Opus wem requires a seek and data chunk")
+	}
+	if fc.Length < opusSkipOffset+2 {
+		return 0, fmt.Errorf("The fmt chunk of an Opus wem must be at least %d "+
+			"bytes, but is %d bytes", opusSkipOffset+2, fc.Length)
+	}
+	channels := hdr.Format.Channels
+	if channels == 0 || channels > 2 {
+		return 0, fmt.Errorf("Extracting Opus wems with %d channels is not "+
+			"supported", channels)
+	}
+
+	var extra [opusSkipOffset + 2]byte
+	_, err = r.ReadAt(extra[:], fc.DataOffset())
+	if err != nil {
+		return 0, err
+	}
+	order := hdr.ByteOrder
+	sampleCount := int64(order.Uint32(extra[opusSampleCountOffset:]))
+	tableCount := order.Uint32(extra[opusTableCountOffset:])
+	skip := order.Uint16(extra[opusSkipOffset:])
+	if int64(tableCount)*2 > int64(seek.Length) {
+		return 0, fmt.Errorf("The seek table declares %d packets, but the seek "+
+			"chunk only holds %d bytes", tableCount, seek.Length)
+	}
+
+	table := make([]byte, tableCount*2)
+	_, err = r.ReadAt(table, seek.DataOffset())
+	if err != nil {
+		return 0, err
+	}
+
+	ogg := &oggWriter{w: w, serial: 1}
+
+	head := new(bytes.Buffer)
+	head.WriteString("OpusHead")
+	head.WriteByte(1)
+	head.WriteByte(byte(channels))
+	binary.Write(head, binary.LittleEndian, skip)
+	binary.Write(head, binary.LittleEndian, hdr.Format.SampleRate)
+	// Output gain and channel mapping family.
+	binary.Write(head, binary.LittleEndian, uint16(0))
+	head.WriteByte(0)
+	err = ogg.writePacket(head.Bytes(), 0, oggFirstPage)
+	if err != nil {
+		return ogg.written, err
+	}
+
+	tags := new(bytes.Buffer)
+	vendor := "wwiseutil"
+	tags.WriteString("OpusTags")
+	binary.Write(tags, binary.LittleEndian, uint32(len(vendor)))
+	tags.WriteString(vendor)
+	binary.Write(tags, binary.LittleEndian, uint32(0))
+	err = ogg.writePacket(tags.Bytes(), 0, 0)
+	if err != nil {
+		return ogg.written, err
+	}
+
+	offset, end := data.DataOffset(), data.End()
+	granule, last := int64(0), int64(skip)+sampleCount
+	for i := uint32(0); i < tableCount; i++ {
+		size := int64(order.Uint16(table[i*2:]))
+		if offset+size > end {
+			return ogg.written, fmt.Errorf("Opus packet %d runs past the end of "+
+				"the data chunk", i)
+		}
+		packet := make([]byte, size)
+		_, err = r.ReadAt(packet, offset)
+		if err != nil {
+			return ogg.written, err
+		}
+		offset += size
+
+		granule += opusPacketSamples(packet)
+		var flags byte
+		if i == tableCount-1 {
+			// The final granule position trims the padding of the last packet.
+			flags, granule = oggLastPage, last
+		}
+		err = ogg.writePacket(packet, granule, flags)
+		if err != nil {
+			return ogg.written, err
+		}
+	}
+
+	return ogg.written, nil
+}
+
+// opusPacketSamples returns the number of 48kHz samples encoded by packet, as
+// determined by its TOC byte.
+func opusPacketSamples(packet []byte) int64 {
+	if len(packet) == 0 {
+		return 0
+	}
+	toc := packet[0]
+	config := toc >> 3
+	// The frame duration, in units of 2.5ms.
+	var duration int64
+	switch {
+	case config < 12: // SILK-only: 10, 20, 40 or 60ms.
+		duration = []int64{4, 8, 16, 24}[config%4]
+	case config < 16: // Hybrid: 10 or 20ms.
+		duration = []int64{4, 8}[config%2]
+	default: // CELT-only: 2.5, 5, 10 or 20ms.
+		duration = []int64{1, 2, 4, 8}[config%4]
+	}
+
+	frames := int64(1)
+	switch toc & 0x3 {
+	case 1, 2:
+		frames = 2
+	case 3:
+		if len(packet) < 2 {
+			return 0
+		}
+		frames = int64(packet[1] & 0x3F)
+	}
+	return frames * duration * opusSampleRate / 400
+}
@@ -0,0 +1,181 @@
+// Package wwise implements access and modification iterfaces and functions to
+// common WWise container formats.
+package wwise
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// The number of bytes used to describe the RIFF header of a wem, including the
+// WAVE form type.
+const RIFF_HEADER_BYTES = 12
+
+// The number of bytes used to describe the header of a single RIFF chunk.
+const CHUNK_HEADER_BYTES = 8
+
+// The minimum number of bytes needed to describe the fmt chunk of a wem.
+const FMT_CHUNK_MIN_BYTES = 16
+
+// The identifier for the start of a little-endian RIFF container.
+var riffId = [4]byte{'R', 'I', 'F', 'F'}
+
+// The identifier for the start of a big-endian RIFF container, used on some
+// consoles.
+var rifxId = [4]byte{'R', 'I', 'F', 'X'}
+
+// The form type of a RIFF container holding audio data.
+var waveId = [4]byte{'W', 'A', 'V', 'E'}
+
+// The identifier for the chunk describing the format of the audio data.
+var fmtChunkId = [4]byte{'f', 'm', 't', ' '}
+
+// The identifier for the chunk holding the audio data.
+var dataChunkId = [4]byte{'d', 'a', 't', 'a'}
+
+// The identifier for the chunk holding Vorbis setup information in older wems.
+var vorbChunkId = [4]byte{'v', 'o', 'r', 'b'}
+
+// A WemHeader describes the RIFF structure of a single wem.
+type WemHeader struct {
+	// The byte order of all values in this wem. This is binary.BigEndian for RIFX
+	// wems, and binary.LittleEndian otherwise.
+	ByteOrder binary.ByteOrder
+	// The length of the RIFF container, excluding the first 8 bytes, as
+	// declared by the RIFF header.
+	Length uint32
+	// The chunks of this wem, in the order that they appear.
+	Chunks []*RiffChunk
+	// The decoded contents of the fmt chunk, or nil if there is no fmt chunk.
+	Format *WemFormat
+}
+
+// A RiffChunk describes the location of a single chunk within a wem.
+type RiffChunk struct {
+	Id [4]byte
+	// The number of bytes from the start of the wem that this chunk's header
+	// begins.
+	Offset int64
+	// The length in bytes of this chunk's data, as declared by its header.
+	Length uint32
+	// The decoded fields of this chunk. This is only populated by InspectWem.
+	Fields []*ChunkField
+}
+
+// A WemFormat represents the fixed portion of the fmt chunk of a wem.
+type WemFormat struct {
+	// The identifier of the codec used to encode this wem.
+	FormatTag         uint16
+	Channels          uint16
+	SampleRate        uint32
+	AvgBytesPerSecond uint32
+	BlockAlign        uint16
+	BitsPerSample     uint16
+}
+
+// ReadWemHeader reads the RIFF structure of the wem stored in the first size
+// bytes of r. Chunks are read leniently: a chunk whose declared length runs past
+// size is still included, so that callers are able to report it.
+func ReadWemHeader(r io.ReaderAt, size int64) (*WemHeader, error) {
+	var riff [RIFF_HEADER_BYTES]byte
+	if size < RIFF_HEADER_BYTES {
+		return nil, fmt.Errorf("%d bytes is too small to hold a RIFF header", size)
+	}
+	_, err := r.ReadAt(riff[:], 0)
+	if err != nil {
+		return nil, err
+	}
+
+	hdr := new(WemHeader)
+	var id [4]byte
+	copy(id[:], riff[0:4])
+	switch id {
+	case riffId:
+		hdr.ByteOrder = binary.LittleEndian
+	case rifxId:
+		hdr.ByteOrder = binary.BigEndian
+	default:
+		return nil, fmt.Errorf("Expected a RIFF or RIFX header but got: %q", id)
+	}
+	hdr.Length = hdr.ByteOrder.Uint32(riff[4:8])
+	copy(id[:], riff[8:12])
+	if id != waveId {
+		return nil, fmt.Errorf("Expected a WAVE form type but got: %q", id)
+	}
+
+	offset := int64(RIFF_HEADER_BYTES)
+	for offset+CHUNK_HEADER_BYTES <= size {
+		var ch [CHUNK_HEADER_BYTES]byte
+		_, err := r.ReadAt(ch[:], offset)
+		if err != nil {
+			return nil, err
+		}
+		chunk := new(RiffChunk)
+		copy(chunk.Id[:], ch[0:4])
+		chunk.Offset = offset
+		chunk.Length = hdr.ByteOrder.Uint32(ch[4:8])
+		hdr.Chunks = append(hdr.Chunks, chunk)
+
+		// Chunks are aligned to 2 bytes; an odd length is followed by a pad byte.
+		offset = chunk.End() + int64(chunk.Length%2)
+	}
+
+	if fc := hdr.Chunk(fmtChunkId); fc != nil && fc.Length >= FMT_CHUNK_MIN_BYTES &&
+		fc.End() <= size {
+		f := new(WemFormat)
+		sr := io.NewSectionReader(r, fc.DataOffset(), FMT_CHUNK_MIN_BYTES)
+		err := binary.Read(sr, hdr.ByteOrder, f)
+		if err != nil {
+			return nil, err
+		}
+		hdr.Format = f
+	}
+
+	return hdr, nil
+}
+
+// Chunk returns the first chunk in this wem with the identifier id, or nil if
+// there is no such chunk.
+func (hdr *WemHeader) Chunk(id [4]byte) *RiffChunk {
+	for _, c := range hdr.Chunks {
+		if c.Id == id {
+			return c
+		}
+	}
+	return nil
+}
+
+// DataOffset returns the number of bytes from the start of the wem that this
+// chunk's data begins.
+func (c *RiffChunk) DataOffset() int64 {
+	return c.Offset + CHUNK_HEADER_BYTES
+}
+
+// End returns the number of bytes from the start of the wem that this chunk
+// ends, as declared by its header.
+func (c *RiffChunk) End() int64 {
+	return c.DataOffset() + int64(c.Length)
+}
+
+func (c *RiffChunk) String() string {
+	return fmt.Sprintf("%s: offset(0x%X) len(%d)", c.Id, c.Offset, c.Length)
+}
+
+func (hdr *WemHeader) String() string {
+	b := new(strings.Builder)
+	byteOrder := "RIFF"
+	if hdr.ByteOrder == binary.BigEndian {
+		byteOrder = "RIFX"
+	}
+	fmt.Fprintf(b, "%s: len(%d) chunk_count(%d)\n", byteOrder, hdr.Length,
+		len(hdr.Chunks))
+	for _, c := range hdr.Chunks {
+		fmt.Fprintln(b, c)
+		for _, f := range c.Fields {
+			fmt.Fprintf(b, "  0x%-6X %-24s %s\n", f.Offset, f.Name, f.Value)
+		}
+	}
+	return b.String()
+}
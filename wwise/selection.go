@@ -0,0 +1,181 @@
+// Package wwise implements access and modification iterfaces and functions to
+// common WWise container formats.
+package wwise
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+)
+
+import (
+	"github.com/hpxro7/wwiseutil/util"
+)
+
+// A Selection chooses a subset of the wems of a container. A wem is selected
+// only if it matches every kind of criteria that is set, and a Selection
+// without any criteria selects every wem.
+type Selection struct {
+	// The ranges of indexes to select. A wem is selected if its index falls
+	// within any of the ranges.
+	Ranges []IndexRange
+	// The IDs of the wems to select.
+	Ids []uint32
+	// Patterns, as used by path.Match, that are matched against both the ID of
+	// each wem, written in decimal, and its canonical file name.
+	Patterns []string
+	// The names of the languages to select, ignoring case. Only File Packages
+	// store a language for each wem, so no wem of a SoundBank is selected by
+	// language.
+	Languages []string
+	// The codecs to select. Wems whose codec cannot be detected are treated as
+	// having the UnknownCodec.
+	Codecs []Codec
+}
+
+// An IndexRange is an inclusive range of wem indexes, where 1 is the first wem.
+type IndexRange struct {
+	First int
+	// The last index of the range. If this is zero, the range continues until
+	// the last wem.
+	Last int
+}
+
+// ParseIndexRanges parses a comma separated list of indexes, where 1 is the
+// first wem, and ranges of indexes, such as "1-10,15,20-". A range without an
+// end continues until the last wem.
+func ParseIndexRanges(s string) ([]IndexRange, error) {
+	var ranges []IndexRange
+	for _, field := range strings.Split(s, ",") {
+		field = strings.TrimSpace(field)
+		first, last := field, field
+		if i := strings.Index(field, "-"); i >= 0 {
+			first, last = field[:i], field[i+1:]
+		}
+		r := IndexRange{}
+		var err error
+		r.First, err = strconv.Atoi(first)
+		if err == nil && last != "" {
+			r.Last, err = strconv.Atoi(last)
+		}
+		if err != nil || r.First < 1 || (last != "" && r.Last < r.First) {
+			return nil, fmt.Errorf("%s is not a valid index range; it must be an "+
+				"index, such as 5, or a range of indexes, such as 1-10 or 20-", field)
+		}
+		ranges = append(ranges, r)
+	}
+	return ranges, nil
+}
+
+// ParseWemIds parses a comma separated list of wem IDs.
+func ParseWemIds(s string) ([]uint32, error) {
+	var ids []uint32
+	for _, field := range strings.Split(s, ",") {
+		field = strings.TrimSpace(field)
+		id, err := strconv.ParseUint(field, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("%s is not a valid wem ID", field)
+		}
+		ids = append(ids, uint32(id))
+	}
+	return ids, nil
+}
+
+// Contains returns true if the index, where 1 is the first wem, falls within
+// this range.
+func (r IndexRange) Contains(index int) bool {
+	return index >= r.First && (r.Last == 0 || index <= r.Last)
+}
+
+// Empty returns true if this Selection has no criteria, and so selects every
+// wem.
+func (sel *Selection) Empty() bool {
+	return len(sel.Ranges) == 0 && len(sel.Ids) == 0 &&
+		len(sel.Patterns) == 0 && len(sel.Languages) == 0 && len(sel.Codecs) == 0
+}
+
+// Select returns the indexes, where zero is the first wem, of every wem of ctn
+// chosen by this Selection, in ascending order. An error is returned if any of
+// the patterns is malformed.
+func (sel *Selection) Select(ctn Container) ([]int, error) {
+	for _, pattern := range sel.Patterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("%s is not a valid pattern: %s", pattern, err)
+		}
+	}
+
+	ids := make(map[uint32]bool)
+	for _, id := range sel.Ids {
+		ids[id] = true
+	}
+	var languages []*WemListing
+	if len(sel.Languages) > 0 {
+		languages = ctn.Listing().Wems
+	}
+
+	wems := ctn.Wems()
+	var indexes []int
+	for i, wem := range wems {
+		if len(sel.Ranges) > 0 && !sel.inRanges(i+1) {
+			continue
+		}
+		if len(ids) > 0 && !ids[wem.Descriptor.WemId] {
+			continue
+		}
+		if len(sel.Patterns) > 0 && !sel.matches(wem.Descriptor.WemId,
+			util.CanonicalWemName(i, len(wems))) {
+			continue
+		}
+		if len(languages) > 0 && !sel.hasLanguage(languages[i].Language) {
+			continue
+		}
+		if len(sel.Codecs) > 0 && !sel.hasCodec(wem) {
+			continue
+		}
+		indexes = append(indexes, i)
+	}
+	return indexes, nil
+}
+
+func (sel *Selection) inRanges(index int) bool {
+	for _, r := range sel.Ranges {
+		if r.Contains(index) {
+			return true
+		}
+	}
+	return false
+}
+
+func (sel *Selection) matches(id uint32, name string) bool {
+	for _, pattern := range sel.Patterns {
+		idMatched, _ := path.Match(pattern, strconv.FormatUint(uint64(id), 10))
+		nameMatched, _ := path.Match(pattern, name)
+		if idMatched || nameMatched {
+			return true
+		}
+	}
+	return false
+}
+
+func (sel *Selection) hasLanguage(language string) bool {
+	for _, l := range sel.Languages {
+		if language != "" && strings.EqualFold(l, language) {
+			return true
+		}
+	}
+	return false
+}
+
+func (sel *Selection) hasCodec(wem *Wem) bool {
+	codec, err := DetectCodec(wem, int64(wem.Descriptor.Length))
+	if err != nil {
+		codec = UnknownCodec
+	}
+	for _, c := range sel.Codecs {
+		if c == codec {
+			return true
+		}
+	}
+	return false
+}
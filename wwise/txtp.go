@@ -0,0 +1,108 @@
+// Package wwise implements access and modification iterfaces and functions to
+// common WWise container formats.
+package wwise
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// The extension of vgmstream playlist files.
+const TxtpExtension = ".txtp"
+
+// A Txtp describes a vgmstream .txtp playlist, which plays one or more wems in
+// sequence, unless they are combined by groups.
+type Txtp struct {
+	// A comment describing what this playlist plays.
+	Comment string
+	// The entries and groups of this playlist, in the order they are written.
+	Lines []TxtpLine
+}
+
+// A TxtpLine is a single line of a Txtp: either a TxtpEntry or a TxtpGroup.
+type TxtpLine interface {
+	String() string
+}
+
+// A TxtpGroupType describes how a TxtpGroup plays its items.
+type TxtpGroupType byte
+
+const (
+	// Every item is played in turn.
+	TxtpSequence TxtpGroupType = 'S'
+	// Every item is played at once.
+	TxtpLayer TxtpGroupType = 'L'
+	// A single item is played.
+	TxtpRandom TxtpGroupType = 'R'
+)
+
+// A TxtpGroup combines the items written just before it in a Txtp, each an
+// entry or an earlier group, into a single item.
+type TxtpGroup struct {
+	Type TxtpGroupType
+	// The number of items combined.
+	Count int
+	// The item played by a random group, where 1 is the first item, or 0 to let
+	// vgmstream choose one at random.
+	Selected int
+}
+
+// A TxtpEntry describes a single wem played by a Txtp.
+type TxtpEntry struct {
+	// The path to the wem or container, relative to the .txtp file.
+	Path string
+	// The index, where 1 is the first wem, of the wem within the container at
+	// Path. This is 0 if Path refers to a standalone wem.
+	Subsong int
+	// How the wem loops, or nil if this is not known and vgmstream should decide
+	// from the wem itself.
+	Loop *LoopValue
+}
+
+// WriteTo writes this Txtp in the vgmstream .txtp format to the Writer
+// specified by w.
+func (t *Txtp) WriteTo(w io.Writer) (written int64, err error) {
+	b := new(strings.Builder)
+	if t.Comment != "" {
+		fmt.Fprintf(b, "# %s\n", t.Comment)
+	}
+	for _, l := range t.Lines {
+		b.WriteString(l.String())
+		b.WriteString("\n")
+	}
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
+
+// String returns the .txtp line that plays this entry.
+func (e *TxtpEntry) String() string {
+	// vgmstream requires forward slashes in paths on all platforms.
+	line := strings.Replace(e.Path, "\\", "/", -1)
+	if e.Subsong > 0 {
+		line += fmt.Sprintf("#%d", e.Subsong)
+	}
+	switch {
+	case e.Loop == nil:
+	case !e.Loop.Loops:
+		// Ignore any loop points stored within the wem.
+		line += " #i"
+	case e.Loop.Value == InfiniteLoops:
+		// Loop the full wem, unless it defines its own loop points.
+		line += " #E"
+	default:
+		// Play the loop a fixed number of times, then play to the end instead of
+		// fading out.
+		line += fmt.Sprintf(" #E #l %d #F", e.Loop.Value)
+	}
+	return line
+}
+
+// String returns the .txtp line that combines the items of this group.
+func (g *TxtpGroup) String() string {
+	line := fmt.Sprintf("group = -%c%d", g.Type, g.Count)
+	if g.Selected > 0 {
+		line += fmt.Sprintf(">%d", g.Selected)
+	}
+	return line
+}
@@ -0,0 +1,135 @@
+// Package wwise implements access and modification iterfaces and functions to
+// common WWise container formats.
+package wwise
+
+import (
+	"fmt"
+)
+
+// The format tag used by wems encoded with Wwise Vorbis.
+const vorbisFormatTag = 0xFFFF
+
+// The length of a fmt chunk that embeds the Vorbis setup information, as used
+// by wems that have no separate vorb chunk.
+const vorbisFmtChunkBytes = 0x42
+
+// Severity describes how likely a ValidationIssue is to break playback.
+type Severity int
+
+const (
+	// The replacement is usable, but may not behave like the original.
+	SeverityWarning Severity = iota
+	// The replacement is very likely to fail to play or crash the game.
+	SeverityError
+)
+
+// A ValidationIssue describes a single problem found with a replacement wem.
+type ValidationIssue struct {
+	Severity Severity
+	Message  string
+}
+
+// ValidateReplacement parses the RIFF header of the replacement r and checks it
+// against the original wem it replaces. All problems that were found are
+// returned; a nil slice means the replacement looks compatible with org.
+func ValidateReplacement(org *Wem, r *ReplacementWem) []*ValidationIssue {
+	var issues []*ValidationIssue
+	warn := func(format string, a ...interface{}) {
+		issues = append(issues,
+			&ValidationIssue{SeverityWarning, fmt.Sprintf(format, a...)})
+	}
+	fail := func(format string, a ...interface{}) {
+		issues = append(issues,
+			&ValidationIssue{SeverityError, fmt.Sprintf(format, a...)})
+	}
+
+	hdr, err := ReadWemHeader(r.Wem, r.Length)
+	if err != nil {
+		fail("The replacement is not a valid wem: %s", err)
+		return issues
+	}
+
+	declared := int64(hdr.Length) + CHUNK_HEADER_BYTES
+	if declared > r.Length {
+		fail("The RIFF header declares %d bytes, but the replacement is only "+
+			"%d bytes; it is likely truncated", declared, r.Length)
+	} else if declared < r.Length {
+		warn("The RIFF header declares %d bytes, but the replacement is %d "+
+			"bytes; the trailing bytes will be ignored", declared, r.Length)
+	}
+
+	for _, c := range hdr.Chunks {
+		if c.End() > r.Length {
+			fail("The %q chunk at offset 0x%X declares %d bytes, which runs past "+
+				"the end of the replacement", c.Id, c.Offset, c.Length)
+		}
+	}
+
+	if hdr.Chunk(dataChunkId) == nil {
+		fail("The replacement has no data chunk")
+	}
+	if hdr.Format == nil {
+		fail("The replacement has no valid fmt chunk")
+		return issues
+	}
+	if hdr.Format.FormatTag == vorbisFormatTag && hdr.Chunk(vorbChunkId) == nil &&
+		hdr.Chunk(fmtChunkId).Length != vorbisFmtChunkBytes {
+		fail("The replacement is Vorbis encoded, but has no vorb chunk or " +
+			"embedded Vorbis setup information")
+	}
+
+	orgHdr, err := ReadWemHeader(org, int64(org.Descriptor.Length))
+	if err != nil || orgHdr.Format == nil {
+		warn("The original wem could not be parsed, so the replacement could not " +
+			"be compared against it")
+		return issues
+	}
+
+	orgFmt, newFmt := orgHdr.Format, hdr.Format
+	if orgHdr.ByteOrder != hdr.ByteOrder {
+		fail("The replacement uses a different byte order than the original")
+	}
+	if orgFmt.FormatTag != newFmt.FormatTag {
+		fail("The replacement's codec (0x%04X) does not match the original's "+
+			"codec (0x%04X)", newFmt.FormatTag, orgFmt.FormatTag)
+	}
+	if orgFmt.Channels != newFmt.Channels {
+		fail("The replacement has %d channel(s), but the original has %d",
+			newFmt.Channels, orgFmt.Channels)
+	}
+	if orgFmt.SampleRate != newFmt.SampleRate {
+		warn("The replacement has a sample rate of %d Hz, but the original has "+
+			"a sample rate of %d Hz", newFmt.SampleRate, orgFmt.SampleRate)
+	}
+	for _, c := range orgHdr.Chunks {
+		if hdr.Chunk(c.Id) == nil {
+			warn("The original has a %q chunk, but the replacement does not", c.Id)
+		}
+	}
+
+	return issues
+}
+
+// HasErrors returns true if any of issues has a severity of SeverityError.
+func HasErrors(issues []*ValidationIssue) bool {
+	for _, issue := range issues {
+		if issue.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityWarning:
+		return "warning"
+	case SeverityError:
+		return "error"
+	}
+	return fmt.Sprintf("Severity(%d)", int(s))
+}
+
+func (issue *ValidationIssue) String() string {
+	return fmt.Sprintf("%s: %s", issue.Severity, issue.Message)
+}
@@ -0,0 +1,60 @@
+// Package wwise implements access and modification iterfaces and functions to
+// common WWise container formats.
+package wwise
+
+import (
+	"fmt"
+	"sort"
+)
+
+// A Problem describes a single inconsistency found within a container.
+type Problem struct {
+	// The offset into the file of the inconsistent data.
+	Offset  int64  `json:"offset"`
+	Message string `json:"message"`
+}
+
+// A Verifier is a container that can check that it is internally consistent.
+type Verifier interface {
+	// Verify returns every problem found within the container, ordered by
+	// offset. A nil slice means that the container is consistent.
+	Verify() []*Problem
+}
+
+// NewProblem returns a Problem at offset, described by format and a in the
+// style of fmt.Sprintf.
+func NewProblem(offset int64, format string, a ...interface{}) *Problem {
+	return &Problem{offset, fmt.Sprintf(format, a...)}
+}
+
+func (p *Problem) String() string {
+	return fmt.Sprintf("0x%08X: %s", p.Offset, p.Message)
+}
+
+// SortProblems sorts problems by their offset, keeping problems at the same
+// offset in the order they were found.
+func SortProblems(problems []*Problem) {
+	sort.SliceStable(problems, func(i, j int) bool {
+		return problems[i].Offset < problems[j].Offset
+	})
+}
+
+// VerifyReadable returns a problem for every wem of ctn whose contents run past
+// the end of the file. start is the offset into the file of the wem at index
+// i.
+func VerifyReadable(ctn Container, start func(i int) int64) []*Problem {
+	var problems []*Problem
+	var last [1]byte
+	for i, wem := range ctn.Wems() {
+		length := int64(wem.Descriptor.Length)
+		if length == 0 {
+			continue
+		}
+		if n, _ := wem.ReadAt(last[:], length-1); n != len(last) {
+			problems = append(problems, NewProblem(start(i), "Wem %d (id %d) "+
+				"declares %d bytes, which runs past the end of the file", i+1,
+				wem.Descriptor.WemId, length))
+		}
+	}
+	return problems
+}
@@ -0,0 +1,113 @@
+// Package wwise implements access and modification iterfaces and functions to
+// common WWise container formats.
+package wwise
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// The number of bytes used to describe an XMA2WAVEFORMATEX structure.
+const XMA2_FORMAT_BYTES = 52
+
+// An xma2Format represents the XMA2WAVEFORMATEX structure stored in the fmt
+// chunk of XMA2 wems.
+type xma2Format struct {
+	WemFormat
+	ExtraLength    uint16
+	NumStreams     uint16
+	ChannelMask    uint32
+	SamplesEncoded uint32
+	BytesPerBlock  uint32
+	PlayBegin      uint32
+	PlayLength     uint32
+	LoopBegin      uint32
+	LoopLength     uint32
+	LoopCount      byte
+	EncoderVersion byte
+	BlockCount     uint16
+}
+
+// extractXMA2 writes the XMA2 wem described by hdr as a standard little-endian
+// XMA2 RIFF file. Wems built for big-endian consoles store their RIFF fields in
+// big-endian order, which other tools do not accept. The XMA2 bitstream itself
+// is always big-endian and is copied as is, along with any seek table.
+func extractXMA2(w io.Writer, r io.ReaderAt,
+	hdr *WemHeader) (written int64, err error) {
+	fc, seek, data := hdr.Chunk(fmtChunkId), hdr.Chunk(seekChunkId),
+		hdr.Chunk(dataChunkId)
+	if data == nil {
+		return 0, errors.New("An XMA2 wem requires a data chunk")
+	}
+	if fc.Length < XMA2_FORMAT_BYTES {
+		return 0, fmt.Errorf("The fmt chunk of an XMA2 wem must be at least %d "+
+			"bytes, but is %d bytes", XMA2_FORMAT_BYTES, fc.Length)
+	}
+
+	f := new(xma2Format)
+	sr := io.NewSectionReader(r, fc.DataOffset(), XMA2_FORMAT_BYTES)
+	err = binary.Read(sr, hdr.ByteOrder, f)
+	if err != nil {
+		return 0, err
+	}
+
+	chunks := new(bytes.Buffer)
+	binary.Write(chunks, binary.LittleEndian, fmtChunkId)
+	binary.Write(chunks, binary.LittleEndian, uint32(XMA2_FORMAT_BYTES))
+	binary.Write(chunks, binary.LittleEndian, f)
+	var copied []*RiffChunk
+	if seek != nil {
+		copied = append(copied, seek)
+	}
+	copied = append(copied, data)
+
+	riffLength := int64(4 + chunks.Len())
+	for _, c := range copied {
+		riffLength += CHUNK_HEADER_BYTES + int64(c.Length) + int64(c.Length%2)
+	}
+
+	riff := new(bytes.Buffer)
+	binary.Write(riff, binary.LittleEndian, riffId)
+	binary.Write(riff, binary.LittleEndian, uint32(riffLength))
+	binary.Write(riff, binary.LittleEndian, waveId)
+	n, err := w.Write(riff.Bytes())
+	written += int64(n)
+	if err != nil {
+		return
+	}
+	n, err = w.Write(chunks.Bytes())
+	written += int64(n)
+	if err != nil {
+		return
+	}
+
+	for _, c := range copied {
+		var ch [CHUNK_HEADER_BYTES]byte
+		copy(ch[0:4], c.Id[:])
+		binary.LittleEndian.PutUint32(ch[4:8], c.Length)
+		n, err := w.Write(ch[:])
+		written += int64(n)
+		if err != nil {
+			return written, err
+		}
+		cn, err := io.Copy(w, io.NewSectionReader(r, c.DataOffset(),
+			int64(c.Length)))
+		written += cn
+		if err != nil {
+			return written, err
+		}
+		if c.Length%2 == 1 {
+			// Keep the next chunk aligned to 2 bytes.
+			n, err = w.Write([]byte{0})
+			written += int64(n)
+			if err != nil {
+				return written, err
+			}
+		}
+	}
+
+	return written, nil
+}